@@ -1,56 +1,266 @@
-// cmd/migrate/main.go
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"log"
-	"movie-project/config"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+
+	"movie-project/config"
+	"movie-project/migrations"
+	"movie-project/pkg/database"
+	"movie-project/pkg/logger"
 )
 
+const usage = `Usage: migrate <command> [arguments]
+
+Commands:
+  up          apply all pending migrations
+  down [N]    roll back N migrations (default 1)
+  goto V      migrate up or down to version V
+  force V     set version V without running migrations and clear the dirty flag,
+              -1 for no migrations applied
+  version     print the current version
+  status      list migrations and whether they are applied
+  create NAME add empty up/down migrations named NAME for every driver
+  check       compare the live schema with the GORM models, exit 1 on drift
+
+Migrations embedded into the binary are used unless MIGRATIONS_DIR is set.
+`
+
+// errUsage reports an unknown command, which exits with status 2 after printing the usage
+var errUsage = errors.New("unknown command")
+
 func main() {
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), flag.Args()[1:]); err != nil {
+		if errors.Is(err, errUsage) {
+			flag.Usage()
+			os.Exit(2)
+		}
+		log.Fatal(err)
+	}
+}
+
+// run runs command, returning an error instead of exiting so that deferred cleanup runs
+func run(command string, args []string) error {
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		return fmt.Errorf("Failed to load config: %w", err)
+	}
+
+	if command == "create" {
+		if len(args) != 1 {
+			return errors.New("create requires a migration NAME")
+		}
+		if err := createMigration(cfg, args[0]); err != nil {
+			return fmt.Errorf("Failed to create migration: %w", err)
+		}
+		return nil
 	}
 
-	dbURL := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, cfg.DBName)
+	if command == "check" {
+		if err := checkDrift(cfg); err != nil {
+			return fmt.Errorf("Failed to check schema: %w", err)
+		}
+		return nil
+	}
+
+	switch command {
+	case "up", "down", "goto", "force", "version", "status":
+	default:
+		return errUsage
+	}
 
-	// Получаем текущую рабочую директорию
-	currentDir, err := os.Getwd()
+	m, err := migrations.New(cfg)
 	if err != nil {
-		log.Fatalf("Failed to get current directory: %v", err)
+		return fmt.Errorf("Failed to create migrate instance: %w", err)
 	}
+	defer m.Close()
 
-	// Формируем путь к директории с миграциями
-	migrationsDir := filepath.Join(currentDir, "migrations")
+	switch command {
+	case "up":
+		err = m.Up()
+	case "down":
+		n := 1
+		if len(args) > 0 {
+			if n, err = parseNumber(args[0], "N", 0); err != nil {
+				return err
+			}
+		}
+		err = m.Steps(-n)
+	case "goto":
+		var v int
+		if v, err = numberArg(args, "goto", "V", 0); err != nil {
+			return err
+		}
+		err = m.Migrate(uint(v))
+	case "force":
+		var v int
+		// -1 marks no migration as applied
+		if v, err = numberArg(args, "force", "V", -1); err != nil {
+			return err
+		}
+		err = m.Force(v)
+	case "version":
+		err = printVersion(m)
+	case "status":
+		err = printStatus(cfg, m)
+	}
 
-	// Проверяем существование директории и файла миграции
-	migrationFile := filepath.Join(migrationsDir, "001_create_movies_table.sql")
-	if _, err := os.Stat(migrationFile); os.IsNotExist(err) {
-		log.Fatalf("Migration file does not exist: %s", migrationFile)
+	if errors.Is(err, migrate.ErrNoChange) {
+		log.Println("No change")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to run %s: %w", command, err)
+	}
+	if command != "version" && command != "status" {
+		log.Printf("Migrate %s completed", command)
 	}
+	return nil
+}
 
-	// Создаем URL для источника миграций
-	migrationsURL := fmt.Sprintf("file://%s", filepath.ToSlash(migrationsDir))
+// numberArg parses the required first argument of command, a number called name of at least min
+func numberArg(args []string, command, name string, min int) (int, error) {
+	if len(args) < 1 {
+		return 0, fmt.Errorf("%s requires %s", command, name)
+	}
+	return parseNumber(args[0], name, min)
+}
 
-	log.Printf("Migrations URL: %s", migrationsURL)
-	log.Printf("Database URL: %s", dbURL)
+func parseNumber(arg, name string, min int) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < min {
+		return 0, fmt.Errorf("Invalid %s: %q", name, arg)
+	}
+	return n, nil
+}
 
-	m, err := migrate.New("C:\\Users\\Admin\\GolandProjects\\movie-project\\migrations", dbURL)
+func printVersion(m *migrate.Migrate) error {
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		fmt.Println("No migrations applied")
+		return nil
+	}
 	if err != nil {
-		log.Fatalf("Failed to create migrate instance: %v", err)
+		return err
 	}
+	fmt.Printf("Version %d, dirty: %t\n", version, dirty)
+	return nil
+}
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		log.Fatalf("Failed to apply migrations: %v", err)
+func printStatus(cfg config.Config, m *migrate.Migrate) error {
+	current, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return err
 	}
 
-	log.Println("Migrations applied successfully")
+	src, err := migrations.Source(cfg)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	for err == nil {
+		r, identifier, readErr := src.ReadUp(version)
+		if readErr != nil {
+			return readErr
+		}
+		r.Close()
+
+		state := "pending"
+		switch {
+		case version == current && dirty:
+			state = "dirty"
+		case version <= current:
+			state = "applied"
+		}
+		fmt.Printf("%03d %-40s %s\n", version, identifier, state)
+
+		version, err = src.Next(version)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func checkDrift(cfg config.Config) error {
+	db, err := database.Open(cfg, logger.Discard())
+	if err != nil {
+		return err
+	}
+
+	drifts, err := migrations.CheckDrift(db, migrations.Models...)
+	if err != nil {
+		return err
+	}
+	if len(drifts) == 0 {
+		fmt.Println("Schema matches the models")
+		return nil
+	}
+
+	for _, drift := range drifts {
+		fmt.Println(drift)
+	}
+	return fmt.Errorf("found %d differences between the schema and the models", len(drifts))
+}
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_.*\.(up|down)\.sql$`)
+
+// createMigration writes empty up/down files with the next free version for every driver,
+// so the dialects never drift apart in numbering.
+func createMigration(cfg config.Config, name string) error {
+	name = strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return errors.New("name must contain letters or digits")
+	}
+
+	dir := cfg.MigrationsDir
+	if dir == "" {
+		dir = "migrations"
+	}
+
+	next := 1
+	for _, driver := range migrations.Drivers {
+		entries, err := os.ReadDir(filepath.Join(dir, driver))
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if match := migrationFileRe.FindStringSubmatch(entry.Name()); match != nil {
+				if version, _ := strconv.Atoi(match[1]); version >= next {
+					next = version + 1
+				}
+			}
+		}
+	}
+
+	for _, driver := range migrations.Drivers {
+		for _, direction := range []string{"up", "down"} {
+			fileName := fmt.Sprintf("%03d_%s.%s.sql", next, name, direction)
+			path := filepath.Join(dir, driver, fileName)
+			header := fmt.Sprintf("-- migrations/%s/%s\n", driver, fileName)
+			if err := os.WriteFile(path, []byte(header), 0o644); err != nil {
+				return err
+			}
+			log.Printf("Created %s", path)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,492 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gopkg.in/yaml.v3"
+
+	"movie-project/pkg/client"
+	pb "movie-project/proto/movie"
+)
+
+// usageError reports invalid arguments, which exit with status 2
+type usageError struct{ msg string }
+
+func (e usageError) Error() string { return e.msg }
+
+func usagef(format string, args ...any) error {
+	return usageError{fmt.Sprintf(format, args...)}
+}
+
+// commands implements the subcommands
+type commands struct {
+	client *client.Client
+	out    printer
+	in     io.Reader // read by import -
+	status io.Writer // progress messages, kept apart from the output
+}
+
+func parseID(arg string) (int64, error) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || id < 1 {
+		return 0, usagef("invalid id %q", arg)
+	}
+	return id, nil
+}
+
+// newFlagSet returns a flag set for the arguments of a command that reports errors instead of exiting
+func newFlagSet(command string) *flag.FlagSet {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return flags
+}
+
+func (c *commands) get(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return usagef("requires at least one ID")
+	}
+	var movies []*pb.Movie
+	for _, arg := range args {
+		id, err := parseID(arg)
+		if err != nil {
+			return err
+		}
+		movie, err := c.client.GetMovie(ctx, id)
+		if err != nil {
+			return fmt.Errorf("movie %d: %w", id, err)
+		}
+		movies = append(movies, movie)
+	}
+	return c.out.print(movies, len(args) == 1)
+}
+
+func (c *commands) list(ctx context.Context, args []string) error {
+	flags := newFlagSet("list")
+	page := flags.Int("page", 1, "page number, from 1")
+	pageSize := flags.Int("page-size", 20, "movies per page")
+	all := flags.Bool("all", false, "list all movies")
+	if err := flags.Parse(args); err != nil {
+		return usagef("%v", err)
+	}
+	if *page < 1 || *pageSize < 1 {
+		return usagef("--page and --page-size must be positive")
+	}
+
+	if *all {
+		movies, err := c.all(ctx, func(*pb.Movie) bool { return true })
+		if err != nil {
+			return err
+		}
+		return c.out.print(movies, false)
+	}
+
+	resp, err := c.client.ListMovies(ctx, int32(*page), int32(*pageSize))
+	if err != nil {
+		return err
+	}
+	if err := c.out.print(resp.Movies, false); err != nil {
+		return err
+	}
+	if c.out.format == "table" {
+		fmt.Fprintf(c.status, "page %d of %d movies\n", *page, resp.TotalCount)
+	}
+	return nil
+}
+
+// all returns the movies matching keep
+func (c *commands) all(ctx context.Context, keep func(*pb.Movie) bool) ([]*pb.Movie, error) {
+	var movies []*pb.Movie
+	it := c.client.Movies(ctx)
+	for it.Next() {
+		if keep(it.Movie()) {
+			movies = append(movies, it.Movie())
+		}
+	}
+	return movies, it.Err()
+}
+
+// movieFlags are the flags setting the fields of a movie
+type movieFlags struct {
+	flags            *flag.FlagSet
+	title            *string
+	director         *string
+	releaseDate      *string
+	genre            *string
+	rating           *float64
+	synopsis         *string
+	tagline          *string
+	runtime          *int
+	countries        *string
+	originalLanguage *string
+	certifications   map[string]string
+	budget           *int64
+	boxOffice        *int64
+	imdbID           *string
+	tmdbID           *int64
+}
+
+func newMovieFlags(command string) movieFlags {
+	flags := newFlagSet(command)
+	f := movieFlags{
+		flags:            flags,
+		title:            flags.String("title", "", "title"),
+		director:         flags.String("director", "", "director"),
+		releaseDate:      flags.String("release-date", "", "release date, YYYY-MM-DD"),
+		genre:            flags.String("genre", "", "genre"),
+		rating:           flags.Float64("rating", 0, "rating from 0 to 10"),
+		synopsis:         flags.String("synopsis", "", "synopsis"),
+		tagline:          flags.String("tagline", "", "tagline"),
+		runtime:          flags.Int("runtime", 0, "running time in minutes"),
+		countries:        flags.String("countries", "", "production countries, comma-separated ISO 3166-1 alpha-2 codes"),
+		originalLanguage: flags.String("original-language", "", "ISO 639 code of the original language"),
+		certifications:   map[string]string{},
+		budget:           flags.Int64("budget", 0, "budget in US dollars"),
+		boxOffice:        flags.Int64("box-office", 0, "box office gross in US dollars"),
+		imdbID:           flags.String("imdb-id", "", "IMDb id, such as tt0068646"),
+		tmdbID:           flags.Int64("tmdb-id", 0, "TMDB id"),
+	}
+	flags.Func("certification", "age certification in a country, COUNTRY=RATING; an empty RATING removes it", func(value string) error {
+		country, rating, ok := strings.Cut(value, "=")
+		if !ok || country == "" {
+			return fmt.Errorf("expected COUNTRY=RATING, got %q", value)
+		}
+		f.certifications[country] = rating
+		return nil
+	})
+	return f
+}
+
+// movieFieldPaths are the UpdateMovieRequest fields set by the movie flags
+var movieFieldPaths = map[string]string{
+	"title":             "title",
+	"director":          "director",
+	"release-date":      "release_date",
+	"genre":             "genre",
+	"rating":            "rating",
+	"synopsis":          "synopsis",
+	"tagline":           "tagline",
+	"runtime":           "runtime_minutes",
+	"countries":         "production_countries",
+	"original-language": "original_language",
+	"certification":     "certifications",
+	"budget":            "budget",
+	"box-office":        "box_office",
+	"imdb-id":           "imdb_id",
+	"tmdb-id":           "tmdb_id",
+}
+
+// updateMask returns the mask of the fields given on the command line
+func (f movieFlags) updateMask() *fieldmaskpb.FieldMask {
+	mask := &fieldmaskpb.FieldMask{}
+	f.flags.Visit(func(fl *flag.Flag) {
+		mask.Paths = append(mask.Paths, movieFieldPaths[fl.Name])
+	})
+	return mask
+}
+
+// apply sets the fields of m given on the command line
+func (f movieFlags) apply(m *pb.Movie) error {
+	var err error
+	f.flags.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "title":
+			m.Title = *f.title
+		case "director":
+			m.Director = *f.director
+		case "release-date":
+			if m.ReleaseDate, err = parseDate(*f.releaseDate); err != nil {
+				err = usagef("%v", err)
+			}
+		case "genre":
+			m.Genre = *f.genre
+		case "rating":
+			m.Rating = float32(*f.rating)
+		case "synopsis":
+			m.Synopsis = *f.synopsis
+		case "tagline":
+			m.Tagline = *f.tagline
+		case "runtime":
+			m.RuntimeMinutes = int32(*f.runtime)
+		case "countries":
+			m.ProductionCountries = nil
+			for _, country := range strings.Split(*f.countries, ",") {
+				if country = strings.TrimSpace(country); country != "" {
+					m.ProductionCountries = append(m.ProductionCountries, country)
+				}
+			}
+		case "original-language":
+			m.OriginalLanguage = *f.originalLanguage
+		case "certification":
+			if m.Certifications == nil {
+				m.Certifications = map[string]string{}
+			}
+			for country, rating := range f.certifications {
+				if rating == "" {
+					delete(m.Certifications, country)
+				} else {
+					m.Certifications[country] = rating
+				}
+			}
+		case "budget":
+			m.Budget = *f.budget
+		case "box-office":
+			m.BoxOffice = *f.boxOffice
+		case "imdb-id":
+			m.ImdbId = *f.imdbID
+		case "tmdb-id":
+			m.TmdbId = *f.tmdbID
+		}
+	})
+	return err
+}
+
+func (c *commands) create(ctx context.Context, args []string) error {
+	flags := newMovieFlags("create")
+	if err := flags.flags.Parse(args); err != nil {
+		return usagef("%v", err)
+	}
+	m := &pb.Movie{}
+	if err := flags.apply(m); err != nil {
+		return err
+	}
+	created, err := c.client.CreateMovie(ctx, createRequest(m))
+	if err != nil {
+		return err
+	}
+	return c.out.print([]*pb.Movie{created}, true)
+}
+
+func (c *commands) update(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return usagef("requires an ID")
+	}
+	id, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+	flags := newMovieFlags("update")
+	if err := flags.flags.Parse(args[1:]); err != nil {
+		return usagef("%v", err)
+	}
+	if flags.flags.NFlag() == 0 {
+		return usagef("nothing to change, set at least one field")
+	}
+
+	// --certification changes single certifications, so start from the current ones, but only
+	// replace the fields given in case the others change meanwhile
+	m, err := c.client.GetMovie(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := flags.apply(m); err != nil {
+		return err
+	}
+	req := updateRequest(m)
+	req.UpdateMask = flags.updateMask()
+	updated, err := c.client.UpdateMovie(ctx, req)
+	if err != nil {
+		return err
+	}
+	return c.out.print([]*pb.Movie{updated}, true)
+}
+
+func createRequest(m *pb.Movie) *pb.CreateMovieRequest {
+	return &pb.CreateMovieRequest{
+		Title:               m.Title,
+		Director:            m.Director,
+		ReleaseDate:         m.ReleaseDate,
+		Genre:               m.Genre,
+		Rating:              m.Rating,
+		Synopsis:            m.Synopsis,
+		Tagline:             m.Tagline,
+		RuntimeMinutes:      m.RuntimeMinutes,
+		ProductionCountries: m.ProductionCountries,
+		OriginalLanguage:    m.OriginalLanguage,
+		Certifications:      m.Certifications,
+		Budget:              m.Budget,
+		BoxOffice:           m.BoxOffice,
+		ImdbId:              m.ImdbId,
+		TmdbId:              m.TmdbId,
+	}
+}
+
+// updateRequest replaces all fields of the movie with those of m
+func updateRequest(m *pb.Movie) *pb.UpdateMovieRequest {
+	return &pb.UpdateMovieRequest{
+		Id:                  m.Id,
+		Title:               m.Title,
+		Director:            m.Director,
+		ReleaseDate:         m.ReleaseDate,
+		Genre:               m.Genre,
+		Rating:              m.Rating,
+		Synopsis:            m.Synopsis,
+		Tagline:             m.Tagline,
+		RuntimeMinutes:      m.RuntimeMinutes,
+		ProductionCountries: m.ProductionCountries,
+		OriginalLanguage:    m.OriginalLanguage,
+		Certifications:      m.Certifications,
+		Budget:              m.Budget,
+		BoxOffice:           m.BoxOffice,
+		ImdbId:              m.ImdbId,
+		TmdbId:              m.TmdbId,
+		UpdateMask:          &fieldmaskpb.FieldMask{Paths: []string{"*"}},
+	}
+}
+
+func (c *commands) delete(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return usagef("requires at least one ID")
+	}
+	ids := make([]int64, len(args))
+	for i, arg := range args {
+		id, err := parseID(arg)
+		if err != nil {
+			return err
+		}
+		ids[i] = id
+	}
+	for _, id := range ids {
+		if err := c.client.DeleteMovie(ctx, id); err != nil {
+			return fmt.Errorf("movie %d: %w", id, err)
+		}
+		fmt.Fprintf(c.status, "deleted movie %d\n", id)
+	}
+	return nil
+}
+
+// search filters all movies on the client; the service has no search call
+func (c *commands) search(ctx context.Context, args []string) error {
+	if len(args) != 1 || strings.TrimSpace(args[0]) == "" {
+		return usagef("requires a QUERY")
+	}
+	query := strings.ToLower(args[0])
+	movies, err := c.all(ctx, func(m *pb.Movie) bool {
+		for _, field := range []string{m.Title, m.Director, m.Genre} {
+			if strings.Contains(strings.ToLower(field), query) {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return err
+	}
+	return c.out.print(movies, false)
+}
+
+func (c *commands) importMovies(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return usagef("requires a FILE")
+	}
+	movies, err := readMovies(args[0], c.in)
+	if err != nil {
+		return err
+	}
+
+	// check all dates before changing anything
+	releaseDates := make([]*timestamppb.Timestamp, len(movies))
+	for i, m := range movies {
+		if releaseDates[i], err = parseDate(m.ReleaseDate); err != nil {
+			return fmt.Errorf("movie %d of %s: %w", i+1, args[0], err)
+		}
+	}
+
+	created, updated := 0, 0
+	for i, m := range movies {
+		if m.ID != 0 {
+			_, err = c.client.UpdateMovie(ctx, updateRequest(toProto(m, releaseDates[i])))
+		} else {
+			_, err = c.client.CreateMovie(ctx, createRequest(toProto(m, releaseDates[i])))
+		}
+		if err != nil {
+			return fmt.Errorf("movie %d of %s (%q): %w (%d created and %d updated before it)", i+1, args[0], m.Title, err, created, updated)
+		}
+		if m.ID != 0 {
+			updated++
+		} else {
+			created++
+		}
+	}
+	fmt.Fprintf(c.status, "imported %d movies: %d created, %d updated\n", len(movies), created, updated)
+	return nil
+}
+
+// readMovies reads a list of movies from a JSON or YAML file, or JSON from stdin for "-"
+func readMovies(path string, stdin io.Reader) ([]movie, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var movies []movie
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &movies)
+	default:
+		err = json.Unmarshal(data, &movies)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return movies, nil
+}
+
+func (c *commands) export(ctx context.Context, args []string) error {
+	if len(args) > 1 {
+		return usagef("takes at most one FILE")
+	}
+	format := c.out.format
+	if len(args) == 1 {
+		switch strings.ToLower(filepath.Ext(args[0])) {
+		case ".yaml", ".yml":
+			format = "yaml"
+		case ".json":
+			format = "json"
+		}
+	}
+	if format == "table" {
+		format = "json"
+	}
+
+	movies, err := c.all(ctx, func(*pb.Movie) bool { return true })
+	if err != nil {
+		return err
+	}
+	out := make([]movie, len(movies))
+	for i, m := range movies {
+		out[i] = fromProto(m)
+	}
+
+	if len(args) == 0 {
+		return encode(c.out.w, format, out)
+	}
+	f, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+	if err := encode(f, format, out); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.status, "exported %d movies to %s\n", len(out), args[0])
+	return nil
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"movie-project/pkg/client"
+	pb "movie-project/proto/movie"
+)
+
+// fakeServer serves movies from memory and records the requests changing them
+type fakeServer struct {
+	pb.UnimplementedMovieServiceServer
+
+	mu      sync.Mutex
+	movies  map[int64]*pb.Movie
+	nextID  int64
+	created []*pb.CreateMovieRequest
+	updated []*pb.UpdateMovieRequest
+	deleted []int64
+}
+
+func newFakeServer(movies ...*pb.Movie) *fakeServer {
+	s := &fakeServer{movies: map[int64]*pb.Movie{}}
+	for _, m := range movies {
+		s.movies[m.Id] = m
+		s.nextID = max(s.nextID, m.Id)
+	}
+	return s
+}
+
+func (s *fakeServer) CreateMovie(_ context.Context, req *pb.CreateMovieRequest) (*pb.Movie, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.created = append(s.created, req)
+	s.nextID++
+	m := &pb.Movie{Id: s.nextID, Title: req.Title, Director: req.Director, ReleaseDate: req.ReleaseDate, Genre: req.Genre, Rating: req.Rating}
+	s.movies[m.Id] = m
+	return m, nil
+}
+
+func (s *fakeServer) GetMovie(_ context.Context, req *pb.GetMovieRequest) (*pb.Movie, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.movies[req.Id]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "Movie not found: record not found")
+	}
+	return proto.Clone(m).(*pb.Movie), nil
+}
+
+func (s *fakeServer) ListMovies(_ context.Context, req *pb.ListMoviesRequest) (*pb.ListMoviesResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []int64
+	for id := range s.movies {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	start := min(int((req.PageNumber-1)*req.PageSize), len(ids))
+	end := min(start+int(req.PageSize), len(ids))
+	resp := &pb.ListMoviesResponse{TotalCount: int32(len(ids))}
+	for _, id := range ids[start:end] {
+		resp.Movies = append(resp.Movies, s.movies[id])
+	}
+	return resp, nil
+}
+
+func (s *fakeServer) UpdateMovie(_ context.Context, req *pb.UpdateMovieRequest) (*pb.Movie, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.movies[req.Id]; !ok {
+		return nil, status.Error(codes.NotFound, "Movie not found: record not found")
+	}
+	s.updated = append(s.updated, req)
+	m := &pb.Movie{Id: req.Id, Title: req.Title, Director: req.Director, ReleaseDate: req.ReleaseDate, Genre: req.Genre, Rating: req.Rating}
+	s.movies[m.Id] = m
+	return m, nil
+}
+
+func (s *fakeServer) DeleteMovie(_ context.Context, req *pb.DeleteMovieRequest) (*pb.DeleteMovieResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.movies[req.Id]; !ok {
+		return nil, status.Error(codes.NotFound, "Movie not found: record not found")
+	}
+	delete(s.movies, req.Id)
+	s.deleted = append(s.deleted, req.Id)
+	return &pb.DeleteMovieResponse{Success: true}, nil
+}
+
+// newTestCommands returns commands calling server over bufconn and printing in format to the
+// returned buffer
+func newTestCommands(t *testing.T, server *fakeServer, format string) (*commands, *bytes.Buffer) {
+	lis := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	pb.RegisterMovieServiceServer(grpcServer, server)
+	go grpcServer.Serve(lis)
+
+	c, err := client.New("passthrough:///bufnet", client.Options{
+		MaxRetries: -1,
+		PageSize:   2,
+		DialOptions: []grpc.DialOption{grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		})},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		c.Close()
+		grpcServer.Stop()
+	})
+
+	var out bytes.Buffer
+	return &commands{client: c, out: printer{format: format, w: &out}, in: strings.NewReader(""), status: &bytes.Buffer{}}, &out
+}
+
+func date(year int, month time.Month, day int) *timestamppb.Timestamp {
+	return timestamppb.New(time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+}
+
+// godfather is a movie with every field set
+func godfather() *pb.Movie {
+	return &pb.Movie{
+		Id:                  1,
+		Title:               "The Godfather",
+		Director:            "Francis Ford Coppola",
+		ReleaseDate:         date(1972, 3, 24),
+		Genre:               "Crime",
+		Rating:              9.2,
+		Synopsis:            "The aging patriarch of an organized crime dynasty transfers control to his son.",
+		Tagline:             "An offer you can't refuse.",
+		RuntimeMinutes:      175,
+		ProductionCountries: []string{"US"},
+		OriginalLanguage:    "en",
+		Certifications:      map[string]string{"US": "R", "DE": "16"},
+		Budget:              6000000,
+		BoxOffice:           250000000,
+		ImdbId:              "tt0068646",
+		TmdbId:              238,
+	}
+}
+
+func TestMovieFlags_Apply(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		args   []string
+		modify func(m *pb.Movie) // applied to godfather() for the expected movie
+		err    string
+	}{
+		{name: "no flags", modify: func(*pb.Movie) {}},
+		{name: "title", args: []string{"--title", "The Godfather Part II"}, modify: func(m *pb.Movie) { m.Title = "The Godfather Part II" }},
+		{name: "empty title", args: []string{"--title="}, modify: func(m *pb.Movie) { m.Title = "" }},
+		{name: "release date", args: []string{"--release-date", "1974-12-20"}, modify: func(m *pb.Movie) { m.ReleaseDate = date(1974, 12, 20) }},
+		{name: "RFC 3339 release date", args: []string{"--release-date", "1974-12-20T00:00:00Z"}, modify: func(m *pb.Movie) { m.ReleaseDate = date(1974, 12, 20) }},
+		{name: "invalid release date", args: []string{"--release-date", "20.12.1974"}, err: "invalid release date"},
+		{name: "rating", args: []string{"--rating", "9"}, modify: func(m *pb.Movie) { m.Rating = 9 }},
+		{
+			name:   "numbers",
+			args:   []string{"--runtime", "202", "--budget", "13000000", "--box-office", "93000000", "--tmdb-id", "240"},
+			modify: func(m *pb.Movie) { m.RuntimeMinutes, m.Budget, m.BoxOffice, m.TmdbId = 202, 13000000, 93000000, 240 },
+		},
+		{name: "invalid number", args: []string{"--runtime", "long"}, err: "invalid value"},
+		{name: "countries", args: []string{"--countries", "US, IT ,,"}, modify: func(m *pb.Movie) { m.ProductionCountries = []string{"US", "IT"} }},
+		{name: "no countries", args: []string{"--countries", ""}, modify: func(m *pb.Movie) { m.ProductionCountries = nil }},
+		{
+			name:   "certifications",
+			args:   []string{"--certification", "FR=12", "--certification", "US=PG-13", "--certification", "DE="},
+			modify: func(m *pb.Movie) { m.Certifications = map[string]string{"US": "PG-13", "FR": "12"} },
+		},
+		{name: "certification without rating", args: []string{"--certification", "US"}, err: "expected COUNTRY=RATING"},
+		{name: "certification without country", args: []string{"--certification", "=R"}, err: "expected COUNTRY=RATING"},
+		{
+			name: "text",
+			args: []string{"--synopsis", "", "--tagline", "Part II", "--original-language", "it", "--imdb-id", "tt0071562"},
+			modify: func(m *pb.Movie) {
+				m.Synopsis, m.Tagline, m.OriginalLanguage, m.ImdbId = "", "Part II", "it", "tt0071562"
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := newMovieFlags("update")
+			m := godfather()
+			err := flags.flags.Parse(tt.args)
+			if err == nil {
+				err = flags.apply(m)
+			}
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			want := godfather()
+			tt.modify(want)
+			assert.True(t, proto.Equal(want, m), "got %v", m)
+		})
+	}
+}
+
+func TestMovieFlags_ApplyCertificationsToNone(t *testing.T) {
+	flags := newMovieFlags("create")
+	require.NoError(t, flags.flags.Parse([]string{"--certification", "US=R"}))
+	m := &pb.Movie{}
+	require.NoError(t, flags.apply(m))
+	assert.Equal(t, map[string]string{"US": "R"}, m.Certifications)
+}
+
+func TestCommands_Create(t *testing.T) {
+	server := newFakeServer()
+	cmd, out := newTestCommands(t, server, "json")
+
+	err := cmd.create(context.Background(), []string{
+		"--title", "Heat", "--director", "Michael Mann", "--release-date", "1995-12-15", "--genre", "Crime", "--rating", "8.3",
+		"--countries", "US", "--certification", "US=R", "--imdb-id", "tt0113277",
+	})
+	require.NoError(t, err)
+	require.Len(t, server.created, 1)
+	req := server.created[0]
+	assert.Equal(t, "Heat", req.Title)
+	assert.True(t, date(1995, 12, 15).AsTime().Equal(req.ReleaseDate.AsTime()))
+	assert.Equal(t, []string{"US"}, req.ProductionCountries)
+	assert.Equal(t, map[string]string{"US": "R"}, req.Certifications)
+	assert.Equal(t, "tt0113277", req.ImdbId)
+
+	var printed movie
+	require.NoError(t, json.Unmarshal(out.Bytes(), &printed), "a single movie is printed as an object")
+	assert.Equal(t, int64(1), printed.ID)
+
+	err = cmd.create(context.Background(), []string{"--unknown"})
+	assert.ErrorAs(t, err, &usageError{})
+}
+
+func TestCommands_Update(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		args   []string
+		modify func(req *pb.UpdateMovieRequest)
+		err    string
+		usage  bool
+	}{
+		{
+			name: "keeps the fields not given",
+			args: []string{"1", "--rating", "9.5", "--certification", "DE=", "--certification", "FR=12"},
+			modify: func(req *pb.UpdateMovieRequest) {
+				req.Rating, req.Certifications = 9.5, map[string]string{"US": "R", "FR": "12"}
+				req.UpdateMask.Paths = []string{"certifications", "rating"}
+			},
+		},
+		{name: "no fields", args: []string{"1"}, err: "nothing to change", usage: true},
+		{name: "no id", err: "requires an ID", usage: true},
+		{name: "invalid id", args: []string{"first", "--rating", "9"}, err: "invalid id", usage: true},
+		{name: "missing movie", args: []string{"2", "--rating", "9"}, err: "not found"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(godfather())
+			cmd, _ := newTestCommands(t, server, "table")
+
+			err := cmd.update(context.Background(), tt.args)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				assert.Equal(t, tt.usage, errors.As(err, &usageError{}))
+				assert.Empty(t, server.updated)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, server.updated, 1)
+			want := updateRequest(godfather())
+			tt.modify(want)
+			assert.True(t, proto.Equal(want, server.updated[0]), "got %v", server.updated[0])
+		})
+	}
+}
+
+func TestCommands_Import(t *testing.T) {
+	const jsonMovies = `[
+		{"title": "Heat", "director": "Michael Mann", "release_date": "1995-12-15", "genre": "Crime", "rating": 8.3,
+		 "production_countries": ["US"], "certifications": {"US": "R"}},
+		{"id": 1, "title": "The Godfather", "director": "Francis Ford Coppola", "release_date": "1972-03-24T00:00:00Z", "genre": "Crime", "rating": 9.2,
+		 "imdb_id": "tt0068646"}
+	]`
+	const yamlMovies = `
+- title: Heat
+  director: Michael Mann
+  release_date: "1995-12-15"
+  genre: Crime
+  rating: 8.3
+  production_countries: [US]
+  certifications:
+    US: R
+- id: 1
+  title: The Godfather
+  director: Francis Ford Coppola
+  release_date: "1972-03-24"
+  genre: Crime
+  rating: 9.2
+  imdb_id: tt0068646
+`
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	for _, tt := range []struct {
+		name  string
+		path  string
+		stdin string
+		err   string
+	}{
+		{name: "JSON", path: write("movies.json", jsonMovies)},
+		{name: "YAML", path: write("movies.yaml", yamlMovies)},
+		{name: "YML", path: write("movies.yml", yamlMovies)},
+		{name: "stdin", path: "-", stdin: jsonMovies},
+		{name: "invalid date", path: write("dates.json", `[{"title": "Heat", "release_date": "1995"}, {"title": "Ronin", "release_date": "1998-09-25"}]`), err: "movie 1 of"},
+		{name: "invalid JSON", path: write("invalid.json", `{"title": "Heat"}`), err: "failed to parse"},
+		{name: "missing file", path: filepath.Join(dir, "missing.json"), err: "no such file"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(godfather())
+			cmd, _ := newTestCommands(t, server, "table")
+			cmd.in = strings.NewReader(tt.stdin)
+
+			err := cmd.importMovies(context.Background(), []string{tt.path})
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				assert.Empty(t, server.created, "nothing is imported")
+				assert.Empty(t, server.updated, "nothing is imported")
+				return
+			}
+			require.NoError(t, err)
+
+			require.Len(t, server.created, 1)
+			created := server.created[0]
+			assert.Equal(t, "Heat", created.Title)
+			assert.True(t, date(1995, 12, 15).AsTime().Equal(created.ReleaseDate.AsTime()))
+			assert.Equal(t, []string{"US"}, created.ProductionCountries)
+			assert.Equal(t, map[string]string{"US": "R"}, created.Certifications)
+
+			require.Len(t, server.updated, 1, "movies with an id are updated")
+			updated := server.updated[0]
+			assert.Equal(t, int64(1), updated.Id)
+			assert.True(t, date(1972, 3, 24).AsTime().Equal(updated.ReleaseDate.AsTime()))
+			assert.Equal(t, "tt0068646", updated.ImdbId)
+			assert.Equal(t, []string{"*"}, updated.UpdateMask.GetPaths(), "fields missing from the file are cleared")
+		})
+	}
+
+	cmd, _ := newTestCommands(t, newFakeServer(), "table")
+	assert.ErrorAs(t, cmd.importMovies(context.Background(), nil), &usageError{})
+}
+
+func TestCommands_GetAndDelete(t *testing.T) {
+	server := newFakeServer(godfather(), &pb.Movie{Id: 2, Title: "Heat", ReleaseDate: date(1995, 12, 15)})
+	cmd, out := newTestCommands(t, server, "json")
+	ctx := context.Background()
+
+	require.NoError(t, cmd.get(ctx, []string{"1", "2"}))
+	var printed []movie
+	require.NoError(t, json.Unmarshal(out.Bytes(), &printed), "several movies are printed as a list")
+	require.Len(t, printed, 2)
+	assert.Equal(t, "Heat", printed[1].Title)
+
+	assert.ErrorContains(t, cmd.get(ctx, []string{"3"}), "movie 3")
+	assert.ErrorAs(t, cmd.get(ctx, nil), &usageError{})
+
+	assert.ErrorAs(t, cmd.delete(ctx, []string{"2", "x"}), &usageError{})
+	assert.Empty(t, server.deleted, "ids are checked before deleting any")
+	require.NoError(t, cmd.delete(ctx, []string{"2", "1"}))
+	assert.Equal(t, []int64{2, 1}, server.deleted)
+}
+
+func TestCommands_ListAndSearch(t *testing.T) {
+	server := newFakeServer(
+		godfather(),
+		&pb.Movie{Id: 2, Title: "Heat", Director: "Michael Mann", Genre: "Crime", ReleaseDate: date(1995, 12, 15)},
+		&pb.Movie{Id: 3, Title: "Collateral", Director: "Michael Mann", Genre: "Thriller", ReleaseDate: date(2004, 8, 6)},
+	)
+	cmd, out := newTestCommands(t, server, "json")
+	ctx := context.Background()
+
+	titles := func() []string {
+		var printed []movie
+		require.NoError(t, json.Unmarshal(out.Bytes(), &printed))
+		out.Reset()
+		var titles []string
+		for _, m := range printed {
+			titles = append(titles, m.Title)
+		}
+		return titles
+	}
+
+	require.NoError(t, cmd.list(ctx, []string{"--page", "2", "--page-size", "2"}))
+	assert.Equal(t, []string{"Collateral"}, titles())
+	require.NoError(t, cmd.list(ctx, []string{"--all"}))
+	assert.Equal(t, []string{"The Godfather", "Heat", "Collateral"}, titles(), "all pages")
+	assert.ErrorAs(t, cmd.list(ctx, []string{"--page", "0"}), &usageError{})
+
+	require.NoError(t, cmd.search(ctx, []string{"MICHAEL"}))
+	assert.Equal(t, []string{"Heat", "Collateral"}, titles())
+	require.NoError(t, cmd.search(ctx, []string{"thrill"}))
+	assert.Equal(t, []string{"Collateral"}, titles())
+	assert.ErrorAs(t, cmd.search(ctx, []string{" "}), &usageError{})
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"movie-project/pkg/client"
+)
+
+const usage = `Usage: moviectl [options] <command> [arguments]
+
+Commands:
+  get ID...                 print movies
+  list [--page N] [--page-size N] [--all]
+                            print a page of movies, or all of them
+  create --title T --director D --release-date YYYY-MM-DD --genre G --rating R
+                            create a movie
+  update ID [--title T] [--director D] [--release-date YYYY-MM-DD] [--genre G] [--rating R]
+                            change the given fields of a movie
+                            create and update also take --synopsis, --tagline, --runtime MINUTES,
+                            --countries US,FR, --original-language CODE, --certification
+                            COUNTRY=RATING (repeatable), --budget USD, --box-office USD,
+                            --imdb-id ID and --tmdb-id ID
+  delete ID...              delete movies
+  search QUERY              print movies whose title, director or genre contain QUERY
+  import FILE               create movies from a JSON or YAML file ("-" reads JSON from stdin);
+                            movies with an id are updated instead
+  export [FILE]             write all movies as JSON or YAML, to stdout by default
+
+Options:
+`
+
+const configUsage = `
+Settings are read from, in increasing precedence, the config file, MOVIECTL_* environment
+variables and the options above. The config file is --config, MOVIECTL_CONFIG or
+~/.config/moviectl/config.yaml, with the keys addr, token, tls, ca_file, cert_file, key_file,
+server_name, output and timeout. Prefer MOVIECTL_TOKEN or the config file to --token, which
+shows up in the process list.
+`
+
+// settings configure the connection and output of moviectl
+type settings struct {
+	Addr       string        `mapstructure:"addr"`
+	Token      string        `mapstructure:"token"`
+	TLS        bool          `mapstructure:"tls"`
+	CAFile     string        `mapstructure:"ca_file"`
+	CertFile   string        `mapstructure:"cert_file"`
+	KeyFile    string        `mapstructure:"key_file"`
+	ServerName string        `mapstructure:"server_name"`
+	Output     string        `mapstructure:"output"`
+	Timeout    time.Duration `mapstructure:"timeout"`
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("moviectl: ")
+	os.Exit(run(os.Args[1:]))
+}
+
+// run runs the command of args and returns the exit status: 0 on success, 1 when the command
+// failed and 2 for invalid arguments
+func run(args []string) int {
+	flags, configFile := newFlags()
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() < 1 {
+		flags.Usage()
+		return 2
+	}
+
+	s, err := loadSettings(flags, *configFile)
+	if err != nil {
+		log.Printf("Failed to load settings: %v", err)
+		return 1
+	}
+	out, err := newPrinter(s.Output)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+
+	c, err := newClient(s)
+	if err != nil {
+		log.Printf("Failed to create client: %v", err)
+		return 1
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	command, commandArgs := flags.Arg(0), flags.Args()[1:]
+	cmd := &commands{client: c, out: out, in: os.Stdin, status: os.Stderr}
+	switch command {
+	case "get":
+		err = cmd.get(ctx, commandArgs)
+	case "list":
+		err = cmd.list(ctx, commandArgs)
+	case "create":
+		err = cmd.create(ctx, commandArgs)
+	case "update":
+		err = cmd.update(ctx, commandArgs)
+	case "delete":
+		err = cmd.delete(ctx, commandArgs)
+	case "search":
+		err = cmd.search(ctx, commandArgs)
+	case "import":
+		err = cmd.importMovies(ctx, commandArgs)
+	case "export":
+		err = cmd.export(ctx, commandArgs)
+	default:
+		flags.Usage()
+		return 2
+	}
+
+	var usageErr usageError
+	if errors.As(err, &usageErr) {
+		log.Printf("%s: %v", command, err)
+		return 2
+	}
+	if err != nil {
+		log.Printf("Failed to run %s: %v", command, err)
+		return 1
+	}
+	return 0
+}
+
+// newFlags returns the flags of the options and the config file option, whose value is
+// MOVIECTL_CONFIG by default
+func newFlags() (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet("moviectl", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+		fmt.Fprint(flags.Output(), configUsage)
+	}
+	configFile := flags.String("config", os.Getenv("MOVIECTL_CONFIG"), "config file (.yaml, .toml, .json or .env)")
+	flags.String("addr", "localhost:50051", "gRPC address of the movie service")
+	flags.String("token", "", "bearer token sent with every call")
+	flags.Bool("tls", false, "connect with TLS")
+	flags.String("ca-file", "", "CA bundle to verify the server certificate, implies --tls")
+	flags.String("cert-file", "", "client certificate, for servers requiring one")
+	flags.String("key-file", "", "key of the client certificate")
+	flags.String("server-name", "", "server name to verify instead of the host of --addr")
+	flags.String("output", "table", "output format: table, json or yaml")
+	flags.Duration("timeout", 30*time.Second, "time limit of the command, 0 for none")
+	return flags, configFile
+}
+
+// loadSettings reads the settings from the config file, the environment and the flags that were set
+func loadSettings(flags *flag.FlagSet, configFile string) (settings, error) {
+	v := viper.New()
+	v.SetEnvPrefix("MOVIECTL")
+	v.AutomaticEnv()
+	flags.VisitAll(func(f *flag.Flag) {
+		if f.Name != "config" {
+			// flag defaults are the defaults of the settings, flags set explicitly override everything
+			v.SetDefault(settingKey(f.Name), f.Value.String())
+		}
+	})
+	flags.Visit(func(f *flag.Flag) {
+		v.Set(settingKey(f.Name), f.Value.String())
+	})
+
+	if configFile == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			configFile = filepath.Join(home, ".config", "moviectl", "config.yaml")
+			if _, err := os.Stat(configFile); err != nil {
+				configFile = ""
+			}
+		}
+	}
+	if configFile != "" {
+		v.SetConfigFile(configFile)
+		if err := v.ReadInConfig(); err != nil {
+			return settings{}, fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+
+	var s settings
+	if err := v.Unmarshal(&s); err != nil {
+		return settings{}, err
+	}
+	return s, nil
+}
+
+// settingKey returns the key of the setting of a flag, e.g. ca_file for --ca-file
+func settingKey(flagName string) string {
+	return strings.ReplaceAll(flagName, "-", "_")
+}
+
+func newClient(s settings) (*client.Client, error) {
+	opts := client.Options{Token: s.Token}
+	if s.TLS || s.CAFile != "" || s.CertFile != "" {
+		tlsConfig := &tls.Config{ServerName: s.ServerName, MinVersion: tls.VersionTLS12}
+		if s.CAFile != "" {
+			pem, err := os.ReadFile(s.CAFile)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates in %s", s.CAFile)
+			}
+		}
+		if s.CertFile != "" || s.KeyFile != "" {
+			cert, err := tls.LoadX509KeyPair(s.CertFile, s.KeyFile)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		opts.TLS = tlsConfig
+	}
+	return client.New(s.Addr, opts)
+}
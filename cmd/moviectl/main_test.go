@@ -0,0 +1,111 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSettings(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte("addr: file:50051\ntoken: from-file\noutput: yaml\ntimeout: 1m\n"), 0o600))
+
+	for _, tt := range []struct {
+		name   string
+		config string
+		env    map[string]string
+		args   []string
+		want   settings
+	}{
+		{
+			name: "defaults",
+			want: settings{Addr: "localhost:50051", Output: "table", Timeout: 30 * time.Second},
+		},
+		{
+			name:   "config file",
+			config: configFile,
+			want:   settings{Addr: "file:50051", Token: "from-file", Output: "yaml", Timeout: time.Minute},
+		},
+		{
+			name:   "environment over config file",
+			config: configFile,
+			env:    map[string]string{"MOVIECTL_ADDR": "env:50051", "MOVIECTL_CA_FILE": "ca.pem"},
+			want:   settings{Addr: "env:50051", Token: "from-file", CAFile: "ca.pem", Output: "yaml", Timeout: time.Minute},
+		},
+		{
+			name:   "flags over environment",
+			config: configFile,
+			env:    map[string]string{"MOVIECTL_ADDR": "env:50051", "MOVIECTL_OUTPUT": "json"},
+			args:   []string{"--addr", "flag:50051", "--timeout", "0", "--tls"},
+			want:   settings{Addr: "flag:50051", Token: "from-file", TLS: true, Output: "json", Timeout: 0},
+		},
+		{
+			name: "environment over defaults",
+			env:  map[string]string{"MOVIECTL_TIMEOUT": "5s", "MOVIECTL_TLS": "true"},
+			want: settings{Addr: "localhost:50051", TLS: true, Output: "table", Timeout: 5 * time.Second},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir()) // no ~/.config/moviectl/config.yaml
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			flags, _ := newFlags()
+			require.NoError(t, flags.Parse(tt.args))
+
+			s, err := loadSettings(flags, tt.config)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, s)
+		})
+	}
+
+	t.Run("default config file", func(t *testing.T) {
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		require.NoError(t, os.MkdirAll(filepath.Join(home, ".config", "moviectl"), 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(home, ".config", "moviectl", "config.yaml"), []byte("token: from-home\n"), 0o600))
+		flags, _ := newFlags()
+		s, err := loadSettings(flags, "")
+		require.NoError(t, err)
+		assert.Equal(t, "from-home", s.Token)
+	})
+
+	t.Run("missing config file", func(t *testing.T) {
+		flags, _ := newFlags()
+		_, err := loadSettings(flags, filepath.Join(dir, "missing.yaml"))
+		assert.ErrorContains(t, err, "failed to read config file")
+	})
+}
+
+func TestRun(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	// Nothing listens on port 1, so calls fail once they are made
+	unreachable := []string{"--addr", "127.0.0.1:1", "--timeout", "2s"}
+	for _, tt := range []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "help", args: []string{"-h"}, want: 0},
+		{name: "no command", want: 2},
+		{name: "unknown option", args: []string{"--verbose", "list"}, want: 2},
+		{name: "unknown command", args: append(unreachable, "watch"), want: 2},
+		{name: "invalid arguments", args: append(unreachable, "get", "first"), want: 2},
+		{name: "unknown output format", args: append(unreachable, "--output", "xml", "list"), want: 1},
+		{name: "failed call", args: append(unreachable, "get", "1"), want: 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, run(tt.args))
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gopkg.in/yaml.v3"
+
+	pb "movie-project/proto/movie"
+)
+
+// dateLayout is the format of release dates in files and output
+const dateLayout = "2006-01-02"
+
+// movie is a movie as read from import files and written by the json and yaml output formats
+type movie struct {
+	ID          int64   `json:"id,omitempty" yaml:"id,omitempty"`
+	Title       string  `json:"title" yaml:"title"`
+	Director    string  `json:"director" yaml:"director"`
+	ReleaseDate string  `json:"release_date" yaml:"release_date"`
+	Genre       string  `json:"genre" yaml:"genre"`
+	Rating      float32 `json:"rating" yaml:"rating"`
+
+	Synopsis            string            `json:"synopsis,omitempty" yaml:"synopsis,omitempty"`
+	Tagline             string            `json:"tagline,omitempty" yaml:"tagline,omitempty"`
+	RuntimeMinutes      int32             `json:"runtime_minutes,omitempty" yaml:"runtime_minutes,omitempty"`
+	ProductionCountries []string          `json:"production_countries,omitempty" yaml:"production_countries,omitempty"`
+	OriginalLanguage    string            `json:"original_language,omitempty" yaml:"original_language,omitempty"`
+	Certifications      map[string]string `json:"certifications,omitempty" yaml:"certifications,omitempty"`
+	Budget              int64             `json:"budget,omitempty" yaml:"budget,omitempty"`
+	BoxOffice           int64             `json:"box_office,omitempty" yaml:"box_office,omitempty"`
+	ImdbID              string            `json:"imdb_id,omitempty" yaml:"imdb_id,omitempty"`
+	TmdbID              int64             `json:"tmdb_id,omitempty" yaml:"tmdb_id,omitempty"`
+}
+
+func fromProto(m *pb.Movie) movie {
+	return movie{
+		ID:          m.Id,
+		Title:       m.Title,
+		Director:    m.Director,
+		ReleaseDate: m.ReleaseDate.AsTime().Format(dateLayout),
+		Genre:       m.Genre,
+		Rating:      m.Rating,
+
+		Synopsis:            m.Synopsis,
+		Tagline:             m.Tagline,
+		RuntimeMinutes:      m.RuntimeMinutes,
+		ProductionCountries: m.ProductionCountries,
+		OriginalLanguage:    m.OriginalLanguage,
+		Certifications:      m.Certifications,
+		Budget:              m.Budget,
+		BoxOffice:           m.BoxOffice,
+		ImdbID:              m.ImdbId,
+		TmdbID:              m.TmdbId,
+	}
+}
+
+// toProto returns m as a movie of the API; the caller parses the release date
+func toProto(m movie, releaseDate *timestamppb.Timestamp) *pb.Movie {
+	return &pb.Movie{
+		Id:                  m.ID,
+		Title:               m.Title,
+		Director:            m.Director,
+		ReleaseDate:         releaseDate,
+		Genre:               m.Genre,
+		Rating:              m.Rating,
+		Synopsis:            m.Synopsis,
+		Tagline:             m.Tagline,
+		RuntimeMinutes:      m.RuntimeMinutes,
+		ProductionCountries: m.ProductionCountries,
+		OriginalLanguage:    m.OriginalLanguage,
+		Certifications:      m.Certifications,
+		Budget:              m.Budget,
+		BoxOffice:           m.BoxOffice,
+		ImdbId:              m.ImdbID,
+		TmdbId:              m.TmdbID,
+	}
+}
+
+// parseDate accepts dates as YYYY-MM-DD or RFC 3339 timestamps
+func parseDate(value string) (*timestamppb.Timestamp, error) {
+	for _, layout := range []string{dateLayout, time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return timestamppb.New(t), nil
+		}
+	}
+	return nil, fmt.Errorf("invalid release date %q: expected YYYY-MM-DD", value)
+}
+
+// printer writes movies in the output format
+type printer struct {
+	format string
+	w      io.Writer
+}
+
+func newPrinter(format string) (printer, error) {
+	switch format {
+	case "table", "json", "yaml":
+		return printer{format: format, w: os.Stdout}, nil
+	default:
+		return printer{}, fmt.Errorf("unknown output format %q: expected table, json or yaml", format)
+	}
+}
+
+// print writes movies; json and yaml write a list unless single is set
+func (p printer) print(movies []*pb.Movie, single bool) error {
+	out := make([]movie, len(movies))
+	for i, m := range movies {
+		out[i] = fromProto(m)
+	}
+
+	switch {
+	case p.format == "table":
+		return writeTable(p.w, out)
+	case single && len(out) == 1:
+		return encode(p.w, p.format, out[0])
+	default:
+		return encode(p.w, p.format, out)
+	}
+}
+
+func writeTable(w io.Writer, movies []movie) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTITLE\tDIRECTOR\tRELEASED\tGENRE\tRATING")
+	for _, m := range movies {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%.1f\n", m.ID, m.Title, m.Director, m.ReleaseDate, m.Genre, m.Rating)
+	}
+	return tw.Flush()
+}
+
+// encode writes value as json or yaml
+func encode(w io.Writer, format string, value any) error {
+	if format == "yaml" {
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(value); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(value)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	pb "movie-project/proto/movie"
+)
+
+func TestPrinter(t *testing.T) {
+	heat := &pb.Movie{Id: 2, Title: "Heat", Director: "Michael Mann", ReleaseDate: date(1995, 12, 15), Genre: "Crime", Rating: 8.3}
+
+	for _, tt := range []struct {
+		format string
+		movies []*pb.Movie
+		single bool
+		want   string
+	}{
+		{
+			format: "table",
+			movies: []*pb.Movie{godfather(), heat},
+			want: "ID  TITLE          DIRECTOR              RELEASED    GENRE  RATING\n" +
+				"1   The Godfather  Francis Ford Coppola  1972-03-24  Crime  9.2\n" +
+				"2   Heat           Michael Mann          1995-12-15  Crime  8.3\n",
+		},
+		{
+			format: "json",
+			movies: []*pb.Movie{heat},
+			single: true,
+			want: `{
+  "id": 2,
+  "title": "Heat",
+  "director": "Michael Mann",
+  "release_date": "1995-12-15",
+  "genre": "Crime",
+  "rating": 8.3
+}
+`,
+		},
+		{
+			format: "json",
+			movies: nil,
+			want:   "[]\n",
+		},
+		{
+			format: "yaml",
+			movies: []*pb.Movie{heat},
+			want: `- id: 2
+  title: Heat
+  director: Michael Mann
+  release_date: "1995-12-15"
+  genre: Crime
+  rating: 8.3
+`,
+		},
+	} {
+		t.Run(tt.format, func(t *testing.T) {
+			var out bytes.Buffer
+			require.NoError(t, printer{format: tt.format, w: &out}.print(tt.movies, tt.single))
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+
+	_, err := newPrinter("xml")
+	assert.ErrorContains(t, err, "unknown output format")
+}
+
+func TestPrinter_RoundTrip(t *testing.T) {
+	// What json and yaml print can be imported again, with every field
+	for _, format := range []string{"json", "yaml"} {
+		var out bytes.Buffer
+		require.NoError(t, printer{format: format, w: &out}.print([]*pb.Movie{godfather()}, false))
+
+		var movies []movie
+		if format == "yaml" {
+			require.NoError(t, yaml.Unmarshal(out.Bytes(), &movies))
+		} else {
+			require.NoError(t, json.Unmarshal(out.Bytes(), &movies))
+		}
+		require.Len(t, movies, 1, format)
+		releaseDate, err := parseDate(movies[0].ReleaseDate)
+		require.NoError(t, err)
+		assert.Equal(t, godfather().String(), toProto(movies[0], releaseDate).String(), format)
+	}
+}
+
+func TestCommands_Export(t *testing.T) {
+	server := newFakeServer(godfather(), &pb.Movie{Id: 2, Title: "Heat", ReleaseDate: date(1995, 12, 15)})
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	cmd, out := newTestCommands(t, server, "table")
+	require.NoError(t, cmd.export(ctx, nil))
+	assert.True(t, strings.HasPrefix(out.String(), "[\n"), "tables are exported as JSON")
+
+	cmd, out = newTestCommands(t, server, "json")
+	path := filepath.Join(dir, "movies.yaml")
+	require.NoError(t, cmd.export(ctx, []string{path}))
+	assert.Empty(t, out.String())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var movies []movie
+	require.NoError(t, yaml.Unmarshal(data, &movies), "the extension chooses the format")
+	require.Len(t, movies, 2)
+	assert.Equal(t, "tt0068646", movies[0].ImdbID)
+
+	assert.ErrorAs(t, cmd.export(ctx, []string{"a.json", "b.json"}), &usageError{})
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"movie-project/config"
+	"movie-project/internal/model"
+	"movie-project/internal/repository"
+	"movie-project/internal/seed"
+	"movie-project/internal/service"
+	"movie-project/pkg/database"
+	"movie-project/pkg/logger"
+)
+
+const usage = `Usage: seed [options]
+
+Populates the configured development database with movies. Movies are created through the
+movie service, so they are validated like movies created through the API. Apply the
+migrations first (go run ./cmd/migrate up).
+
+Options:
+`
+
+func main() {
+	count := flag.Int("count", 50, "number of fake movies to create")
+	seedValue := flag.Uint64("seed", 1, "seed of the fake movies; the same seed creates the same movies")
+	sample := flag.Bool("sample", false, "also create the bundled sample of real movies")
+	truncate := flag.Bool("truncate", false, "delete all movies and restart ids at 1 first; their images stay in the media store")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 0 || *count < 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Environment == config.EnvProduction {
+		log.Fatalf("Refusing to seed a %s database", config.EnvProduction)
+	}
+
+	db, err := database.Open(cfg, logger.Discard())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	ctx := context.Background()
+
+	if *truncate {
+		if err := seed.Truncate(ctx, db); err != nil {
+			log.Fatalf("Failed to truncate movies: %v", err)
+		}
+		log.Println("Deleted all movies")
+	}
+
+	var movies []*model.Movie
+	if *sample {
+		if movies, err = seed.Sample(); err != nil {
+			log.Fatalf("Failed to load sample movies: %v", err)
+		}
+	}
+	movies = append(movies, seed.NewGenerator(*seedValue).Movies(*count)...)
+
+	repo := repository.NewMovieRepository(*db, *logger.Discard())
+	svc := service.NewMovieService(repo, *logger.Discard())
+	for i, movie := range movies {
+		if err := svc.CreateMovie(ctx, movie); err != nil {
+			log.Fatalf("Failed to create movie %q (%d created): %v", movie.Title, i, err)
+		}
+	}
+	log.Printf("Created %d movies", len(movies))
+}
@@ -3,9 +3,11 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"google.golang.org/grpc/metadata"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
@@ -15,131 +17,478 @@ import (
 	"time"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/pflag"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
 
-	"github.com/go-openapi/runtime/middleware"
+	openapi "github.com/go-openapi/runtime/middleware"
 	"movie-project/config"
 	"movie-project/internal/handler"
 	"movie-project/internal/repository"
 	"movie-project/internal/service"
+	"movie-project/migrations"
+	"movie-project/pkg/accesslog"
+	"movie-project/pkg/admin"
+	"movie-project/pkg/auth"
+	"movie-project/pkg/certs"
+	"movie-project/pkg/database"
+	"movie-project/pkg/deadline"
+	"movie-project/pkg/health"
+	"movie-project/pkg/inprocess"
 	"movie-project/pkg/logger"
 	"movie-project/pkg/metrics"
+	"movie-project/pkg/middleware"
+	"movie-project/pkg/ratelimit"
+	"movie-project/pkg/requestid"
+	"movie-project/pkg/storage"
+	"movie-project/pkg/tracing"
 	pb "movie-project/proto/movie"
 )
 
 func main() {
+	// Load configuration: defaults, config file, environment and flags, in increasing precedence
+	loader, err := config.NewLoader(os.Args[1:])
+	if errors.Is(err, pflag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		slog.Error("Failed to read config", "error", err)
+		os.Exit(2)
+	}
+	cfg, err := loader.Load()
+	if err != nil {
+		slog.Error("Invalid config", "error", err)
+		os.Exit(1)
+	}
+
 	// Initialize logger
-	log := logger.NewLogger()
+	log, err := logger.NewLogger(logger.Options{
+		Level:            cfg.LogLevel,
+		Format:           cfg.LogFormat,
+		Output:           cfg.LogOutput,
+		MaxSizeMB:        cfg.LogMaxSizeMB,
+		MaxBackups:       cfg.LogMaxBackups,
+		MaxAgeDays:       cfg.LogMaxAgeDays,
+		Compress:         cfg.LogCompress,
+		AddSource:        cfg.LogAddSource,
+		SampleInitial:    cfg.LogSampleInitial,
+		SampleThereafter: cfg.LogSampleThereafter,
+		SampleInterval:   cfg.LogSampleInterval,
+	})
+	if err != nil {
+		slog.Error("Failed to initialize logger", "error", err)
+		os.Exit(1)
+	}
+
+	if file := loader.ConfigFile(); file != "" {
+		log.Info("Using config file", "file", file)
+	}
 
-	// Load configuration
-	cfg, err := config.LoadConfig()
+	// Initialize tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		ServiceName:  cfg.TracingServiceName,
+		Exporter:     cfg.TracingExporter,
+		OTLPEndpoint: cfg.TracingOTLPEndpoint,
+		OTLPInsecure: cfg.TracingOTLPInsecure,
+		SampleRatio:  cfg.TracingSampleRatio,
+	})
 	if err != nil {
-		log.Error("Failed to load config", "error", err)
+		log.Error("Failed to initialize tracing", "error", err)
 		os.Exit(1)
 	}
 
 	// Initialize database connection
-	db, err := gorm.Open(postgres.Open(cfg.GetDSN()), &gorm.Config{})
+	db, err := database.Open(cfg, log)
 	if err != nil {
 		log.Error("Failed to connect to database", "error", err)
 		os.Exit(1)
 	}
 
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Error("Failed to get database handle", "error", err)
+		os.Exit(1)
+	}
+
+	// Apply pending migrations before serving
+	if cfg.MigrateOnStart {
+		if err := migrations.ApplyOnStart(context.Background(), cfg, sqlDB, log); err != nil {
+			log.Error("Failed to apply migrations", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Initialize storage of movie images
+	store, err := newBlobStore(cfg)
+	if err != nil {
+		log.Error("Failed to initialize media storage", "error", err)
+		os.Exit(1)
+	}
+
 	// Initialize repository, service, and handler
 	repo := repository.NewMovieRepository(*db, *log)
 	svc := service.NewMovieService(repo, *log)
-	movieHandler := handler.NewMovieHandler(svc, *log)
+	mediaSvc := service.NewMediaService(repo, store, service.MediaOptions{
+		MaxSize:       int64(cfg.MediaMaxSizeMB) << 20,
+		ThumbnailSize: cfg.MediaThumbnailSize,
+	}, *log)
+	translationRepo := repository.NewTranslationRepository(*db, *log)
+	translationSvc := service.NewTranslationService(translationRepo, repo, *log)
+	movieHandler := handler.NewMovieHandler(svc, &mediaSvc, &translationSvc, *log)
+	mediaHandler := handler.NewMediaHandler(&mediaSvc, *log)
+	translationHandler := handler.NewTranslationHandler(&translationSvc, *log)
 	//repo := repository.NewMovieRepository(*db, *log)
 	//svc := service.NewMovieService(*repo, *log)
 	//movieHandler := handler.NewMovieHandler(*svc, *log)
 
+	// Initialize health checks: the service is ready when the database answers and the schema is migrated
+	latestMigration, err := migrations.LatestVersion(cfg)
+	if err != nil {
+		log.Error("Failed to read migrations", "error", err)
+		os.Exit(1)
+	}
+	checker := health.NewChecker(cfg.HealthCheckTimeout, pb.MovieService_ServiceDesc.ServiceName, pb.MediaService_ServiceDesc.ServiceName,
+		pb.TranslationService_ServiceDesc.ServiceName)
+	checker.AddCheck("database", sqlDB.PingContext)
+	checker.AddCheck("migrations", func(ctx context.Context) error {
+		return migrations.CheckVersion(ctx, sqlDB, latestMigration)
+	})
+
 	// Initialize Prometheus metrics
 	metrics.InitMetrics()
+	if err := metrics.RegisterDBStats(sqlDB, cfg.DBName); err != nil {
+		log.Error("Failed to register database metrics", "error", err)
+		os.Exit(1)
+	}
+	if replicas := database.Replicas(db); replicas != nil {
+		// named by position, the DSNs carry credentials
+		for i, replica := range replicas.DBs() {
+			if err := metrics.RegisterDBStats(replica, fmt.Sprintf("%s-replica-%d", cfg.DBName, i)); err != nil {
+				log.Error("Failed to register database metrics", "error", err, "replica", i)
+				os.Exit(1)
+			}
+		}
+	}
 
-	// Initialize gRPC server
-	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(metrics.UnaryServerInterceptor),
-	)
-	pb.RegisterMovieServiceServer(grpcServer, &movieHandler)
-	reflection.Register(grpcServer)
+	// Identify callers by their bearer token, before access logging and rate limiting use the subject
+	verifier := auth.NewVerifier(cfg.JWTSecret)
+	authMiddleware := auth.Middleware(verifier)
 
-	// Start gRPC server
-	grpcAddr := fmt.Sprintf("%s:%s", cfg.ServerHost, cfg.GRPCPort)
-	lis, err := net.Listen("tcp", grpcAddr)
+	// Initialize access logging, after request ids so every line carries one
+	unaryInterceptors := []grpc.UnaryServerInterceptor{requestid.UnaryServerInterceptor, auth.UnaryServerInterceptor(verifier)}
+	streamInterceptors := []grpc.StreamServerInterceptor{requestid.StreamServerInterceptor, auth.StreamServerInterceptor(verifier)}
+	accessLogMiddleware := func(next http.Handler) http.Handler { return next }
+	if cfg.AccessLogEnabled {
+		slowMethods, err := accesslog.ParseSlowMethods(cfg.AccessLogSlowMethods)
+		if err != nil {
+			log.Error("Invalid access log configuration", "error", err)
+			os.Exit(1)
+		}
+		accessLogOpts := accesslog.Options{
+			Redact:        cfg.AccessLogRedact,
+			Payloads:      cfg.AccessLogPayloads,
+			SlowThreshold: cfg.AccessLogSlowThreshold,
+			SlowMethods:   slowMethods,
+		}
+		unaryInterceptors = append(unaryInterceptors, accesslog.UnaryServerInterceptor(log, accessLogOpts))
+		streamInterceptors = append(streamInterceptors, accesslog.StreamServerInterceptor(log, accessLogOpts))
+		accessLogMiddleware = accesslog.Middleware(log, accessLogOpts)
+	}
+	unaryInterceptors = append(unaryInterceptors, metrics.UnaryServerInterceptor)
+	streamInterceptors = append(streamInterceptors, metrics.StreamServerInterceptor)
+
+	// Initialize deadlines, after access logging and metrics so they record DeadlineExceeded
+	timeoutMethods, err := deadline.ParseMethods(cfg.RPCTimeoutMethods)
 	if err != nil {
-		log.Error("Failed to listen for gRPC", "error", err)
+		log.Error("Invalid RPC timeout configuration", "error", err)
 		os.Exit(1)
 	}
-	go func() {
-		log.Info("Starting gRPC server", "address", grpcAddr)
-		if err := grpcServer.Serve(lis); err != nil {
-			log.Error("Failed to serve gRPC", "error", err)
+	unaryInterceptors = append(unaryInterceptors, deadline.UnaryServerInterceptor(cfg.RPCTimeoutDefault, timeoutMethods))
+
+	// Initialize rate limiting
+	rateLimitMiddleware := func(next http.Handler) http.Handler { return next }
+	var grpcLimiter, httpLimiter *ratelimit.Limiter
+	if cfg.RateLimitEnabled {
+		limits, err := parseRateLimits(cfg)
+		if err != nil {
+			log.Error("Invalid rate limit configuration", "error", err)
 			os.Exit(1)
 		}
-	}()
+		clients, err := ratelimit.NewClientResolver(cfg.TrustedProxies)
+		if err != nil {
+			log.Error("Invalid rate limit configuration", "error", err)
+			os.Exit(1)
+		}
+		grpcLimiter = ratelimit.NewLimiter(limits.grpcDefault, limits.grpcMethods)
+		httpLimiter = ratelimit.NewLimiter(limits.http, nil)
+		unaryInterceptors = append(unaryInterceptors, ratelimit.UnaryServerInterceptor(grpcLimiter, clients))
+		streamInterceptors = append(streamInterceptors, ratelimit.StreamServerInterceptor(grpcLimiter, clients))
+		rateLimitMiddleware = ratelimit.Middleware(httpLimiter, clients)
+	}
 
-	// Initialize gRPC-Gateway
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Load TLS certificates, reloaded from disk whenever they change
+	grpcOpts := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	}
+	dialCreds := insecure.NewCredentials()
+	var serverCerts *certs.Reloader
+	if cfg.TLSEnabled() {
+		serverCerts, err = certs.NewReloader(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSCAFile, log)
+		if err != nil {
+			log.Error("Failed to load TLS certificates", "error", err)
+			os.Exit(1)
+		}
+		if err := serverCerts.Watch(ctx); err != nil {
+			log.Error("Failed to watch TLS certificates", "error", err)
+			os.Exit(1)
+		}
+	}
+	// In single-port mode TLS is terminated by the HTTP server and the gateway dials gRPC in-process
+	if serverCerts != nil && !cfg.SinglePort {
+		clientCertFile, clientKeyFile := cfg.GetTLSClientCert()
+		gatewayCerts, err := certs.NewReloader(clientCertFile, clientKeyFile, cfg.TLSCAFile, log)
+		if err != nil {
+			log.Error("Failed to load gateway TLS certificates", "error", err)
+			os.Exit(1)
+		}
+		if err := gatewayCerts.Watch(ctx); err != nil {
+			log.Error("Failed to watch TLS certificates", "error", err)
+			os.Exit(1)
+		}
+
+		grpcClientAuth := tls.NoClientCert
+		if cfg.GRPCRequireClientCert {
+			grpcClientAuth = tls.RequireAndVerifyClientCert
+		}
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(serverCerts.ServerConfig(grpcClientAuth))))
+		dialCreds = credentials.NewTLS(gatewayCerts.ClientConfig(cfg.TLSServerName))
+	}
+	dialOpts := []grpc.DialOption{
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		grpc.WithTransportCredentials(dialCreds),
+	}
+
+	// Initialize gRPC server
+	grpcServer := grpc.NewServer(grpcOpts...)
+	pb.RegisterMovieServiceServer(grpcServer, &movieHandler)
+	pb.RegisterMediaServiceServer(grpcServer, &mediaHandler)
+	pb.RegisterTranslationServiceServer(grpcServer, &translationHandler)
+	reflection.Register(grpcServer)
+	checker.Register(grpcServer)
+	go checker.Run(ctx, cfg.HealthCheckInterval)
+	go svc.RunMetricsRefresh(ctx, cfg.MetricsRefreshInterval)
+	if replicas := database.Replicas(db); replicas != nil {
+		// queries fall back to the primary while no replica answers
+		go replicas.Run(ctx, cfg.HealthCheckInterval, cfg.HealthCheckTimeout)
+	}
+
+	// Start gRPC server. In single-port mode external calls arrive through the HTTP server,
+	// and the gateway reaches the server over an in-memory listener.
+	var grpcAddr string
+	if cfg.SinglePort {
+		inProcess := inprocess.Listen()
+		grpcAddr = "passthrough:///in-process"
+		dialOpts = append(dialOpts, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return inProcess.DialContext(ctx)
+		}))
+		go func() {
+			if err := grpcServer.Serve(inProcess); err != nil {
+				log.Error("Failed to serve in-process gRPC", "error", err)
+				os.Exit(1)
+			}
+		}()
+	} else {
+		grpcAddr = fmt.Sprintf("%s:%s", cfg.ServerHost, cfg.GRPCPort)
+		lis, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			log.Error("Failed to listen for gRPC", "error", err)
+			os.Exit(1)
+		}
+		go func() {
+			log.Info("Starting gRPC server", "address", grpcAddr, "tls", cfg.TLSEnabled(), "clientCerts", cfg.GRPCRequireClientCert)
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Error("Failed to serve gRPC", "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// Initialize gRPC-Gateway
 	gwmux := runtime.NewServeMux(
+		runtime.WithOutgoingHeaderMatcher(outgoingHeaderMatcher),
 		runtime.WithMetadata(func(ctx context.Context, req *http.Request) metadata.MD {
+			proto := "http"
+			if req.TLS != nil {
+				proto = "https"
+			}
 			return metadata.Pairs(
 				"x-forwarded-host", req.Host,
-				"x-forwarded-proto", "http", // TODO in prod https
+				"x-forwarded-proto", proto,
+				requestid.MetadataKey, requestid.FromContext(req.Context()),
 			)
 		}),
 	)
-	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
-	err = pb.RegisterMovieServiceHandlerFromEndpoint(ctx, gwmux, grpcAddr, opts)
+	// Every service behind the gateway, multipart uploads included, shares one connection to the gRPC server
+	gatewayConn, err := grpc.NewClient(grpcAddr, dialOpts...)
 	if err != nil {
-		log.Error("Failed to register gRPC-Gateway", "error", err)
+		log.Error("Failed to connect gRPC-Gateway to the gRPC server", "error", err)
+		os.Exit(1)
+	}
+	defer gatewayConn.Close()
+	for _, register := range []func(context.Context, *runtime.ServeMux, *grpc.ClientConn) error{
+		pb.RegisterMovieServiceHandler,
+		pb.RegisterMediaServiceHandler,
+		pb.RegisterTranslationServiceHandler,
+	} {
+		if err := register(ctx, gwmux, gatewayConn); err != nil {
+			log.Error("Failed to register gRPC-Gateway", "error", err)
+			os.Exit(1)
+		}
+	}
+	mediaClient := pb.NewMediaServiceClient(gatewayConn)
+	if err := gwmux.HandlePath(http.MethodPost, handler.UploadPath, handler.NewUploadHandler(gwmux, mediaClient, mediaSvc.MaxSize())); err != nil {
+		log.Error("Failed to register media uploads", "error", err)
 		os.Exit(1)
 	}
 
 	// Create an HTTP server
 	mux := http.NewServeMux()
 	mux.Handle("/", instrumentHandler(gwmux, "grpc_gateway"))
-	mux.Handle("/metrics", promhttp.Handler())
+	if files, ok := store.(*storage.FileStore); ok {
+		mux.Handle(mediaPath+"/", instrumentHandler(http.StripPrefix(mediaPath, files.Handler()), "media"))
+	}
 
 	// Configure CORS
-	corsMiddleware := corsMiddleware(cfg.AllowedOrigins)
+	corsPolicy := middleware.NewCORSPolicy(corsOptions(cfg))
 
 	// Настройка Swagger UI
 	httpAddr := fmt.Sprintf("%s:%s", cfg.ServerHost, cfg.ServerPort)
-	swaggerOpts := middleware.SwaggerUIOpts{SpecURL: fmt.Sprintf("http://%s/api/api.swagger.json", httpAddr)}
-	sh := middleware.SwaggerUI(swaggerOpts, nil)
+	scheme := "http"
+	if cfg.TLSEnabled() {
+		scheme = "https"
+	}
+	swaggerOpts := openapi.SwaggerUIOpts{SpecURL: fmt.Sprintf("%s://%s/api/api.swagger.json", scheme, httpAddr)}
+	sh := openapi.SwaggerUI(swaggerOpts, nil)
 	mux.Handle("/docs", sh)
 	mux.HandleFunc("/api/api.swagger.json", func(w http.ResponseWriter, r *http.Request) {
-		log.Info("Received request for Swagger JSON", "path", r.URL.Path)
 		http.ServeFile(w, r, "api/api.swagger.json")
 	})
 
+	// Health probes bypass tracing, CORS and rate limiting. Traced requests continue the
+	// W3C trace context of the caller through the gateway into gRPC.
+	root := http.NewServeMux()
+	root.Handle("/healthz", checker.LiveHandler())
+	root.Handle("/readyz", checker.ReadyHandler())
+	root.Handle("/", otelhttp.NewHandler(requestid.Middleware(authMiddleware(accessLogMiddleware(corsPolicy.Middleware(rateLimitMiddleware(mux))))), "http",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string { return "HTTP " + r.Method }),
+	))
+
+	var httpHandler http.Handler = root
+	if cfg.SinglePort {
+		httpHandler = middleware.GRPC(grpcServer, cfg.GRPCRequireClientCert)(httpHandler)
+		if serverCerts == nil {
+			// gRPC needs HTTP/2, which without TLS means h2c
+			httpHandler = h2c.NewHandler(httpHandler, &http2.Server{})
+		}
+	}
+
 	srv := &http.Server{
 		Addr:    httpAddr,
-		Handler: corsMiddleware(mux),
+		Handler: httpHandler,
+	}
+	if serverCerts != nil {
+		httpClientAuth := tls.NoClientCert
+		if cfg.SinglePort && cfg.GRPCRequireClientCert {
+			// Browsers and REST clients may connect without a certificate, gRPC calls are checked per request
+			httpClientAuth = tls.VerifyClientCertIfGiven
+		}
+		srv.TLSConfig = serverCerts.ServerConfig(httpClientAuth)
 	}
 
 	// Start HTTP server
 	go func() {
-		log.Info("Starting HTTP server", "address", httpAddr)
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Info("Starting HTTP server", "address", httpAddr, "tls", cfg.TLSEnabled(), "singlePort", cfg.SinglePort)
+		var err error
+		if srv.TLSConfig != nil {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Error("Failed to serve HTTP", "error", err)
 			os.Exit(1)
 		}
 	}()
 
+	// Start admin server: metrics, pprof, expvar, log level and config, kept off the API listener
+	var adminSrv *http.Server
+	if cfg.AdminAddr != "" {
+		adminSrv = &http.Server{Addr: cfg.AdminAddr, Handler: admin.NewHandler(admin.Options{
+			Token:  cfg.AdminToken,
+			Logger: log,
+			Config: func() any { return loader.Current().Masked() },
+		})}
+		go func() {
+			log.Info("Starting admin server", "address", cfg.AdminAddr, "token", cfg.AdminToken != "")
+			if err := adminSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error("Failed to serve admin HTTP", "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// Apply config file changes that are safe without a restart
+	err = loader.Watch(ctx, func(old, new config.Config) {
+		reloadable, restart := config.Changes(old, new)
+		if len(restart) > 0 {
+			log.Warn("Config changes take effect after a restart", "settings", restart)
+		}
+		if len(reloadable) == 0 {
+			return
+		}
+		if err := log.SetLevel(new.LogLevel); err != nil {
+			log.Error("Failed to change log level", "error", err)
+		}
+		corsPolicy.Update(corsOptions(new))
+		if grpcLimiter != nil {
+			limits, err := parseRateLimits(new)
+			if err != nil {
+				log.Error("Invalid rate limit configuration, keeping the current limits", "error", err)
+			} else {
+				grpcLimiter.SetLimits(limits.grpcDefault, limits.grpcMethods)
+				httpLimiter.SetLimits(limits.http, nil)
+			}
+		}
+		log.Info("Reloaded config", "settings", reloadable)
+	}, func(err error) {
+		log.Error("Ignoring invalid config change", "error", err)
+	})
+	if err != nil {
+		log.Error("Failed to watch config file", "error", err)
+		os.Exit(1)
+	}
+
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Info("Shutting down server...")
 
+	// Fail readiness first so load balancers drain before the listeners close
+	checker.Shutdown()
+	time.Sleep(cfg.ShutdownDrainDelay)
+
 	// Shutdown HTTP server
 	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -150,25 +499,89 @@ func main() {
 	// Stop gRPC server
 	grpcServer.GracefulStop()
 
+	// Shutdown admin server, with its own timeout since GracefulStop may have used up the one above
+	if adminSrv != nil {
+		adminCtx, adminCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer adminCancel()
+		if err := adminSrv.Shutdown(adminCtx); err != nil {
+			log.Error("Admin server forced to shutdown", "error", err)
+		}
+	}
+
+	// Flush pending spans
+	if err := shutdownTracing(ctx); err != nil {
+		log.Error("Failed to flush traces", "error", err)
+	}
+
 	log.Info("Server exited")
+	log.Close()
 }
 
-func corsMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*") // Разрешаем все источники
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-			w.Header().Set("Access-Control-Max-Age", "3600")
-
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusNoContent)
-				return
-			}
+// mediaPath is where images of the local media store are served
+const mediaPath = "/media"
 
-			next.ServeHTTP(w, r)
+// newBlobStore returns the store of movie images configured by MEDIA_STORE
+func newBlobStore(cfg config.Config) (storage.BlobStore, error) {
+	if cfg.MediaStore == config.MediaStoreS3 {
+		return storage.NewS3Store(storage.S3Options{
+			Endpoint:  cfg.MediaS3Endpoint,
+			Region:    cfg.MediaS3Region,
+			Bucket:    cfg.MediaS3Bucket,
+			AccessKey: cfg.MediaS3AccessKey,
+			SecretKey: cfg.MediaS3SecretKey,
+			BaseURL:   cfg.MediaBaseURL,
 		})
 	}
+	baseURL := cfg.MediaBaseURL
+	if baseURL == "" {
+		baseURL = mediaPath
+	}
+	return storage.NewFileStore(cfg.MediaDir, baseURL)
+}
+
+// rateLimits are the limits configured for gRPC methods and HTTP requests
+type rateLimits struct {
+	grpcDefault ratelimit.Limit
+	grpcMethods map[string]ratelimit.Limit
+	http        ratelimit.Limit
+}
+
+func parseRateLimits(cfg config.Config) (rateLimits, error) {
+	var limits rateLimits
+	var err error
+	if limits.grpcDefault, err = ratelimit.ParseLimit(cfg.RateLimitDefault); err != nil {
+		return rateLimits{}, err
+	}
+	if limits.grpcMethods, err = ratelimit.ParseMethodLimits(cfg.RateLimitMethods); err != nil {
+		return rateLimits{}, err
+	}
+	if limits.http, err = ratelimit.ParseLimit(cfg.RateLimitHTTP); err != nil {
+		return rateLimits{}, err
+	}
+	return limits, nil
+}
+
+func corsOptions(cfg config.Config) middleware.CORSOptions {
+	return middleware.CORSOptions{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowedMethods:   cfg.CORSAllowedMethods,
+		AllowedHeaders:   cfg.CORSAllowedHeaders,
+		ExposedHeaders:   cfg.CORSExposedHeaders,
+		AllowCredentials: cfg.CORSAllowCredentials,
+		MaxAge:           cfg.CORSMaxAge,
+	}
+}
+
+// outgoingHeaderMatcher passes Retry-After through the gateway as is and prefixes other gRPC headers.
+// The request id is already set by the HTTP middleware.
+func outgoingHeaderMatcher(key string) (string, bool) {
+	switch key {
+	case "retry-after":
+		return "Retry-After", true
+	case requestid.MetadataKey:
+		return "", false
+	}
+	return runtime.MetadataHeaderPrefix + key, true
 }
 
 func instrumentHandler(next http.Handler, handlerName string) http.Handler {
@@ -3,14 +3,31 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
+	"reflect"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
+// Supported values of DB_DRIVER
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Supported values of MEDIA_STORE
+const (
+	MediaStoreLocal = "local"
+	MediaStoreS3    = "s3"
+)
+
 type Config struct {
 	Environment string `mapstructure:"ENVIRONMENT"`
 
+	DBDriver   string `mapstructure:"DB_DRIVER"`
+	DBPath     string `mapstructure:"DB_PATH"`
 	DBHost     string `mapstructure:"DB_HOST"`
 	DBPort     string `mapstructure:"DB_PORT"`
 	DBUser     string `mapstructure:"DB_USER"`
@@ -18,64 +35,286 @@ type Config struct {
 	DBName     string `mapstructure:"DB_NAME"`
 	DBSSLMode  string `mapstructure:"DB_SSLMODE"`
 
+	DBMaxOpenConns     int           `mapstructure:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns     int           `mapstructure:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime  time.Duration `mapstructure:"DB_CONN_MAX_LIFETIME"`
+	DBConnMaxIdleTime  time.Duration `mapstructure:"DB_CONN_MAX_IDLE_TIME"`
+	DBConnectTimeout   time.Duration `mapstructure:"DB_CONNECT_TIMEOUT"`
+	DBStatementTimeout time.Duration `mapstructure:"DB_STATEMENT_TIMEOUT"`
+	DBReplicaDSNs      []string      `mapstructure:"DB_REPLICA_DSNS"`
+
+	MigrationsDir  string `mapstructure:"MIGRATIONS_DIR"`
+	MigrateOnStart bool   `mapstructure:"MIGRATE_ON_START"`
+
 	ServerHost string `mapstructure:"SERVER_HOST"`
 	ServerPort string `mapstructure:"SERVER_PORT"`
 	GRPCPort   string `mapstructure:"GRPC_PORT"`
+	SinglePort bool   `mapstructure:"SINGLE_PORT"`
+
+	HealthCheckInterval time.Duration `mapstructure:"HEALTH_CHECK_INTERVAL"`
+	HealthCheckTimeout  time.Duration `mapstructure:"HEALTH_CHECK_TIMEOUT"`
+	ShutdownDrainDelay  time.Duration `mapstructure:"SHUTDOWN_DRAIN_DELAY"`
+
+	TLSCertFile           string `mapstructure:"TLS_CERT_FILE"`
+	TLSKeyFile            string `mapstructure:"TLS_KEY_FILE"`
+	TLSCAFile             string `mapstructure:"TLS_CA_FILE"`
+	TLSClientCertFile     string `mapstructure:"TLS_CLIENT_CERT_FILE"`
+	TLSClientKeyFile      string `mapstructure:"TLS_CLIENT_KEY_FILE"`
+	TLSServerName         string `mapstructure:"TLS_SERVER_NAME"`
+	GRPCRequireClientCert bool   `mapstructure:"GRPC_REQUIRE_CLIENT_CERT"`
 
 	JWTSecret          string        `mapstructure:"JWT_SECRET"`
 	JWTExpirationHours time.Duration `mapstructure:"JWT_EXPIRATION_HOURS"`
 
-	LogLevel string `mapstructure:"LOG_LEVEL"`
+	LogLevel            string        `mapstructure:"LOG_LEVEL"`
+	LogFormat           string        `mapstructure:"LOG_FORMAT"`
+	LogOutput           string        `mapstructure:"LOG_OUTPUT"`
+	LogMaxSizeMB        int           `mapstructure:"LOG_MAX_SIZE_MB"`
+	LogMaxBackups       int           `mapstructure:"LOG_MAX_BACKUPS"`
+	LogMaxAgeDays       int           `mapstructure:"LOG_MAX_AGE_DAYS"`
+	LogCompress         bool          `mapstructure:"LOG_COMPRESS"`
+	LogAddSource        bool          `mapstructure:"LOG_ADD_SOURCE"`
+	LogSampleInitial    int           `mapstructure:"LOG_SAMPLE_INITIAL"`
+	LogSampleThereafter int           `mapstructure:"LOG_SAMPLE_THEREAFTER"`
+	LogSampleInterval   time.Duration `mapstructure:"LOG_SAMPLE_INTERVAL"`
 
-	AllowedOrigins []string `mapstructure:"ALLOWED_ORIGINS"`
-}
+	AccessLogEnabled       bool          `mapstructure:"ACCESS_LOG_ENABLED"`
+	AccessLogRedact        []string      `mapstructure:"ACCESS_LOG_REDACT"`
+	AccessLogPayloads      bool          `mapstructure:"ACCESS_LOG_PAYLOADS"`
+	AccessLogSlowThreshold time.Duration `mapstructure:"ACCESS_LOG_SLOW_THRESHOLD"`
+	AccessLogSlowMethods   []string      `mapstructure:"ACCESS_LOG_SLOW_METHODS"`
 
-// LoadConfig reads configuration from file or environment variables.
-func LoadConfig() (config Config, err error) {
-	viper.SetConfigName("app")
-	viper.SetConfigType("env")
-	viper.AddConfigPath(".")
-	viper.AddConfigPath("./config")
-	viper.AddConfigPath("/etc/movie-project")
+	AdminAddr  string `mapstructure:"ADMIN_ADDR"`
+	AdminToken string `mapstructure:"ADMIN_TOKEN"`
 
-	viper.AutomaticEnv() // read in environment variables that match
+	MetricsRefreshInterval time.Duration `mapstructure:"METRICS_REFRESH_INTERVAL"`
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
-	}
+	RPCTimeoutDefault time.Duration `mapstructure:"RPC_TIMEOUT_DEFAULT"`
+	RPCTimeoutMethods []string      `mapstructure:"RPC_TIMEOUT_METHODS"`
+
+	MediaStore         string `mapstructure:"MEDIA_STORE"`
+	MediaDir           string `mapstructure:"MEDIA_DIR"`
+	MediaBaseURL       string `mapstructure:"MEDIA_BASE_URL"`
+	MediaMaxSizeMB     int    `mapstructure:"MEDIA_MAX_SIZE_MB"`
+	MediaThumbnailSize int    `mapstructure:"MEDIA_THUMBNAIL_SIZE"`
+	MediaS3Endpoint    string `mapstructure:"MEDIA_S3_ENDPOINT"`
+	MediaS3Region      string `mapstructure:"MEDIA_S3_REGION"`
+	MediaS3Bucket      string `mapstructure:"MEDIA_S3_BUCKET"`
+	MediaS3AccessKey   string `mapstructure:"MEDIA_S3_ACCESS_KEY"`
+	MediaS3SecretKey   string `mapstructure:"MEDIA_S3_SECRET_KEY"`
 
-	// Set default values
-	setDefaults()
+	TracingExporter     string  `mapstructure:"TRACING_EXPORTER"`
+	TracingServiceName  string  `mapstructure:"TRACING_SERVICE_NAME"`
+	TracingOTLPEndpoint string  `mapstructure:"TRACING_OTLP_ENDPOINT"`
+	TracingOTLPInsecure bool    `mapstructure:"TRACING_OTLP_INSECURE"`
+	TracingSampleRatio  float64 `mapstructure:"TRACING_SAMPLE_RATIO"`
 
-	err = viper.Unmarshal(&config)
-	return
+	AllowedOrigins       []string      `mapstructure:"ALLOWED_ORIGINS"`
+	CORSAllowedMethods   []string      `mapstructure:"CORS_ALLOWED_METHODS"`
+	CORSAllowedHeaders   []string      `mapstructure:"CORS_ALLOWED_HEADERS"`
+	CORSExposedHeaders   []string      `mapstructure:"CORS_EXPOSED_HEADERS"`
+	CORSAllowCredentials bool          `mapstructure:"CORS_ALLOW_CREDENTIALS"`
+	CORSMaxAge           time.Duration `mapstructure:"CORS_MAX_AGE"`
+
+	RateLimitEnabled bool     `mapstructure:"RATE_LIMIT_ENABLED"`
+	RateLimitDefault string   `mapstructure:"RATE_LIMIT_DEFAULT"`
+	RateLimitMethods []string `mapstructure:"RATE_LIMIT_METHODS"`
+	RateLimitHTTP    string   `mapstructure:"RATE_LIMIT_HTTP"`
+	TrustedProxies   []string `mapstructure:"TRUSTED_PROXIES"`
+}
+
+// LoadConfig reads and validates the configuration from defaults, a config file and environment
+// variables, for tools and tests that don't take command-line flags
+func LoadConfig() (Config, error) {
+	loader, err := NewLoader(nil)
+	if err != nil {
+		return Config{}, err
+	}
+	return loader.Load()
 }
 
-func setDefaults() {
-	viper.SetDefault("ENVIRONMENT", "development")
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("ENVIRONMENT", "development")
+
+	v.SetDefault("DB_DRIVER", DriverPostgres)
+	v.SetDefault("DB_PATH", "movie.db")
+	v.SetDefault("DB_HOST", "localhost")
+	v.SetDefault("DB_PORT", "5432")
+	v.SetDefault("DB_USER", "postgres")
+	v.SetDefault("DB_PASSWORD", "")
+	v.SetDefault("DB_NAME", "moviedb")
+	v.SetDefault("DB_SSLMODE", "disable")
+
+	// Pool settings apply to the primary and every replica; SQLite always uses a single connection
+	v.SetDefault("DB_MAX_OPEN_CONNS", 25)
+	v.SetDefault("DB_MAX_IDLE_CONNS", 10)
+	v.SetDefault("DB_CONN_MAX_LIFETIME", 30*time.Minute)
+	v.SetDefault("DB_CONN_MAX_IDLE_TIME", 5*time.Minute)
+	v.SetDefault("DB_CONNECT_TIMEOUT", 30*time.Second)  // how long to retry connecting at startup
+	v.SetDefault("DB_STATEMENT_TIMEOUT", 5*time.Second) // 0 disables the per-statement timeout
+	v.SetDefault("DB_REPLICA_DSNS", []string{})         // PostgreSQL read replicas for queries
+
+	v.SetDefault("MIGRATIONS_DIR", "") // empty: use migrations embedded into the binary
+	v.SetDefault("MIGRATE_ON_START", false)
+
+	v.SetDefault("SERVER_HOST", "0.0.0.0")
+	v.SetDefault("SERVER_PORT", "8080")
+	v.SetDefault("GRPC_PORT", "50051")
+	v.SetDefault("SINGLE_PORT", false) // serve gRPC on SERVER_PORT as well, GRPC_PORT is unused
+
+	v.SetDefault("HEALTH_CHECK_INTERVAL", 10*time.Second)
+	v.SetDefault("HEALTH_CHECK_TIMEOUT", 2*time.Second)
+	v.SetDefault("SHUTDOWN_DRAIN_DELAY", 5*time.Second) // time for load balancers to notice NOT_SERVING
+
+	// TLS is enabled when a certificate and key are configured
+	v.SetDefault("TLS_CERT_FILE", "")
+	v.SetDefault("TLS_KEY_FILE", "")
+	v.SetDefault("TLS_CA_FILE", "")
+	v.SetDefault("TLS_CLIENT_CERT_FILE", "")
+	v.SetDefault("TLS_CLIENT_KEY_FILE", "")
+	v.SetDefault("TLS_SERVER_NAME", "localhost")
+	v.SetDefault("GRPC_REQUIRE_CLIENT_CERT", false)
+
+	v.SetDefault("JWT_SECRET", "your-secret-key")
+	v.SetDefault("JWT_EXPIRATION_HOURS", 24)
 
-	viper.SetDefault("DB_HOST", "localhost")
-	viper.SetDefault("DB_PORT", "5432")
-	viper.SetDefault("DB_USER", "postgres")
-	viper.SetDefault("DB_PASSWORD", "")
-	viper.SetDefault("DB_NAME", "moviedb")
-	viper.SetDefault("DB_SSLMODE", "disable")
+	v.SetDefault("LOG_LEVEL", "info")
+	v.SetDefault("LOG_FORMAT", "json")
+	v.SetDefault("LOG_OUTPUT", "stdout") // stdout, stderr or a file path
+	v.SetDefault("LOG_MAX_SIZE_MB", 100)
+	v.SetDefault("LOG_MAX_BACKUPS", 5)
+	v.SetDefault("LOG_MAX_AGE_DAYS", 30)
+	v.SetDefault("LOG_COMPRESS", true)
+	v.SetDefault("LOG_ADD_SOURCE", true)
+	v.SetDefault("LOG_SAMPLE_INITIAL", 100) // 0 disables sampling of debug messages
+	v.SetDefault("LOG_SAMPLE_THEREAFTER", 100)
+	v.SetDefault("LOG_SAMPLE_INTERVAL", time.Second)
 
-	viper.SetDefault("SERVER_HOST", "0.0.0.0")
-	viper.SetDefault("SERVER_PORT", "8080")
-	viper.SetDefault("GRPC_PORT", "50051")
+	// Values of these headers, metadata keys, query parameters and payload fields are never logged
+	v.SetDefault("ACCESS_LOG_ENABLED", true)
+	v.SetDefault("ACCESS_LOG_REDACT", []string{"authorization", "cookie", "set-cookie", "password", "token", "secret"})
+	v.SetDefault("ACCESS_LOG_PAYLOADS", false)
+	v.SetDefault("ACCESS_LOG_SLOW_THRESHOLD", time.Second) // 0 disables slow request warnings
+	v.SetDefault("ACCESS_LOG_SLOW_METHODS", []string{"ListMovies=2s"})
 
-	viper.SetDefault("JWT_SECRET", "your-secret-key")
-	viper.SetDefault("JWT_EXPIRATION_HOURS", 24)
+	v.SetDefault("ADMIN_ADDR", "127.0.0.1:9090") // empty disables the admin server
+	v.SetDefault("ADMIN_TOKEN", "")              // bearer token required by the admin server, if set
 
-	viper.SetDefault("LOG_LEVEL", "info")
+	v.SetDefault("METRICS_REFRESH_INTERVAL", 30*time.Second) // how often gauges like movies per genre are recomputed
 
-	viper.SetDefault("ALLOWED_ORIGINS", []string{"http://localhost:3000"})
+	// Server-side deadlines of unary gRPC calls, "Method=duration" per method; 0 sets none
+	v.SetDefault("RPC_TIMEOUT_DEFAULT", 10*time.Second)
+	v.SetDefault("RPC_TIMEOUT_METHODS", []string{"ListMovies=5s"})
+
+	// Images of movies are stored in MEDIA_DIR and served under /media/, or in an S3-compatible bucket
+	v.SetDefault("MEDIA_STORE", MediaStoreLocal) // local or s3
+	v.SetDefault("MEDIA_DIR", "media")
+	v.SetDefault("MEDIA_BASE_URL", "") // URL prefix of images; empty: /media for local, the bucket URL for s3
+	v.SetDefault("MEDIA_MAX_SIZE_MB", 10)
+	v.SetDefault("MEDIA_THUMBNAIL_SIZE", 320) // thumbnails fit in a square of this many pixels
+	v.SetDefault("MEDIA_S3_ENDPOINT", "")     // e.g. https://s3.eu-west-1.amazonaws.com or http://localhost:9000
+	v.SetDefault("MEDIA_S3_REGION", "us-east-1")
+	v.SetDefault("MEDIA_S3_BUCKET", "")
+	v.SetDefault("MEDIA_S3_ACCESS_KEY", "")
+	v.SetDefault("MEDIA_S3_SECRET_KEY", "")
+
+	v.SetDefault("TRACING_EXPORTER", "none") // none, stdout or otlp
+	v.SetDefault("TRACING_SERVICE_NAME", "movie-service")
+	v.SetDefault("TRACING_OTLP_ENDPOINT", "localhost:4317")
+	v.SetDefault("TRACING_OTLP_INSECURE", true)
+	v.SetDefault("TRACING_SAMPLE_RATIO", 1.0)
+
+	v.SetDefault("ALLOWED_ORIGINS", []string{"http://localhost:3000"})
+	v.SetDefault("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"})
+	v.SetDefault("CORS_ALLOWED_HEADERS", []string{"Content-Type", "Authorization", "If-Match"})
+	v.SetDefault("CORS_EXPOSED_HEADERS", []string{"X-Request-ID"})
+	v.SetDefault("CORS_ALLOW_CREDENTIALS", false)
+	v.SetDefault("CORS_MAX_AGE", time.Hour)
+
+	// Limits are "requests per second:burst" per client
+	v.SetDefault("RATE_LIMIT_ENABLED", true)
+	v.SetDefault("RATE_LIMIT_DEFAULT", "20:40")
+	v.SetDefault("RATE_LIMIT_METHODS", []string{"ListMovies=10:20"})
+	v.SetDefault("RATE_LIMIT_HTTP", "50:100")
+	v.SetDefault("TRUSTED_PROXIES", []string{"127.0.0.1/32", "::1/128"})
+}
+
+// secretKeys are settings whose values are never shown, e.g. in the admin config dump
+var secretKeys = map[string]bool{
+	"DB_PASSWORD":         true,
+	"DB_REPLICA_DSNS":     true,
+	"JWT_SECRET":          true,
+	"ADMIN_TOKEN":         true,
+	"MEDIA_S3_SECRET_KEY": true,
+}
+
+// Masked returns the settings by name with secret values replaced by "****"
+func (c Config) Masked() map[string]any {
+	settings := c.settings()
+	for key, value := range settings {
+		switch v := value.(type) {
+		case time.Duration:
+			settings[key] = v.String()
+		case string:
+			if secretKeys[key] && v != "" {
+				settings[key] = "****"
+			}
+		case []string:
+			if secretKeys[key] && len(v) > 0 {
+				settings[key] = "****"
+			}
+		}
+	}
+	return settings
+}
+
+// settings returns the settings by name
+func (c Config) settings() map[string]any {
+	settings := map[string]any{}
+	v := reflect.ValueOf(c)
+	for i := 0; i < v.NumField(); i++ {
+		if key := v.Type().Field(i).Tag.Get("mapstructure"); key != "" {
+			settings[key] = v.Field(i).Interface()
+		}
+	}
+	return settings
+}
+
+// TLSEnabled reports whether the listeners serve TLS
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// GetTLSClientCert returns the certificate and key the gateway presents to the gRPC server,
+// falling back to the server certificate
+func (c *Config) GetTLSClientCert() (certFile, keyFile string) {
+	if c.TLSClientCertFile != "" && c.TLSClientKeyFile != "" {
+		return c.TLSClientCertFile, c.TLSClientKeyFile
+	}
+	return c.TLSCertFile, c.TLSKeyFile
 }
 
-// GetDSN returns the Data Source Name
+// GetDSN returns the Data Source Name for the configured driver
 func (c *Config) GetDSN() string {
+	if c.DBDriver == DriverSQLite {
+		return c.DBPath
+	}
 	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=UTC",
 		c.DBHost, c.DBUser, c.DBPassword, c.DBName, c.DBPort, c.DBSSLMode)
 }
+
+// GetMigrateURL returns the database URL in the form expected by golang-migrate
+func (c *Config) GetMigrateURL() string {
+	if c.DBDriver == DriverSQLite {
+		return fmt.Sprintf("sqlite3://%s", c.DBPath)
+	}
+	// url.URL escapes credentials and names with reserved characters such as "@" or "/"
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.DBUser, c.DBPassword),
+		Host:     net.JoinHostPort(c.DBHost, c.DBPort),
+		Path:     "/" + c.DBName,
+		RawQuery: url.Values{"sslmode": {c.DBSSLMode}}.Encode(),
+	}
+	return u.String()
+}
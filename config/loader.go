@@ -0,0 +1,224 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Loader reads the configuration from, in increasing precedence, defaults, a config file,
+// environment variables and command-line flags, and reloads it when the config file changes.
+type Loader struct {
+	v *viper.Viper
+
+	mu      sync.Mutex
+	current Config
+}
+
+// NewLoader parses args as command-line flags. Every setting has a flag named after its key,
+// e.g. --log-level for LOG_LEVEL. The config file is taken from --config or CONFIG_FILE and
+// may be .env, .yaml, .yml, .toml or .json; without one, app.* is looked up in the working
+// directory, ./config and /etc/movie-project. A missing file is not an error.
+func NewLoader(args []string) (*Loader, error) {
+	v := viper.New()
+	setDefaults(v)
+	v.AutomaticEnv()
+
+	flags := pflag.NewFlagSet(filepath.Base(os.Args[0]), pflag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "config file (.env, .yaml, .toml or .json)")
+	if err := bindFlags(flags, v); err != nil {
+		return nil, err
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		v.SetConfigFile(*configFile)
+	} else {
+		v.SetConfigName("app")
+		v.AddConfigPath(".")
+		v.AddConfigPath("./config")
+		v.AddConfigPath("/etc/movie-project")
+	}
+	if err := v.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+	return &Loader{v: v}, nil
+}
+
+// bindFlags adds a flag for every setting of Config and binds it to the setting's key
+func bindFlags(flags *pflag.FlagSet, v *viper.Viper) error {
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("mapstructure")
+		if key == "" {
+			continue
+		}
+		name := strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+		usage := "sets " + key
+		if secretKeys[key] {
+			// keep secrets out of --help and, preferably, out of the process list
+			usage += " (prefer the environment variable)"
+		}
+
+		switch field := t.Field(i).Type; {
+		case field == reflect.TypeOf(time.Duration(0)):
+			flags.Duration(name, v.GetDuration(key), usage)
+		case field.Kind() == reflect.String:
+			def := v.GetString(key)
+			if secretKeys[key] {
+				def = ""
+			}
+			flags.String(name, def, usage)
+		case field.Kind() == reflect.Bool:
+			flags.Bool(name, v.GetBool(key), usage)
+		case field.Kind() == reflect.Int:
+			flags.Int(name, v.GetInt(key), usage)
+		case field.Kind() == reflect.Float64:
+			flags.Float64(name, v.GetFloat64(key), usage)
+		case field.Kind() == reflect.Slice && field.Elem().Kind() == reflect.String:
+			flags.StringSlice(name, v.GetStringSlice(key), usage)
+		default:
+			return fmt.Errorf("no flag type for setting %s of type %s", key, field)
+		}
+		if err := v.BindPFlag(key, flags.Lookup(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConfigFile returns the path of the config file in use, or "" when there is none
+func (l *Loader) ConfigFile() string {
+	return l.v.ConfigFileUsed()
+}
+
+// Load reads and validates the configuration. On success it becomes the Current one.
+func (l *Loader) Load() (Config, error) {
+	var config Config
+	if err := l.v.Unmarshal(&config); err != nil {
+		return Config{}, err
+	}
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	l.mu.Lock()
+	l.current = config
+	l.mu.Unlock()
+	return config, nil
+}
+
+// Current returns the configuration last returned by Load
+func (l *Loader) Current() Config {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.current
+}
+
+// Watch reloads the config file whenever it changes until ctx is done. onChange is called with
+// the previous and the new configuration when the file yields a different, valid configuration;
+// onError is called when it doesn't parse or validate, and the current configuration is kept.
+// Watch does nothing without a config file.
+func (l *Loader) Watch(ctx context.Context, onChange func(old, new Config), onError func(error)) error {
+	file := l.ConfigFile()
+	if file == "" {
+		return nil
+	}
+	file = filepath.Clean(file)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// Watch the directory: editors and Kubernetes ConfigMaps replace the file instead of writing it
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				name := filepath.Clean(event.Name)
+				if name != file && !strings.HasPrefix(filepath.Base(name), "..") {
+					continue
+				}
+				if err := l.v.ReadInConfig(); err != nil {
+					onError(fmt.Errorf("failed to read config file: %w", err))
+					continue
+				}
+				old := l.Current()
+				config, err := l.Load()
+				if err != nil {
+					onError(err)
+					continue
+				}
+				if !reflect.DeepEqual(old, config) {
+					onChange(old, config)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				onError(err)
+			}
+		}
+	}()
+	return nil
+}
+
+// reloadableKeys are settings that take effect without a restart when the config file changes
+var reloadableKeys = map[string]bool{
+	"LOG_LEVEL":              true,
+	"RATE_LIMIT_DEFAULT":     true,
+	"RATE_LIMIT_METHODS":     true,
+	"RATE_LIMIT_HTTP":        true,
+	"ALLOWED_ORIGINS":        true,
+	"CORS_ALLOWED_METHODS":   true,
+	"CORS_ALLOWED_HEADERS":   true,
+	"CORS_EXPOSED_HEADERS":   true,
+	"CORS_ALLOW_CREDENTIALS": true,
+	"CORS_MAX_AGE":           true,
+}
+
+// Changes returns the keys of the settings that differ between old and new, split into those
+// applied on reload and those that need a restart
+func Changes(old, new Config) (reloadable, restart []string) {
+	oldSettings, newSettings := old.settings(), new.settings()
+	for key, value := range newSettings {
+		if reflect.DeepEqual(oldSettings[key], value) {
+			continue
+		}
+		if reloadableKeys[key] {
+			reloadable = append(reloadable, key)
+		} else {
+			restart = append(restart, key)
+		}
+	}
+	sort.Strings(reloadable)
+	sort.Strings(restart)
+	return reloadable, restart
+}
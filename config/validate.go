@@ -0,0 +1,109 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// EnvProduction is the ENVIRONMENT in which insecure defaults are rejected
+const EnvProduction = "production"
+
+// defaultJWTSecret is the development default of JWT_SECRET
+const defaultJWTSecret = "your-secret-key"
+
+// minJWTSecretLength is the shortest JWT_SECRET accepted in production, 256 bits for HS256
+const minJWTSecretLength = 32
+
+// Validate reports every invalid setting. In production it also rejects insecure defaults,
+// such as the development JWT_SECRET or an empty DB_PASSWORD.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.DBDriver == DriverPostgres || c.DBDriver == DriverSQLite,
+		"DB_DRIVER must be %q or %q, got %q", DriverPostgres, DriverSQLite, c.DBDriver)
+	check(validPort(c.ServerPort), "SERVER_PORT must be a port number, got %q", c.ServerPort)
+	check(c.SinglePort || validPort(c.GRPCPort), "GRPC_PORT must be a port number, got %q", c.GRPCPort)
+
+	check(c.DBMaxOpenConns >= 0 && c.DBMaxIdleConns >= 0, "DB_MAX_OPEN_CONNS and DB_MAX_IDLE_CONNS must not be negative")
+	check(c.DBConnMaxLifetime >= 0 && c.DBConnMaxIdleTime >= 0, "DB_CONN_MAX_LIFETIME and DB_CONN_MAX_IDLE_TIME must not be negative")
+	check(c.DBConnectTimeout >= 0, "DB_CONNECT_TIMEOUT must not be negative")
+	check(c.DBStatementTimeout >= 0, "DB_STATEMENT_TIMEOUT must not be negative")
+	check(len(c.DBReplicaDSNs) == 0 || c.DBDriver == DriverPostgres, "DB_REPLICA_DSNS are only supported with DB_DRIVER %q", DriverPostgres)
+
+	check(c.HealthCheckInterval > 0, "HEALTH_CHECK_INTERVAL must be positive")
+	check(c.HealthCheckTimeout > 0, "HEALTH_CHECK_TIMEOUT must be positive")
+	check(c.ShutdownDrainDelay >= 0, "SHUTDOWN_DRAIN_DELAY must not be negative")
+	check(c.MetricsRefreshInterval > 0, "METRICS_REFRESH_INTERVAL must be positive")
+	check(c.RPCTimeoutDefault >= 0, "RPC_TIMEOUT_DEFAULT must not be negative")
+	check(c.AccessLogSlowThreshold >= 0, "ACCESS_LOG_SLOW_THRESHOLD must not be negative")
+
+	switch c.MediaStore {
+	case MediaStoreLocal:
+		check(c.MediaDir != "", "MEDIA_DIR must be set for MEDIA_STORE %q", MediaStoreLocal)
+	case MediaStoreS3:
+		check(c.MediaS3Endpoint != "" && c.MediaS3Bucket != "", "MEDIA_S3_ENDPOINT and MEDIA_S3_BUCKET must be set for MEDIA_STORE %q", MediaStoreS3)
+		check(c.MediaS3AccessKey != "" && c.MediaS3SecretKey != "", "MEDIA_S3_ACCESS_KEY and MEDIA_S3_SECRET_KEY must be set for MEDIA_STORE %q", MediaStoreS3)
+	default:
+		check(false, "MEDIA_STORE must be %q or %q, got %q", MediaStoreLocal, MediaStoreS3, c.MediaStore)
+	}
+	check(c.MediaMaxSizeMB > 0, "MEDIA_MAX_SIZE_MB must be positive")
+	check(c.MediaThumbnailSize > 0, "MEDIA_THUMBNAIL_SIZE must be positive")
+
+	check((c.TLSCertFile == "") == (c.TLSKeyFile == ""), "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	check((c.TLSClientCertFile == "") == (c.TLSClientKeyFile == ""), "TLS_CLIENT_CERT_FILE and TLS_CLIENT_KEY_FILE must be set together")
+	check(!c.GRPCRequireClientCert || (c.TLSEnabled() && c.TLSCAFile != ""),
+		"GRPC_REQUIRE_CLIENT_CERT needs TLS_CERT_FILE, TLS_KEY_FILE and TLS_CA_FILE")
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.LogLevel)) == nil, "LOG_LEVEL must be debug, info, warn or error, got %q", c.LogLevel)
+	check(strings.EqualFold(c.LogFormat, "json") || strings.EqualFold(c.LogFormat, "text"), "LOG_FORMAT must be json or text, got %q", c.LogFormat)
+	check(c.LogSampleInitial >= 0 && c.LogSampleThereafter >= 0, "LOG_SAMPLE_INITIAL and LOG_SAMPLE_THEREAFTER must not be negative")
+
+	switch c.TracingExporter {
+	case "none", "stdout", "otlp":
+	default:
+		check(false, "TRACING_EXPORTER must be none, stdout or otlp, got %q", c.TracingExporter)
+	}
+	check(c.TracingSampleRatio >= 0 && c.TracingSampleRatio <= 1, "TRACING_SAMPLE_RATIO must be between 0 and 1, got %v", c.TracingSampleRatio)
+	anyOrigin := slices.ContainsFunc(c.AllowedOrigins, func(origin string) bool { return strings.TrimSpace(origin) == "*" })
+	check(!c.CORSAllowCredentials || !anyOrigin,
+		"ALLOWED_ORIGINS must list the origins instead of \"*\" when CORS_ALLOW_CREDENTIALS is set")
+
+	if c.Environment == EnvProduction {
+		check(c.JWTSecret != defaultJWTSecret && len(c.JWTSecret) >= minJWTSecretLength,
+			"JWT_SECRET must be set to at least %d characters in production", minJWTSecretLength)
+		check(c.DBDriver != DriverPostgres || c.DBPassword != "", "DB_PASSWORD must be set in production")
+		check(c.AdminAddr == "" || c.AdminToken != "" || loopback(c.AdminAddr),
+			"ADMIN_TOKEN must be set in production when ADMIN_ADDR %q is not a loopback address", c.AdminAddr)
+	}
+
+	return errors.Join(errs...)
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n < 65536
+}
+
+// loopback reports whether addr ("host:port") only listens on a loopback interface
+func loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
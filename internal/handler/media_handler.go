@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"movie-project/internal/model"
+	"movie-project/internal/service"
+	"movie-project/pkg/logger"
+	"movie-project/pkg/tracing"
+	pb "movie-project/proto/movie"
+)
+
+type MediaHandler struct {
+	pb.UnimplementedMediaServiceServer
+	service *service.MediaService
+	logger  logger.Logger
+}
+
+func NewMediaHandler(service *service.MediaService, logger logger.Logger) MediaHandler {
+	return MediaHandler{service: service, logger: logger}
+}
+
+func (h *MediaHandler) UploadMedia(stream pb.MediaService_UploadMediaServer) (err error) {
+	ctx, span := tracing.Start(stream.Context(), "MediaHandler.UploadMedia")
+	defer func() { tracing.End(span, err) }()
+
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	info := first.GetInfo()
+	if info == nil {
+		return status.Error(codes.InvalidArgument, "Failed to upload media: the first message must carry the media info")
+	}
+	span.SetAttributes(attribute.Int64("movie.id", info.MovieId), attribute.String("media.kind", info.Kind))
+
+	var data bytes.Buffer
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if req.GetInfo() != nil {
+			return status.Error(codes.InvalidArgument, "Failed to upload media: only the first message may carry the media info")
+		}
+		if int64(data.Len()+len(req.GetChunk())) > h.service.MaxSize() {
+			return status.Errorf(codes.InvalidArgument, "Failed to upload media: larger than the limit of %d bytes", h.service.MaxSize())
+		}
+		data.Write(req.GetChunk())
+	}
+
+	movie, err := h.service.Upload(ctx, uint(info.MovieId), model.MediaKind(info.Kind), info.ContentType, data.Bytes())
+	if err != nil {
+		return errorToStatus(err, "Failed to upload media")
+	}
+	return stream.SendAndClose(modelToProto(movie, h.service))
+}
+
+func (h *MediaHandler) DeleteMedia(ctx context.Context, req *pb.DeleteMediaRequest) (_ *pb.Movie, err error) {
+	ctx, span := tracing.Start(ctx, "MediaHandler.DeleteMedia", attribute.Int64("movie.id", req.MovieId), attribute.String("media.kind", req.Kind))
+	defer func() { tracing.End(span, err) }()
+
+	movie, err := h.service.Delete(ctx, uint(req.MovieId), model.MediaKind(req.Kind))
+	if err != nil {
+		return nil, errorToStatus(err, "Failed to delete media")
+	}
+	return modelToProto(movie, h.service), nil
+}
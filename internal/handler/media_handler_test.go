@@ -0,0 +1,280 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+
+	"movie-project/internal/handler"
+	"movie-project/internal/repository"
+	"movie-project/internal/service"
+	"movie-project/pkg/logger"
+	"movie-project/pkg/storage"
+	pb "movie-project/proto/movie"
+)
+
+const testMaxSize = 256 * 1024
+
+type mediaTestServer struct {
+	testServer
+	store *storage.FileStore
+}
+
+// newMediaTestServer serves the movie and media handlers backed by a fresh SQLite database
+// and a file store over bufconn.
+func newMediaTestServer(t *testing.T) mediaTestServer {
+	store, err := storage.NewFileStore(filepath.Join(t.TempDir(), "media"), "/media")
+	require.NoError(t, err)
+
+	server := newTestServer(t, func(s *grpc.Server, db *gorm.DB) {
+		log := logger.Discard()
+		repo := repository.NewMovieRepository(*db, *log)
+		mediaSvc := service.NewMediaService(repo, store, service.MediaOptions{MaxSize: testMaxSize, ThumbnailSize: 100}, *log)
+		movieHandler := handler.NewMovieHandler(service.NewMovieService(repo, *log), &mediaSvc, nil, *log)
+		mediaHandler := handler.NewMediaHandler(&mediaSvc, *log)
+		pb.RegisterMovieServiceServer(s, &movieHandler)
+		pb.RegisterMediaServiceServer(s, &mediaHandler)
+	})
+	return mediaTestServer{testServer: server, store: store}
+}
+
+// testImage encodes a width x height gradient as PNG or JPEG
+func testImage(t *testing.T, contentType string, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if contentType == "image/png" {
+		require.NoError(t, png.Encode(&buf, img))
+	} else {
+		require.NoError(t, jpeg.Encode(&buf, img, nil))
+	}
+	return buf.Bytes()
+}
+
+// uploadMedia sends info, unless nil, and data in chunks of 1 KiB
+func uploadMedia(client pb.MediaServiceClient, info *pb.MediaInfo, data []byte) (*pb.Movie, error) {
+	stream, err := client.UploadMedia(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if info != nil {
+		err = stream.Send(&pb.UploadMediaRequest{Payload: &pb.UploadMediaRequest_Info{Info: info}})
+	}
+	for len(data) > 0 && err == nil {
+		n := min(len(data), 1024)
+		err = stream.Send(&pb.UploadMediaRequest{Payload: &pb.UploadMediaRequest_Chunk{Chunk: data[:n]}})
+		data = data[n:]
+	}
+	return stream.CloseAndRecv()
+}
+
+// blob returns the content of the blob the URL of the file store points at
+func blob(t *testing.T, store *storage.FileStore, url string) ([]byte, error) {
+	r, err := store.Get(context.Background(), strings.TrimPrefix(url, "/media/"))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func TestMediaHandler_Upload(t *testing.T) {
+	srv := newMediaTestServer(t)
+	ctx := context.Background()
+	created, err := srv.movies.CreateMovie(ctx, createRequest("Fallen Angels"))
+	require.NoError(t, err)
+	assert.Empty(t, created.PosterUrl)
+
+	poster := testImage(t, "image/png", 200, 300)
+	movie, err := uploadMedia(srv.media, &pb.MediaInfo{MovieId: created.Id, Kind: "poster", ContentType: "image/png"}, poster)
+	require.NoError(t, err)
+	assert.Regexp(t, fmt.Sprintf(`^/media/movies/%d/poster-[0-9a-f]{16}\.png$`, created.Id), movie.PosterUrl)
+	assert.Equal(t, strings.TrimSuffix(movie.PosterUrl, ".png")+"-thumb.png", movie.PosterThumbnailUrl)
+	assert.Empty(t, movie.BackdropUrl)
+
+	stored, err := blob(t, srv.store, movie.PosterUrl)
+	require.NoError(t, err)
+	assert.Equal(t, poster, stored)
+	thumbnail, err := blob(t, srv.store, movie.PosterThumbnailUrl)
+	require.NoError(t, err)
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(thumbnail))
+	require.NoError(t, err)
+	assert.Equal(t, "png", format)
+	assert.Equal(t, 67, cfg.Width)
+	assert.Equal(t, 100, cfg.Height)
+
+	backdrop, err := uploadMedia(srv.media, &pb.MediaInfo{MovieId: created.Id, Kind: "backdrop"}, testImage(t, "image/jpeg", 320, 180))
+	require.NoError(t, err, "the content type is detected when not declared")
+	assert.True(t, strings.HasSuffix(backdrop.BackdropUrl, ".jpg"))
+	assert.Equal(t, movie.PosterUrl, backdrop.PosterUrl)
+
+	got, err := srv.movies.GetMovie(ctx, &pb.GetMovieRequest{Id: created.Id})
+	require.NoError(t, err)
+	assert.Equal(t, movie.PosterUrl, got.PosterUrl)
+	assert.Equal(t, backdrop.BackdropThumbnailUrl, got.BackdropThumbnailUrl)
+
+	update := createRequest("Fallen Angels")
+	updated, err := srv.movies.UpdateMovie(ctx, &pb.UpdateMovieRequest{Id: created.Id, Title: "Duo luo tian shi",
+		Director: update.Director, ReleaseDate: update.ReleaseDate, Genre: update.Genre, Rating: update.Rating})
+	require.NoError(t, err)
+	assert.Equal(t, movie.PosterUrl, updated.PosterUrl, "updates keep the images")
+
+	replaced, err := uploadMedia(srv.media, &pb.MediaInfo{MovieId: created.Id, Kind: "poster"}, testImage(t, "image/png", 100, 150))
+	require.NoError(t, err)
+	assert.NotEqual(t, movie.PosterUrl, replaced.PosterUrl)
+	_, err = blob(t, srv.store, movie.PosterUrl)
+	assert.ErrorIs(t, err, storage.ErrNotFound, "the replaced poster is deleted")
+	_, err = blob(t, srv.store, movie.PosterThumbnailUrl)
+	assert.ErrorIs(t, err, storage.ErrNotFound, "the replaced thumbnail is deleted")
+
+	deleted, err := srv.media.DeleteMedia(ctx, &pb.DeleteMediaRequest{MovieId: created.Id, Kind: "poster"})
+	require.NoError(t, err)
+	assert.Empty(t, deleted.PosterUrl)
+	assert.Empty(t, deleted.PosterThumbnailUrl)
+	assert.Equal(t, backdrop.BackdropUrl, deleted.BackdropUrl)
+	_, err = blob(t, srv.store, replaced.PosterUrl)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	_, err = srv.media.DeleteMedia(ctx, &pb.DeleteMediaRequest{MovieId: created.Id, Kind: "poster"})
+	assert.NoError(t, err, "deleting a missing image is not an error")
+}
+
+func TestMediaHandler_UploadErrors(t *testing.T) {
+	srv := newMediaTestServer(t)
+	created, err := srv.movies.CreateMovie(context.Background(), createRequest("Happy Together"))
+	require.NoError(t, err)
+	png := testImage(t, "image/png", 20, 30)
+
+	for name, tt := range map[string]struct {
+		info *pb.MediaInfo
+		data []byte
+		code codes.Code
+	}{
+		"unknown kind":      {&pb.MediaInfo{MovieId: created.Id, Kind: "trailer"}, png, codes.InvalidArgument},
+		"empty":             {&pb.MediaInfo{MovieId: created.Id, Kind: "poster"}, nil, codes.InvalidArgument},
+		"not an image":      {&pb.MediaInfo{MovieId: created.Id, Kind: "poster"}, []byte("GIF89a not really"), codes.InvalidArgument},
+		"corrupt image":     {&pb.MediaInfo{MovieId: created.Id, Kind: "poster"}, png[:len(png)/2], codes.InvalidArgument},
+		"wrong type":        {&pb.MediaInfo{MovieId: created.Id, Kind: "poster", ContentType: "image/jpeg"}, png, codes.InvalidArgument},
+		"too large":         {&pb.MediaInfo{MovieId: created.Id, Kind: "poster"}, make([]byte, testMaxSize+1), codes.InvalidArgument},
+		"movie not found":   {&pb.MediaInfo{MovieId: 4242, Kind: "poster"}, png, codes.NotFound},
+		"no info":           {nil, png, codes.InvalidArgument},
+		"type with params":  {&pb.MediaInfo{MovieId: created.Id, Kind: "poster", ContentType: "image/png; q=1"}, png, codes.OK},
+		"declared and kind": {&pb.MediaInfo{MovieId: created.Id, Kind: "backdrop", ContentType: "image/png"}, png, codes.OK},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := uploadMedia(srv.media, tt.info, tt.data)
+			assert.Equal(t, tt.code, status.Code(err), "%v", err)
+		})
+	}
+
+	_, err = srv.media.DeleteMedia(context.Background(), &pb.DeleteMediaRequest{MovieId: created.Id, Kind: "trailer"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = srv.media.DeleteMedia(context.Background(), &pb.DeleteMediaRequest{MovieId: 4242, Kind: "poster"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// multipartBody returns a form with the file field and its content type
+func multipartBody(t *testing.T, contentType string, data []byte) (io.Reader, string) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	require.NoError(t, form.WriteField("comment", "fields before the file are skipped"))
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="file"; filename="poster"`)
+	header.Set("Content-Type", contentType)
+	part, err := form.CreatePart(header)
+	require.NoError(t, err)
+	part.Write(data)
+	require.NoError(t, form.Close())
+	return &body, form.FormDataContentType()
+}
+
+func TestMediaHandler_MultipartUpload(t *testing.T) {
+	srv := newMediaTestServer(t)
+	created, err := srv.movies.CreateMovie(context.Background(), createRequest("Days of Being Wild"))
+	require.NoError(t, err)
+
+	gwmux := runtime.NewServeMux()
+	require.NoError(t, pb.RegisterMediaServiceHandlerClient(context.Background(), gwmux, srv.media))
+	require.NoError(t, gwmux.HandlePath(http.MethodPost, handler.UploadPath, handler.NewUploadHandler(gwmux, srv.media, testMaxSize)))
+	gateway := httptest.NewServer(gwmux)
+	defer gateway.Close()
+	url := fmt.Sprintf("%s/v1/movies/%d/media/poster", gateway.URL, created.Id)
+
+	post := func(url, contentType string, data []byte) *http.Response {
+		t.Helper()
+		body, formType := multipartBody(t, contentType, data)
+		resp, err := http.Post(url, formType, body)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	resp := post(url, "image/jpeg", testImage(t, "image/jpeg", 40, 60))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var movie struct {
+		PosterURL          string `json:"posterUrl"`
+		PosterThumbnailURL string `json:"posterThumbnailUrl"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&movie))
+	assert.True(t, strings.HasSuffix(movie.PosterURL, ".jpg"))
+	assert.NotEmpty(t, movie.PosterThumbnailURL)
+
+	resp = post(url, "application/octet-stream", testImage(t, "image/png", 40, 60))
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "generic content types are detected")
+
+	resp = post(url, "image/gif", []byte("GIF89a"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+	var st struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&st))
+	assert.Equal(t, int(codes.InvalidArgument), st.Code)
+	assert.Contains(t, st.Message, "image/gif")
+
+	resp = post(url, "image/png", make([]byte, testMaxSize+1))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	resp = post(url, "image/png", []byte("not a png"))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = post(fmt.Sprintf("%s/v1/movies/4242/media/poster", gateway.URL), "image/png", testImage(t, "image/png", 4, 4))
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Post(url, "application/json", strings.NewReader("{}"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "uploads must be multipart")
+
+	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
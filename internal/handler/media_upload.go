@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"movie-project/pkg/imaging"
+	pb "movie-project/proto/movie"
+)
+
+// UploadPath is the HTTP route of multipart media uploads
+const UploadPath = "/v1/movies/{movie_id}/media/{kind}"
+
+// uploadChunkSize is the size of the chunks multipart uploads are streamed to gRPC in
+const uploadChunkSize = 64 * 1024
+
+// NewUploadHandler returns the handler of POST UploadPath, to register with mux.HandlePath.
+// The image is read from the "file" field of the multipart form and streamed to UploadMedia
+// through client, so uploads pass the same interceptors as gRPC calls. Responses are written
+// like those of the gateway; files larger than maxSize are rejected with 413, and files
+// declared in a format other than JPEG or PNG with 415.
+func NewUploadHandler(mux *runtime.ServeMux, client pb.MediaServiceClient, maxSize int64) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+		ctx, err := runtime.AnnotateContext(r.Context(), mux, r, "/movie.MediaService/UploadMedia", runtime.WithHTTPPathPattern(UploadPath))
+		if err != nil {
+			runtime.HTTPError(r.Context(), mux, outbound, w, r, err)
+			return
+		}
+		movieID, err := strconv.ParseInt(pathParams["movie_id"], 10, 64)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, r, status.Errorf(codes.InvalidArgument, "invalid movie_id %q", pathParams["movie_id"]))
+			return
+		}
+
+		// Leave room for the other parts and headers of the form
+		r.Body = http.MaxBytesReader(w, r.Body, maxSize+1024*1024)
+		file, contentType, err := formFile(r, "file")
+		if err != nil {
+			writeError(w, outbound, http.StatusBadRequest, status.New(codes.InvalidArgument, err.Error()))
+			return
+		}
+		if contentType != "" {
+			if _, ok := imaging.Extensions[contentType]; !ok {
+				writeError(w, outbound, http.StatusUnsupportedMediaType,
+					status.Newf(codes.InvalidArgument, "unsupported content type %s, expected image/jpeg or image/png", contentType))
+				return
+			}
+		}
+
+		info := &pb.MediaInfo{MovieId: movieID, Kind: pathParams["kind"], ContentType: contentType}
+		var md runtime.ServerMetadata
+		movie, err := upload(ctx, client, info, io.LimitReader(file, maxSize+1), maxSize, &md)
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.Is(err, errTooLarge), errors.As(err, &maxBytesErr):
+			writeError(w, outbound, http.StatusRequestEntityTooLarge,
+				status.Newf(codes.InvalidArgument, "file is larger than the limit of %d bytes", maxSize))
+			return
+		case err != nil:
+			runtime.HTTPError(runtime.NewServerMetadataContext(ctx, md), mux, outbound, w, r, err)
+			return
+		}
+		runtime.ForwardResponseMessage(runtime.NewServerMetadataContext(ctx, md), mux, outbound, w, r, movie)
+	}
+}
+
+// errTooLarge is returned by upload for files larger than the limit
+var errTooLarge = errors.New("file too large")
+
+// upload streams file to UploadMedia, recording the response metadata in md
+func upload(ctx context.Context, client pb.MediaServiceClient, info *pb.MediaInfo, file io.Reader, maxSize int64, md *runtime.ServerMetadata) (*pb.Movie, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := client.UploadMedia(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send fails with io.EOF when the server ended the call; CloseAndRecv returns its status
+	err = stream.Send(&pb.UploadMediaRequest{Payload: &pb.UploadMediaRequest_Info{Info: info}})
+	buf := make([]byte, uploadChunkSize)
+	var size int64
+	for err == nil {
+		n, readErr := file.Read(buf)
+		if size += int64(n); size > maxSize {
+			return nil, errTooLarge
+		}
+		if n > 0 {
+			err = stream.Send(&pb.UploadMediaRequest{Payload: &pb.UploadMediaRequest_Chunk{Chunk: buf[:n]}})
+		}
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	movie, err := stream.CloseAndRecv()
+	if header, headerErr := stream.Header(); headerErr == nil {
+		md.HeaderMD = header
+	}
+	md.TrailerMD = stream.Trailer()
+	return movie, err
+}
+
+// formFile returns the part of the multipart form named field and its declared content type,
+// "" when the client declared none or a generic one
+func formFile(r *http.Request, field string) (io.Reader, string, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, "", err
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, "", errors.New("no " + field + " in the form")
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if part.FormName() != field {
+			continue
+		}
+		contentType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if err != nil || contentType == "application/octet-stream" {
+			contentType = ""
+		}
+		return part, contentType, nil
+	}
+}
+
+// writeError writes st like the gateway writes errors, but with the given HTTP status code
+func writeError(w http.ResponseWriter, marshaler runtime.Marshaler, code int, st *status.Status) {
+	body, err := marshaler.Marshal(st.Proto())
+	if err != nil {
+		http.Error(w, st.Message(), code)
+		return
+	}
+	w.Header().Set("Content-Type", marshaler.ContentType(st.Proto()))
+	w.WriteHeader(code)
+	w.Write(body)
+}
@@ -3,6 +3,10 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/text/language"
 	"gorm.io/gorm"
 	"movie-project/internal/service"
 
@@ -12,67 +16,102 @@ import (
 
 	"movie-project/internal/model"
 	"movie-project/pkg/logger"
+	"movie-project/pkg/tracing"
 	pb "movie-project/proto/movie"
 )
 
 type MovieHandler struct {
 	pb.UnimplementedMovieServiceServer
-	service service.MovieService
-	logger  logger.Logger
+	service      service.MovieService
+	media        *service.MediaService
+	translations *service.TranslationService
+	logger       logger.Logger
 }
 
-func NewMovieHandler(service service.MovieService, logger logger.Logger) MovieHandler {
-	return MovieHandler{service: service, logger: logger}
+// NewMovieHandler returns the handler of the MovieService. Movies carry the URLs of their
+// images when media is set, and GetMovie and ListMovies return them localized when
+// translations is set.
+func NewMovieHandler(service service.MovieService, media *service.MediaService, translations *service.TranslationService, logger logger.Logger) MovieHandler {
+	return MovieHandler{service: service, media: media, translations: translations, logger: logger}
 }
 
-func (h *MovieHandler) CreateMovie(ctx context.Context, req *pb.CreateMovieRequest) (*pb.Movie, error) {
+func (h *MovieHandler) CreateMovie(ctx context.Context, req *pb.CreateMovieRequest) (_ *pb.Movie, err error) {
+	ctx, span := tracing.Start(ctx, "MovieHandler.CreateMovie")
+	defer func() { tracing.End(span, err) }()
+
 	movie := &model.Movie{
-		Title:       req.Title,
-		Director:    req.Director,
-		ReleaseDate: req.ReleaseDate.AsTime(),
-		Genre:       req.Genre,
-		Rating:      req.Rating,
+		Title:               req.Title,
+		Director:            req.Director,
+		ReleaseDate:         req.ReleaseDate.AsTime(),
+		Genre:               req.Genre,
+		Rating:              req.Rating,
+		Synopsis:            req.Synopsis,
+		Tagline:             req.Tagline,
+		RuntimeMinutes:      req.RuntimeMinutes,
+		ProductionCountries: req.ProductionCountries,
+		OriginalLanguage:    req.OriginalLanguage,
+		Certifications:      req.Certifications,
+		Budget:              req.Budget,
+		BoxOffice:           req.BoxOffice,
+		ImdbID:              req.ImdbId,
+		TmdbID:              req.TmdbId,
 	}
 
-	err := h.service.CreateMovie(ctx, movie)
+	err = h.service.CreateMovie(ctx, movie)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "Failed to create movie: %v", err)
+		return nil, errorToStatus(err, "Failed to create movie")
 	}
 
-	return modelToProto(movie), nil
+	return modelToProto(movie, h.media), nil
 }
 
-func (h *MovieHandler) GetMovie(ctx context.Context, req *pb.GetMovieRequest) (*pb.Movie, error) {
+func (h *MovieHandler) GetMovie(ctx context.Context, req *pb.GetMovieRequest) (_ *pb.Movie, err error) {
+	ctx, span := tracing.Start(ctx, "MovieHandler.GetMovie", attribute.Int64("movie.id", req.Id))
+	defer func() { tracing.End(span, err) }()
+
+	prefs, err := preferredLanguages(ctx, req.Locale)
+	if err != nil {
+		return nil, err
+	}
+
 	movie, err := h.service.GetMovie(ctx, uint(req.Id))
 	if err != nil {
-		return nil, status.Errorf(codes.NotFound, "Movie not found: %v", err)
+		return nil, errorToStatus(err, "Failed to get movie")
 	}
 
-	return modelToProto(movie), nil
+	pbMovie := modelToProto(movie, h.media)
+	if err := h.localize(ctx, prefs, []*model.Movie{movie}, []*pb.Movie{pbMovie}); err != nil {
+		return nil, errorToStatus(err, "Failed to get movie")
+	}
+	return pbMovie, nil
 }
 
-func (h *MovieHandler) ListMovies(ctx context.Context, req *pb.ListMoviesRequest) (*pb.ListMoviesResponse, error) {
+func (h *MovieHandler) ListMovies(ctx context.Context, req *pb.ListMoviesRequest) (_ *pb.ListMoviesResponse, err error) {
+	ctx, span := tracing.Start(ctx, "MovieHandler.ListMovies")
+	defer func() { tracing.End(span, err) }()
+
 	if req.PageNumber < 1 {
 		req.PageNumber = 1
 	}
 	if req.PageSize < 1 {
 		req.PageSize = 10 // или любое другое значение по умолчанию
 	}
-
-	h.logger.InfoContext(ctx, "Listing movies request", "pageNumber", req.PageNumber, "pageSize", req.PageSize)
+	prefs, err := preferredLanguages(ctx, req.Locale)
+	if err != nil {
+		return nil, err
+	}
 
 	movies, total, err := h.service.ListMovies(ctx, int(req.PageNumber), int(req.PageSize))
 	if err != nil {
-		h.logger.ErrorContext(ctx, "Failed to list movies", "error", err)
-		return nil, status.Errorf(codes.Internal, "Failed to list movies: %v", err)
+		return nil, errorToStatus(err, "Failed to list movies")
 	}
 
-	h.logger.InfoContext(ctx, "Movies retrieved", "count", len(movies), "total", total)
-
 	pbMovies := make([]*pb.Movie, len(movies))
 	for i, movie := range movies {
-		pbMovies[i] = modelToProto(movie)
-		h.logger.InfoContext(ctx, "Movie converted to proto", "id", movie.ID, "title", movie.Title)
+		pbMovies[i] = modelToProto(movie, h.media)
+	}
+	if err := h.localize(ctx, prefs, movies, pbMovies); err != nil {
+		return nil, errorToStatus(err, "Failed to list movies")
 	}
 
 	response := &pb.ListMoviesResponse{
@@ -80,45 +119,167 @@ func (h *MovieHandler) ListMovies(ctx context.Context, req *pb.ListMoviesRequest
 		TotalCount: int32(total),
 	}
 
-	h.logger.InfoContext(ctx, "Response prepared", "moviesCount", len(response.Movies), "totalCount", response.TotalCount)
-
 	return response, nil
 }
 
-func (h *MovieHandler) UpdateMovie(ctx context.Context, req *pb.UpdateMovieRequest) (*pb.Movie, error) {
+func (h *MovieHandler) UpdateMovie(ctx context.Context, req *pb.UpdateMovieRequest) (_ *pb.Movie, err error) {
+	ctx, span := tracing.Start(ctx, "MovieHandler.UpdateMovie", attribute.Int64("movie.id", req.Id))
+	defer func() { tracing.End(span, err) }()
+
 	movie := &model.Movie{
-		Model:       gorm.Model{ID: uint(req.Id)},
-		Title:       req.Title,
-		Director:    req.Director,
-		ReleaseDate: req.ReleaseDate.AsTime(),
-		Genre:       req.Genre,
-		Rating:      req.Rating,
+		Model:               gorm.Model{ID: uint(req.Id)},
+		Title:               req.Title,
+		Director:            req.Director,
+		ReleaseDate:         req.ReleaseDate.AsTime(),
+		Genre:               req.Genre,
+		Rating:              req.Rating,
+		Synopsis:            req.Synopsis,
+		Tagline:             req.Tagline,
+		RuntimeMinutes:      req.RuntimeMinutes,
+		ProductionCountries: req.ProductionCountries,
+		OriginalLanguage:    req.OriginalLanguage,
+		Certifications:      req.Certifications,
+		Budget:              req.Budget,
+		BoxOffice:           req.BoxOffice,
+		ImdbID:              req.ImdbId,
+		TmdbID:              req.TmdbId,
 	}
 
-	err := h.service.UpdateMovie(ctx, movie)
+	fields, err := updateFields(req)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "Failed to update movie: %v", err)
+		return nil, err
 	}
+	err = h.service.UpdateMovie(ctx, movie, fields...)
+	if err != nil {
+		return nil, errorToStatus(err, "Failed to update movie")
+	}
+
+	return modelToProto(movie, h.media), nil
+}
 
-	return modelToProto(movie), nil
+// movieFields are the fields of model.Movie by the names of the UpdateMovieRequest fields setting them
+var movieFields = map[string]string{
+	"title":                "Title",
+	"director":             "Director",
+	"release_date":         "ReleaseDate",
+	"genre":                "Genre",
+	"rating":               "Rating",
+	"synopsis":             "Synopsis",
+	"tagline":              "Tagline",
+	"runtime_minutes":      "RuntimeMinutes",
+	"production_countries": "ProductionCountries",
+	"original_language":    "OriginalLanguage",
+	"certifications":       "Certifications",
+	"budget":               "Budget",
+	"box_office":           "BoxOffice",
+	"imdb_id":              "ImdbID",
+	"tmdb_id":              "TmdbID",
 }
 
-func (h *MovieHandler) DeleteMovie(ctx context.Context, req *pb.DeleteMovieRequest) (*pb.DeleteMovieResponse, error) {
-	err := h.service.DeleteMovie(ctx, uint(req.Id))
+// updateFields returns the fields of model.Movie to replace for req: those of its update mask, or
+// none for all of them without a mask or with "*"
+func updateFields(req *pb.UpdateMovieRequest) ([]string, error) {
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) == 0 || (len(paths) == 1 && paths[0] == "*") {
+		return nil, nil
+	}
+	fields := make([]string, 0, len(paths))
+	for _, path := range paths {
+		field, ok := movieFields[path]
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "Failed to update movie: update_mask has unknown field %q", path)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func (h *MovieHandler) DeleteMovie(ctx context.Context, req *pb.DeleteMovieRequest) (_ *pb.DeleteMovieResponse, err error) {
+	ctx, span := tracing.Start(ctx, "MovieHandler.DeleteMovie", attribute.Int64("movie.id", req.Id))
+	defer func() { tracing.End(span, err) }()
+
+	err = h.service.DeleteMovie(ctx, uint(req.Id))
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "Failed to delete movie: %v", err)
+		return nil, errorToStatus(err, "Failed to delete movie")
 	}
 
 	return &pb.DeleteMovieResponse{Success: true}, nil
 }
 
-func modelToProto(movie *model.Movie) *pb.Movie {
-	return &pb.Movie{
-		Id:          int64(movie.ID),
-		Title:       movie.Title,
-		Director:    movie.Director,
-		ReleaseDate: timestamppb.New(movie.ReleaseDate),
-		Genre:       movie.Genre,
-		Rating:      movie.Rating,
+// localize replaces the text of pbMovies with the translations of movies that best match prefs,
+// keeping the original text in the original_* fields
+func (h *MovieHandler) localize(ctx context.Context, prefs []language.Tag, movies []*model.Movie, pbMovies []*pb.Movie) error {
+	if len(prefs) == 0 {
+		return nil
+	}
+	for _, pbMovie := range pbMovies {
+		pbMovie.OriginalTitle = pbMovie.Title
+		pbMovie.OriginalSynopsis = pbMovie.Synopsis
+		pbMovie.OriginalGenre = pbMovie.Genre
+	}
+	if h.translations == nil {
+		return nil
+	}
+	localized, err := h.translations.Localize(ctx, prefs, movies...)
+	if err != nil {
+		return err
+	}
+	for i, l := range localized {
+		pbMovies[i].Locale = l.Language
+		pbMovies[i].Title = l.Title
+		pbMovies[i].Synopsis = l.Synopsis
+		pbMovies[i].Genre = l.Genre
+	}
+	return nil
+}
+
+// errorToStatus maps service errors to gRPC status codes
+func errorToStatus(err error, msg string) error {
+	var validationErrors validator.ValidationErrors
+	switch {
+	case errors.Is(err, service.ErrTranslationNotFound):
+		return status.Errorf(codes.NotFound, "%s: %v", msg, err)
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return status.Errorf(codes.AlreadyExists, "%s: a movie with the same IMDb or TMDB id already exists", msg)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return status.Errorf(codes.NotFound, "Movie not found: %v", err)
+	case errors.As(err, &validationErrors), errors.Is(err, service.ErrInvalidMedia):
+		return status.Errorf(codes.InvalidArgument, "%s: %v", msg, err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Errorf(codes.DeadlineExceeded, "%s: deadline exceeded", msg)
+	case errors.Is(err, context.Canceled):
+		return status.Errorf(codes.Canceled, "%s: canceled", msg)
+	default:
+		return status.Errorf(codes.Internal, "%s: %v", msg, err)
+	}
+}
+
+func modelToProto(movie *model.Movie, media *service.MediaService) *pb.Movie {
+	m := &pb.Movie{
+		Id:                  int64(movie.ID),
+		Title:               movie.Title,
+		Director:            movie.Director,
+		ReleaseDate:         timestamppb.New(movie.ReleaseDate),
+		Genre:               movie.Genre,
+		Rating:              movie.Rating,
+		Synopsis:            movie.Synopsis,
+		Tagline:             movie.Tagline,
+		RuntimeMinutes:      movie.RuntimeMinutes,
+		ProductionCountries: movie.ProductionCountries,
+		OriginalLanguage:    movie.OriginalLanguage,
+		Certifications:      movie.Certifications,
+		Budget:              movie.Budget,
+		BoxOffice:           movie.BoxOffice,
+		ImdbId:              movie.ImdbID,
+		TmdbId:              movie.TmdbID,
+	}
+	if media != nil && movie.PosterKey != "" {
+		m.PosterUrl = media.URL(movie.PosterKey)
+		m.PosterThumbnailUrl = media.URL(model.ThumbnailKey(movie.PosterKey))
+	}
+	if media != nil && movie.BackdropKey != "" {
+		m.BackdropUrl = media.URL(movie.BackdropKey)
+		m.BackdropThumbnailUrl = media.URL(model.ThumbnailKey(movie.BackdropKey))
 	}
+	return m
 }
@@ -0,0 +1,377 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+
+	"movie-project/config"
+	"movie-project/internal/handler"
+	"movie-project/internal/repository"
+	"movie-project/internal/service"
+	"movie-project/migrations"
+	"movie-project/pkg/database"
+	"movie-project/pkg/deadline"
+	"movie-project/pkg/logger"
+	pb "movie-project/proto/movie"
+)
+
+// testServer is a gRPC server backed by a fresh SQLite database, reached over bufconn
+type testServer struct {
+	movies       pb.MovieServiceClient
+	media        pb.MediaServiceClient
+	translations pb.TranslationServiceClient
+}
+
+// newTestServer serves the handlers that register adds for a fresh SQLite database over bufconn.
+// Clients of services that aren't registered fail with codes.Unimplemented.
+func newTestServer(t *testing.T, register func(s *grpc.Server, db *gorm.DB), opts ...grpc.ServerOption) testServer {
+	db, err := database.Open(config.Config{
+		DBDriver: config.DriverSQLite,
+		DBPath:   filepath.Join(t.TempDir(), "movie.db"),
+	}, logger.Discard())
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(migrations.Models...))
+
+	lis := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer(opts...)
+	register(grpcServer, db)
+	go grpcServer.Serve(lis)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+		grpcServer.Stop()
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	return testServer{
+		movies:       pb.NewMovieServiceClient(conn),
+		media:        pb.NewMediaServiceClient(conn),
+		translations: pb.NewTranslationServiceClient(conn),
+	}
+}
+
+// registerMovies registers a MovieHandler without media nor translations
+func registerMovies(s *grpc.Server, db *gorm.DB) {
+	log := logger.Discard()
+	repo := repository.NewMovieRepository(*db, *log)
+	movieHandler := handler.NewMovieHandler(service.NewMovieService(repo, *log), nil, nil, *log)
+	pb.RegisterMovieServiceServer(s, &movieHandler)
+}
+
+// newTestClient serves a MovieHandler backed by a fresh SQLite database over bufconn.
+func newTestClient(t *testing.T, opts ...grpc.ServerOption) pb.MovieServiceClient {
+	return newTestServer(t, registerMovies, opts...).movies
+}
+
+// newTestGateway exposes the movie and translation services of server through grpc-gateway on an httptest server.
+func newTestGateway(t *testing.T, server testServer) *httptest.Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	gwmux := runtime.NewServeMux()
+	require.NoError(t, pb.RegisterMovieServiceHandlerClient(ctx, gwmux, server.movies))
+	require.NoError(t, pb.RegisterTranslationServiceHandlerClient(ctx, gwmux, server.translations))
+
+	srv := httptest.NewServer(gwmux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func createRequest(title string) *pb.CreateMovieRequest {
+	return &pb.CreateMovieRequest{
+		Title:       title,
+		Director:    "Wong Kar-wai",
+		ReleaseDate: timestamppb.New(time.Date(2000, 5, 22, 0, 0, 0, 0, time.UTC)),
+		Genre:       "Romance",
+		Rating:      8.1,
+	}
+}
+
+func TestMovieHandler_CRUD(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	created, err := client.CreateMovie(ctx, createRequest("In the Mood for Love"))
+	require.NoError(t, err)
+	require.NotZero(t, created.Id)
+	assert.Equal(t, "In the Mood for Love", created.Title)
+
+	got, err := client.GetMovie(ctx, &pb.GetMovieRequest{Id: created.Id})
+	require.NoError(t, err)
+	assert.Equal(t, created.Title, got.Title)
+	assert.Equal(t, float32(8.1), got.Rating)
+	assert.True(t, created.ReleaseDate.AsTime().Equal(got.ReleaseDate.AsTime()))
+
+	updated, err := client.UpdateMovie(ctx, &pb.UpdateMovieRequest{
+		Id:          created.Id,
+		Title:       "2046",
+		Director:    "Wong Kar-wai",
+		ReleaseDate: timestamppb.New(time.Date(2004, 9, 29, 0, 0, 0, 0, time.UTC)),
+		Genre:       "Drama",
+		Rating:      7.4,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "2046", updated.Title)
+
+	deleted, err := client.DeleteMovie(ctx, &pb.DeleteMovieRequest{Id: created.Id})
+	require.NoError(t, err)
+	assert.True(t, deleted.Success)
+
+	_, err = client.GetMovie(ctx, &pb.GetMovieRequest{Id: created.Id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestMovieHandler_ListMovies(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		_, err := client.CreateMovie(ctx, createRequest(fmt.Sprintf("Movie %d", i)))
+		require.NoError(t, err)
+	}
+
+	resp, err := client.ListMovies(ctx, &pb.ListMoviesRequest{PageSize: 2, PageNumber: 2})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, resp.TotalCount)
+	require.Len(t, resp.Movies, 1)
+	assert.Equal(t, "Movie 3", resp.Movies[0].Title)
+
+	resp, err = client.ListMovies(ctx, &pb.ListMoviesRequest{})
+	require.NoError(t, err)
+	assert.Len(t, resp.Movies, 3, "zero paging values fall back to defaults")
+}
+
+func TestMovieHandler_ErrorCodes(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.CreateMovie(ctx, createRequest(""))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.GetMovie(ctx, &pb.GetMovieRequest{Id: 4242})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	update := &pb.UpdateMovieRequest{Id: 4242, Title: "Ghost", Director: "Nobody", ReleaseDate: timestamppb.Now(), Genre: "Drama", Rating: 5}
+	_, err = client.UpdateMovie(ctx, update)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.DeleteMovie(ctx, &pb.DeleteMovieRequest{Id: 4242})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestMovieHandler_Metadata(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	req := createRequest("In the Mood for Love")
+	req.Synopsis = "Hong Kong, 1962."
+	req.Tagline = "Feel the heat, keep the feeling burning."
+	req.RuntimeMinutes = 98
+	req.ProductionCountries = []string{"HK", "FR"}
+	req.OriginalLanguage = "yue"
+	req.Certifications = map[string]string{"US": "PG", "DE": "6"}
+	req.Budget = 3_000_000
+	req.BoxOffice = 14_000_000
+	req.ImdbId = "tt0118694"
+	req.TmdbId = 843
+	created, err := client.CreateMovie(ctx, req)
+	require.NoError(t, err)
+
+	got, err := client.GetMovie(ctx, &pb.GetMovieRequest{Id: created.Id})
+	require.NoError(t, err)
+	assert.Equal(t, "Hong Kong, 1962.", got.Synopsis)
+	assert.Equal(t, req.Tagline, got.Tagline)
+	assert.Equal(t, int32(98), got.RuntimeMinutes)
+	assert.Equal(t, []string{"HK", "FR"}, got.ProductionCountries)
+	assert.Equal(t, "yue", got.OriginalLanguage)
+	assert.Equal(t, map[string]string{"US": "PG", "DE": "6"}, got.Certifications)
+	assert.Equal(t, int64(3_000_000), got.Budget)
+	assert.Equal(t, int64(14_000_000), got.BoxOffice)
+	assert.Equal(t, "tt0118694", got.ImdbId)
+	assert.Equal(t, int64(843), got.TmdbId)
+
+	duplicate := createRequest("Copy")
+	duplicate.ImdbId = "tt0118694"
+	_, err = client.CreateMovie(ctx, duplicate)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	duplicate.ImdbId, duplicate.TmdbId = "", 843
+	_, err = client.CreateMovie(ctx, duplicate)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	for name, modify := range map[string]func(*pb.CreateMovieRequest){
+		"unknown country":         func(r *pb.CreateMovieRequest) { r.ProductionCountries = []string{"XX"} },
+		"lowercase country":       func(r *pb.CreateMovieRequest) { r.ProductionCountries = []string{"fr"} },
+		"unknown language":        func(r *pb.CreateMovieRequest) { r.OriginalLanguage = "xyz" },
+		"language tag":            func(r *pb.CreateMovieRequest) { r.OriginalLanguage = "en-US" },
+		"certification country":   func(r *pb.CreateMovieRequest) { r.Certifications = map[string]string{"USA": "R"} },
+		"empty certification":     func(r *pb.CreateMovieRequest) { r.Certifications = map[string]string{"US": ""} },
+		"negative runtime":        func(r *pb.CreateMovieRequest) { r.RuntimeMinutes = -1 },
+		"negative budget":         func(r *pb.CreateMovieRequest) { r.Budget = -1 },
+		"malformed IMDb id":       func(r *pb.CreateMovieRequest) { r.ImdbId = "0118694" },
+		"negative TMDB id":        func(r *pb.CreateMovieRequest) { r.TmdbId = -1 },
+		"synopsis over the limit": func(r *pb.CreateMovieRequest) { r.Synopsis = strings.Repeat("a", 10001) },
+	} {
+		r := createRequest("Invalid")
+		modify(r)
+		_, err := client.CreateMovie(ctx, r)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), name)
+	}
+}
+
+func TestMovieHandler_UpdateMask(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	req := createRequest("In the Mood for Love")
+	req.Tagline = "Feel the heat, keep the feeling burning."
+	req.Certifications = map[string]string{"US": "PG"}
+	req.ImdbId = "tt0118694"
+	created, err := client.CreateMovie(ctx, req)
+	require.NoError(t, err)
+
+	// Fields in the mask are replaced even when empty, and only they are validated
+	updated, err := client.UpdateMovie(ctx, &pb.UpdateMovieRequest{
+		Id:         created.Id,
+		Rating:     8.1,
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"rating", "tagline"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, float32(8.1), updated.Rating)
+	assert.Empty(t, updated.Tagline)
+	assert.Equal(t, req.Title, updated.Title)
+	assert.Equal(t, "tt0118694", updated.ImdbId)
+
+	// Without a mask, as with "*", all fields are replaced and the ones left empty are cleared
+	for _, mask := range []*fieldmaskpb.FieldMask{nil, {Paths: []string{"*"}}} {
+		updated, err = client.UpdateMovie(ctx, &pb.UpdateMovieRequest{
+			Id:          created.Id,
+			Title:       "2046",
+			Director:    "Wong Kar-wai",
+			ReleaseDate: timestamppb.New(time.Date(2004, 9, 29, 0, 0, 0, 0, time.UTC)),
+			Genre:       "Drama",
+			Rating:      7.4,
+			Tagline:     "Every memory has traces of tears.",
+			UpdateMask:  mask,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "2046", updated.Title)
+		assert.Equal(t, "Every memory has traces of tears.", updated.Tagline)
+		assert.Empty(t, updated.Certifications)
+		assert.Empty(t, updated.ImdbId)
+	}
+
+	for name, update := range map[string]*pb.UpdateMovieRequest{
+		"unknown field":        {Id: created.Id, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"poster_url"}}},
+		"invalid masked value": {Id: created.Id, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title"}}},
+		"invalid full replace": {Id: created.Id, Rating: 5},
+	} {
+		_, err := client.UpdateMovie(ctx, update)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), name)
+	}
+
+	_, err = client.UpdateMovie(ctx, &pb.UpdateMovieRequest{Id: 4242, Rating: 5, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"rating"}}})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestMovieHandler_Gateway(t *testing.T) {
+	srv := newTestGateway(t, newTestServer(t, registerMovies))
+
+	body := `{"title":"Chungking Express","director":"Wong Kar-wai","releaseDate":"1994-07-14T00:00:00Z","genre":"Drama","rating":8}`
+	resp, err := http.Post(srv.URL+"/v1/movies", "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var created struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	assert.Equal(t, "Chungking Express", created.Title)
+
+	resp, err = http.Get(srv.URL + "/v1/movies/" + created.ID)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(srv.URL + "/v1/movies?pageSize=10&pageNumber=1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var list struct {
+		Movies     []json.RawMessage `json:"movies"`
+		TotalCount int               `json:"totalCount"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	assert.Len(t, list.Movies, 1)
+	assert.Equal(t, 1, list.TotalCount)
+
+	// The JSON form of update_mask lists the fields in lowerCamelCase
+	put, err := http.NewRequest(http.MethodPut, srv.URL+"/v1/movies/"+created.ID, strings.NewReader(`{"rating":9,"updateMask":"rating"}`))
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(put)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var updated struct {
+		Title  string  `json:"title"`
+		Rating float32 `json:"rating"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&updated))
+	assert.Equal(t, "Chungking Express", updated.Title)
+	assert.Equal(t, float32(9), updated.Rating)
+
+	resp, err = http.Get(srv.URL + "/v1/movies/4242")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Post(srv.URL+"/v1/movies", "application/json", strings.NewReader(`{"title":""}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestMovieHandler_DeadlineExceeded(t *testing.T) {
+	interceptor := deadline.UnaryServerInterceptor(time.Nanosecond, nil)
+	server := newTestServer(t, registerMovies, grpc.UnaryInterceptor(interceptor))
+
+	_, err := server.movies.ListMovies(context.Background(), &pb.ListMoviesRequest{})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+
+	srv := newTestGateway(t, server)
+	resp, err := http.Get(srv.URL + "/v1/movies")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/text/language"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"movie-project/internal/model"
+	"movie-project/internal/service"
+	"movie-project/pkg/logger"
+	"movie-project/pkg/tracing"
+	pb "movie-project/proto/movie"
+)
+
+type TranslationHandler struct {
+	pb.UnimplementedTranslationServiceServer
+	service *service.TranslationService
+	logger  logger.Logger
+}
+
+func NewTranslationHandler(service *service.TranslationService, logger logger.Logger) TranslationHandler {
+	return TranslationHandler{service: service, logger: logger}
+}
+
+func (h *TranslationHandler) SetMovieTranslation(ctx context.Context, req *pb.SetMovieTranslationRequest) (_ *pb.MovieTranslation, err error) {
+	ctx, span := tracing.Start(ctx, "TranslationHandler.SetMovieTranslation", attribute.Int64("movie.id", req.MovieId), attribute.String("language", req.Language))
+	defer func() { tracing.End(span, err) }()
+
+	translation := &model.MovieTranslation{
+		MovieID:  uint(req.MovieId),
+		Language: req.Language,
+		Title:    req.Title,
+		Synopsis: req.Synopsis,
+	}
+	if err := h.service.SetMovieTranslation(ctx, translation); err != nil {
+		return nil, errorToStatus(err, "Failed to set movie translation")
+	}
+	return movieTranslationToProto(translation), nil
+}
+
+func (h *TranslationHandler) GetMovieTranslation(ctx context.Context, req *pb.GetMovieTranslationRequest) (_ *pb.MovieTranslation, err error) {
+	ctx, span := tracing.Start(ctx, "TranslationHandler.GetMovieTranslation", attribute.Int64("movie.id", req.MovieId), attribute.String("language", req.Language))
+	defer func() { tracing.End(span, err) }()
+
+	translation, err := h.service.GetMovieTranslation(ctx, uint(req.MovieId), req.Language)
+	if err != nil {
+		return nil, errorToStatus(err, "Failed to get movie translation")
+	}
+	return movieTranslationToProto(translation), nil
+}
+
+func (h *TranslationHandler) ListMovieTranslations(ctx context.Context, req *pb.ListMovieTranslationsRequest) (_ *pb.ListMovieTranslationsResponse, err error) {
+	ctx, span := tracing.Start(ctx, "TranslationHandler.ListMovieTranslations", attribute.Int64("movie.id", req.MovieId))
+	defer func() { tracing.End(span, err) }()
+
+	translations, err := h.service.ListMovieTranslations(ctx, uint(req.MovieId))
+	if err != nil {
+		return nil, errorToStatus(err, "Failed to list movie translations")
+	}
+	response := &pb.ListMovieTranslationsResponse{Translations: make([]*pb.MovieTranslation, len(translations))}
+	for i, translation := range translations {
+		response.Translations[i] = movieTranslationToProto(translation)
+	}
+	return response, nil
+}
+
+func (h *TranslationHandler) DeleteMovieTranslation(ctx context.Context, req *pb.DeleteMovieTranslationRequest) (_ *pb.DeleteTranslationResponse, err error) {
+	ctx, span := tracing.Start(ctx, "TranslationHandler.DeleteMovieTranslation", attribute.Int64("movie.id", req.MovieId), attribute.String("language", req.Language))
+	defer func() { tracing.End(span, err) }()
+
+	if err := h.service.DeleteMovieTranslation(ctx, uint(req.MovieId), req.Language); err != nil {
+		return nil, errorToStatus(err, "Failed to delete movie translation")
+	}
+	return &pb.DeleteTranslationResponse{Success: true}, nil
+}
+
+func (h *TranslationHandler) SetGenreTranslation(ctx context.Context, req *pb.SetGenreTranslationRequest) (_ *pb.GenreTranslation, err error) {
+	ctx, span := tracing.Start(ctx, "TranslationHandler.SetGenreTranslation", attribute.String("genre", req.Genre), attribute.String("language", req.Language))
+	defer func() { tracing.End(span, err) }()
+
+	translation := &model.GenreTranslation{
+		Genre:    req.Genre,
+		Language: req.Language,
+		Name:     req.Name,
+	}
+	if err := h.service.SetGenreTranslation(ctx, translation); err != nil {
+		return nil, errorToStatus(err, "Failed to set genre translation")
+	}
+	return genreTranslationToProto(translation), nil
+}
+
+func (h *TranslationHandler) GetGenreTranslation(ctx context.Context, req *pb.GetGenreTranslationRequest) (_ *pb.GenreTranslation, err error) {
+	ctx, span := tracing.Start(ctx, "TranslationHandler.GetGenreTranslation", attribute.String("genre", req.Genre), attribute.String("language", req.Language))
+	defer func() { tracing.End(span, err) }()
+
+	translation, err := h.service.GetGenreTranslation(ctx, req.Genre, req.Language)
+	if err != nil {
+		return nil, errorToStatus(err, "Failed to get genre translation")
+	}
+	return genreTranslationToProto(translation), nil
+}
+
+func (h *TranslationHandler) ListGenreTranslations(ctx context.Context, req *pb.ListGenreTranslationsRequest) (_ *pb.ListGenreTranslationsResponse, err error) {
+	ctx, span := tracing.Start(ctx, "TranslationHandler.ListGenreTranslations", attribute.String("genre", req.Genre))
+	defer func() { tracing.End(span, err) }()
+
+	translations, err := h.service.ListGenreTranslations(ctx, req.Genre)
+	if err != nil {
+		return nil, errorToStatus(err, "Failed to list genre translations")
+	}
+	response := &pb.ListGenreTranslationsResponse{Translations: make([]*pb.GenreTranslation, len(translations))}
+	for i, translation := range translations {
+		response.Translations[i] = genreTranslationToProto(translation)
+	}
+	return response, nil
+}
+
+func (h *TranslationHandler) DeleteGenreTranslation(ctx context.Context, req *pb.DeleteGenreTranslationRequest) (_ *pb.DeleteTranslationResponse, err error) {
+	ctx, span := tracing.Start(ctx, "TranslationHandler.DeleteGenreTranslation", attribute.String("genre", req.Genre), attribute.String("language", req.Language))
+	defer func() { tracing.End(span, err) }()
+
+	if err := h.service.DeleteGenreTranslation(ctx, req.Genre, req.Language); err != nil {
+		return nil, errorToStatus(err, "Failed to delete genre translation")
+	}
+	return &pb.DeleteTranslationResponse{Success: true}, nil
+}
+
+// acceptLanguageKeys are the metadata keys of the Accept-Language header, as sent by gRPC
+// clients and as forwarded by the gateway
+var acceptLanguageKeys = []string{"accept-language", runtime.MetadataPrefix + "accept-language"}
+
+// preferredLanguages returns the languages a call prefers, most preferred first: those of
+// locale when set, otherwise those of the Accept-Language header. Malformed headers are ignored.
+func preferredLanguages(ctx context.Context, locale string) ([]language.Tag, error) {
+	if locale != "" {
+		prefs, _, err := language.ParseAcceptLanguage(locale)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid locale %q: %v", locale, err)
+		}
+		return prefs, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, key := range acceptLanguageKeys {
+		for _, value := range md.Get(key) {
+			if prefs, _, err := language.ParseAcceptLanguage(value); err == nil && len(prefs) > 0 {
+				return prefs, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func movieTranslationToProto(translation *model.MovieTranslation) *pb.MovieTranslation {
+	return &pb.MovieTranslation{
+		MovieId:  int64(translation.MovieID),
+		Language: translation.Language,
+		Title:    translation.Title,
+		Synopsis: translation.Synopsis,
+	}
+}
+
+func genreTranslationToProto(translation *model.GenreTranslation) *pb.GenreTranslation {
+	return &pb.GenreTranslation{
+		Genre:    translation.Genre,
+		Language: translation.Language,
+		Name:     translation.Name,
+	}
+}
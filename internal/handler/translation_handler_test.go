@@ -0,0 +1,294 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+
+	"movie-project/internal/handler"
+	"movie-project/internal/repository"
+	"movie-project/internal/service"
+	"movie-project/pkg/logger"
+	pb "movie-project/proto/movie"
+)
+
+// registerTranslations registers a MovieHandler that localizes movies and a TranslationHandler
+func registerTranslations(s *grpc.Server, db *gorm.DB) {
+	log := logger.Discard()
+	repo := repository.NewMovieRepository(*db, *log)
+	translationSvc := service.NewTranslationService(repository.NewTranslationRepository(*db, *log), repo, *log)
+	movieHandler := handler.NewMovieHandler(service.NewMovieService(repo, *log), nil, &translationSvc, *log)
+	translationHandler := handler.NewTranslationHandler(&translationSvc, *log)
+	pb.RegisterMovieServiceServer(s, &movieHandler)
+	pb.RegisterTranslationServiceServer(s, &translationHandler)
+}
+
+func TestTranslationHandler_MovieTranslations(t *testing.T) {
+	server := newTestServer(t, registerTranslations)
+	ctx := context.Background()
+	movie, err := server.movies.CreateMovie(ctx, createRequest("In the Mood for Love"))
+	require.NoError(t, err)
+
+	set, err := server.translations.SetMovieTranslation(ctx, &pb.SetMovieTranslationRequest{
+		MovieId: movie.Id, Language: "zh-hant", Title: "花樣年華", Synopsis: "香港，1962年。",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "zh-Hant", set.Language, "language tags are canonicalized")
+
+	got, err := server.translations.GetMovieTranslation(ctx, &pb.GetMovieTranslationRequest{MovieId: movie.Id, Language: "zh-Hant"})
+	require.NoError(t, err)
+	assert.Equal(t, "花樣年華", got.Title)
+	assert.Equal(t, "香港，1962年。", got.Synopsis)
+
+	_, err = server.translations.SetMovieTranslation(ctx, &pb.SetMovieTranslationRequest{MovieId: movie.Id, Language: "fr", Synopsis: "Hong Kong, 1962."})
+	require.NoError(t, err, "the title may fall back to the original")
+	list, err := server.translations.ListMovieTranslations(ctx, &pb.ListMovieTranslationsRequest{MovieId: movie.Id})
+	require.NoError(t, err)
+	require.Len(t, list.Translations, 2)
+	assert.Equal(t, "fr", list.Translations[0].Language)
+
+	deleted, err := server.translations.DeleteMovieTranslation(ctx, &pb.DeleteMovieTranslationRequest{MovieId: movie.Id, Language: "fr"})
+	require.NoError(t, err)
+	assert.True(t, deleted.Success)
+	_, err = server.translations.GetMovieTranslation(ctx, &pb.GetMovieTranslationRequest{MovieId: movie.Id, Language: "fr"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = server.translations.DeleteMovieTranslation(ctx, &pb.DeleteMovieTranslationRequest{MovieId: movie.Id, Language: "fr"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	for name, req := range map[string]*pb.SetMovieTranslationRequest{
+		"invalid language":      {MovieId: movie.Id, Language: "not a tag", Title: "Titre"},
+		"missing language":      {MovieId: movie.Id, Title: "Titre"},
+		"no title nor synopsis": {MovieId: movie.Id, Language: "fr"},
+	} {
+		_, err := server.translations.SetMovieTranslation(ctx, req)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), name)
+	}
+	_, err = server.translations.SetMovieTranslation(ctx, &pb.SetMovieTranslationRequest{MovieId: movie.Id + 1, Language: "fr", Title: "Titre"})
+	assert.Equal(t, codes.NotFound, status.Code(err), "the movie must exist")
+	_, err = server.translations.ListMovieTranslations(ctx, &pb.ListMovieTranslationsRequest{MovieId: movie.Id + 1})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestTranslationHandler_GenreTranslations(t *testing.T) {
+	server := newTestServer(t, registerTranslations)
+	ctx := context.Background()
+
+	set, err := server.translations.SetGenreTranslation(ctx, &pb.SetGenreTranslationRequest{Genre: "Romance", Language: "FR", Name: "Romance"})
+	require.NoError(t, err)
+	assert.Equal(t, "fr", set.Language)
+	_, err = server.translations.SetGenreTranslation(ctx, &pb.SetGenreTranslationRequest{Genre: "Romance", Language: "de", Name: "Liebesfilm"})
+	require.NoError(t, err)
+
+	got, err := server.translations.GetGenreTranslation(ctx, &pb.GetGenreTranslationRequest{Genre: "Romance", Language: "de"})
+	require.NoError(t, err)
+	assert.Equal(t, "Liebesfilm", got.Name)
+
+	list, err := server.translations.ListGenreTranslations(ctx, &pb.ListGenreTranslationsRequest{Genre: "Romance"})
+	require.NoError(t, err)
+	assert.Len(t, list.Translations, 2)
+
+	_, err = server.translations.DeleteGenreTranslation(ctx, &pb.DeleteGenreTranslationRequest{Genre: "Romance", Language: "de"})
+	require.NoError(t, err)
+	_, err = server.translations.GetGenreTranslation(ctx, &pb.GetGenreTranslationRequest{Genre: "Romance", Language: "de"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = server.translations.SetGenreTranslation(ctx, &pb.SetGenreTranslationRequest{Genre: "Romance", Language: "de"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "the name is required")
+}
+
+// localizedMovies creates a movie with German and Brazilian Portuguese translations, the
+// latter without a synopsis, a German genre name and a movie without translations
+func localizedMovies(t *testing.T, server testServer) (translated, untranslated *pb.Movie) {
+	ctx := context.Background()
+	req := createRequest("In the Mood for Love")
+	req.Synopsis = "Hong Kong, 1962."
+	translated, err := server.movies.CreateMovie(ctx, req)
+	require.NoError(t, err)
+	untranslated, err = server.movies.CreateMovie(ctx, createRequest("Chungking Express"))
+	require.NoError(t, err)
+
+	for _, req := range []*pb.SetMovieTranslationRequest{
+		{MovieId: translated.Id, Language: "de", Title: "In the Mood for Love – Der Klang der Liebe", Synopsis: "Hongkong, 1962."},
+		{MovieId: translated.Id, Language: "pt-BR", Title: "Amor à Flor da Pele"},
+	} {
+		_, err := server.translations.SetMovieTranslation(ctx, req)
+		require.NoError(t, err)
+	}
+	_, err = server.translations.SetGenreTranslation(ctx, &pb.SetGenreTranslationRequest{Genre: "Romance", Language: "de", Name: "Liebesfilm"})
+	require.NoError(t, err)
+	return translated, untranslated
+}
+
+func TestMovieHandler_Localization(t *testing.T) {
+	server := newTestServer(t, registerTranslations)
+	ctx := context.Background()
+	movie, _ := localizedMovies(t, server)
+
+	for _, tt := range []struct {
+		locale, title, synopsis, genre, language string
+	}{
+		{"", "In the Mood for Love", "Hong Kong, 1962.", "Romance", ""},
+		{"de", "In the Mood for Love – Der Klang der Liebe", "Hongkong, 1962.", "Liebesfilm", "de"},
+		{"de-AT", "In the Mood for Love – Der Klang der Liebe", "Hongkong, 1962.", "Liebesfilm", "de"},
+		{"ja, pt;q=0.9, de;q=0.8", "Amor à Flor da Pele", "Hong Kong, 1962.", "Liebesfilm", "pt-BR"},
+		{"pt-PT", "Amor à Flor da Pele", "Hong Kong, 1962.", "Romance", "pt-BR"},
+		{"ja", "In the Mood for Love", "Hong Kong, 1962.", "Romance", ""},
+		{"zh-Hans", "In the Mood for Love", "Hong Kong, 1962.", "Romance", ""},
+	} {
+		got, err := server.movies.GetMovie(ctx, &pb.GetMovieRequest{Id: movie.Id, Locale: tt.locale})
+		require.NoError(t, err, tt.locale)
+		assert.Equal(t, tt.title, got.Title, tt.locale)
+		assert.Equal(t, tt.synopsis, got.Synopsis, tt.locale)
+		assert.Equal(t, tt.genre, got.Genre, tt.locale)
+		assert.Equal(t, tt.language, got.Locale, tt.locale)
+	}
+
+	_, err := server.movies.GetMovie(ctx, &pb.GetMovieRequest{Id: movie.Id, Locale: "de;q=nope"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Without a locale the Accept-Language header applies; malformed headers are ignored
+	headerCtx := metadata.AppendToOutgoingContext(ctx, "accept-language", "pt-BR")
+	got, err := server.movies.GetMovie(headerCtx, &pb.GetMovieRequest{Id: movie.Id})
+	require.NoError(t, err)
+	assert.Equal(t, "Amor à Flor da Pele", got.Title)
+	assert.Equal(t, "pt-BR", got.Locale)
+	got, err = server.movies.GetMovie(headerCtx, &pb.GetMovieRequest{Id: movie.Id, Locale: "de"})
+	require.NoError(t, err)
+	assert.Equal(t, "de", got.Locale, "locale takes precedence over the header")
+	got, err = server.movies.GetMovie(metadata.AppendToOutgoingContext(ctx, "accept-language", "de;q=nope"), &pb.GetMovieRequest{Id: movie.Id})
+	require.NoError(t, err)
+	assert.Equal(t, "In the Mood for Love", got.Title)
+
+	got, err = server.movies.GetMovie(ctx, &pb.GetMovieRequest{Id: movie.Id})
+	require.NoError(t, err)
+	assert.Empty(t, got.OriginalTitle, "the original text is only repeated for localized requests")
+
+	got, err = server.movies.GetMovie(ctx, &pb.GetMovieRequest{Id: movie.Id, Locale: "de"})
+	require.NoError(t, err)
+	assert.Equal(t, "In the Mood for Love", got.OriginalTitle)
+	assert.Equal(t, "Hong Kong, 1962.", got.OriginalSynopsis)
+	assert.Equal(t, "Romance", got.OriginalGenre)
+}
+
+func TestMovieHandler_LocalizedList(t *testing.T) {
+	server := newTestServer(t, registerTranslations)
+	movie, untranslated := localizedMovies(t, server)
+
+	resp, err := server.movies.ListMovies(context.Background(), &pb.ListMoviesRequest{PageSize: 10, Locale: "de"})
+	require.NoError(t, err)
+	require.Len(t, resp.Movies, 2)
+	assert.Equal(t, "In the Mood for Love – Der Klang der Liebe", resp.Movies[0].Title)
+	assert.Equal(t, movie.Id, resp.Movies[0].Id)
+	assert.Equal(t, "Chungking Express", resp.Movies[1].Title, "falls back to the original title")
+	assert.Empty(t, resp.Movies[1].Locale)
+	assert.Equal(t, "Liebesfilm", resp.Movies[1].Genre, "genres are translated on their own")
+	assert.Equal(t, untranslated.Id, resp.Movies[1].Id)
+}
+
+func TestMovieHandler_LocaleParameter(t *testing.T) {
+	server := newTestServer(t, registerTranslations)
+	gateway := newTestGateway(t, server)
+	movie, _ := localizedMovies(t, server)
+
+	get := func(path, acceptLanguage string) map[string]any {
+		req, err := http.NewRequest(http.MethodGet, gateway.URL+path, nil)
+		require.NoError(t, err)
+		if acceptLanguage != "" {
+			req.Header.Set("Accept-Language", acceptLanguage)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return body
+	}
+
+	path := fmt.Sprintf("/v1/movies/%d", movie.Id)
+	assert.Equal(t, "In the Mood for Love", get(path, "")["title"])
+	assert.Equal(t, "In the Mood for Love – Der Klang der Liebe", get(path, "de")["title"], "the gateway forwards Accept-Language")
+	assert.Equal(t, "Amor à Flor da Pele", get(path+"?locale=pt", "de")["title"], "locale takes precedence over the header")
+	assert.Equal(t, "Amor à Flor da Pele", get(path+"?locale="+url.QueryEscape("pt-BR,pt;q=0.9,en;q=0.8"), "")["title"])
+	assert.Equal(t, "In the Mood for Love", get(path+"?locale="+url.QueryEscape("fr;q=1.0, *;q=0.5"), "")["title"])
+
+	list := get("/v1/movies?locale=de", "")["movies"].([]any)
+	require.Len(t, list, 2)
+	assert.Equal(t, "Liebesfilm", list[0].(map[string]any)["genre"])
+}
+
+// TestMovieHandler_LocalizedRoundTrip updates movies from what GetMovie returned, as clients
+// editing a movie do, and checks that translations don't replace the original text
+func TestMovieHandler_LocalizedRoundTrip(t *testing.T) {
+	server := newTestServer(t, registerTranslations)
+	gateway := newTestGateway(t, server)
+	movie, _ := localizedMovies(t, server)
+	ctx := context.Background()
+	path := fmt.Sprintf("%s/v1/movies/%d", gateway.URL, movie.Id)
+
+	// A browser gets the movie localized by its Accept-Language header, changes the rating and
+	// sends the body back with a PUT naming the changed field
+	req, err := http.NewRequest(http.MethodGet, path, nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Language", "de")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	var body map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	resp.Body.Close()
+	require.Equal(t, "Liebesfilm", body["genre"])
+	body["rating"], body["updateMask"] = 8, "rating"
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPut, path, bytes.NewReader(data))
+	require.NoError(t, err)
+	req.Header.Set("Accept-Language", "de")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	got, err := server.movies.GetMovie(ctx, &pb.GetMovieRequest{Id: movie.Id})
+	require.NoError(t, err)
+	assert.Equal(t, "In the Mood for Love", got.Title)
+	assert.Equal(t, "Romance", got.Genre)
+	assert.Equal(t, float32(8), got.Rating)
+
+	// A client asking for a locale writes back the original text
+	localized, err := server.movies.GetMovie(ctx, &pb.GetMovieRequest{Id: movie.Id, Locale: "de"})
+	require.NoError(t, err)
+	require.Equal(t, "Liebesfilm", localized.Genre)
+	_, err = server.movies.UpdateMovie(ctx, &pb.UpdateMovieRequest{
+		Id:          localized.Id,
+		Title:       localized.OriginalTitle,
+		Director:    localized.Director,
+		ReleaseDate: localized.ReleaseDate,
+		Genre:       localized.OriginalGenre,
+		Rating:      9,
+		Synopsis:    localized.OriginalSynopsis,
+	})
+	require.NoError(t, err)
+
+	got, err = server.movies.GetMovie(ctx, &pb.GetMovieRequest{Id: movie.Id})
+	require.NoError(t, err)
+	assert.Equal(t, "In the Mood for Love", got.Title)
+	assert.Equal(t, "Hong Kong, 1962.", got.Synopsis)
+	assert.Equal(t, "Romance", got.Genre)
+	assert.Equal(t, float32(9), got.Rating)
+
+	got, err = server.movies.GetMovie(ctx, &pb.GetMovieRequest{Id: movie.Id, Locale: "de"})
+	require.NoError(t, err)
+	assert.Equal(t, "Liebesfilm", got.Genre, "the genre translation still applies")
+}
@@ -0,0 +1,78 @@
+// internal/model/metadata.go
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Countries are ISO 3166-1 alpha-2 country codes, stored as a comma-separated list
+type Countries []string
+
+func (c Countries) Value() (driver.Value, error) {
+	return strings.Join(c, ","), nil
+}
+
+func (c *Countries) Scan(value any) error {
+	s, err := scanString(value)
+	if err != nil {
+		return err
+	}
+	if s == "" {
+		*c = nil
+		return nil
+	}
+	*c = strings.Split(s, ",")
+	return nil
+}
+
+func (Countries) GormDataType() string {
+	return "string"
+}
+
+// Certifications map ISO 3166-1 alpha-2 country codes to the age certification of a movie
+// there, such as "PG-13" in "US". They are stored as a JSON object, "" without any.
+type Certifications map[string]string
+
+func (c Certifications) Value() (driver.Value, error) {
+	if len(c) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(map[string]string(c))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (c *Certifications) Scan(value any) error {
+	s, err := scanString(value)
+	if err != nil {
+		return err
+	}
+	if s == "" {
+		*c = nil
+		return nil
+	}
+	return json.Unmarshal([]byte(s), (*map[string]string)(c))
+}
+
+func (Certifications) GormDataType() string {
+	return "string"
+}
+
+// scanString returns the text of a column value
+func scanString(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	default:
+		return "", fmt.Errorf("unsupported column value %T", value)
+	}
+}
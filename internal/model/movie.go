@@ -2,6 +2,10 @@
 package model
 
 import (
+	"math"
+	"path"
+	"strings"
+
 	"google.golang.org/protobuf/types/known/timestamppb"
 	pb "movie-project/proto/movie"
 	"time"
@@ -13,9 +17,83 @@ type Movie struct {
 	gorm.Model
 	Title       string    `json:"title" gorm:"not null" validate:"required,min=1,max=255"`
 	Director    string    `json:"director" gorm:"not null" validate:"required,min=1,max=255"`
-	ReleaseDate time.Time `json:"release_date" validate:"required"`
-	Genre       string    `json:"genre" validate:"required,min=1,max=100"`
-	Rating      float32   `json:"rating" gorm:"type:decimal(3,1)" validate:"required,min=0,max=10"`
+	ReleaseDate time.Time `json:"release_date" gorm:"type:date;not null" validate:"required"`
+	Genre       string    `json:"genre" gorm:"not null" validate:"required,min=1,max=100"`
+	Rating      float32   `json:"rating" gorm:"type:decimal(3,1);not null" validate:"required,min=0,max=10"`
+	Synopsis    string    `json:"synopsis" gorm:"type:text;not null;default:''" validate:"max=10000"`
+	Tagline     string    `json:"tagline" gorm:"size:255;not null;default:''" validate:"max=255"`
+	// Running time in minutes, 0 when unknown
+	RuntimeMinutes      int32     `json:"runtime_minutes" gorm:"not null;default:0" validate:"min=0"`
+	ProductionCountries Countries `json:"production_countries" gorm:"size:255;not null;default:''" validate:"max=50,dive,iso3166_1_alpha2"`
+	// ISO 639 code of the original language, such as "en" or "yue"
+	OriginalLanguage string         `json:"original_language" gorm:"size:3;not null;default:''" validate:"omitempty,iso639"`
+	Certifications   Certifications `json:"certifications" gorm:"type:text;not null;default:''" validate:"max=50,dive,keys,iso3166_1_alpha2,endkeys,required,max=16"`
+	// Budget and worldwide box office gross in US dollars, 0 when unknown
+	Budget    int64 `json:"budget" gorm:"not null;default:0" validate:"min=0"`
+	BoxOffice int64 `json:"box_office" gorm:"not null;default:0" validate:"min=0"`
+	// External ids are unique among movies that are not deleted; "" and 0 mean none
+	ImdbID string `json:"imdb_id" gorm:"size:12;not null;default:'';uniqueIndex:idx_movies_imdb_id,where:imdb_id <> '' AND deleted_at IS NULL" validate:"omitempty,imdb_id"`
+	TmdbID int64  `json:"tmdb_id" gorm:"not null;default:0;uniqueIndex:idx_movies_tmdb_id,where:tmdb_id <> 0 AND deleted_at IS NULL" validate:"min=0"`
+	// Blob keys of the images, "" without one. They are set through the media service only.
+	PosterKey   string `json:"poster_key" gorm:"size:255;not null;default:''"`
+	BackdropKey string `json:"backdrop_key" gorm:"size:255;not null;default:''"`
+}
+
+// MediaKind is a kind of image of a movie
+type MediaKind string
+
+const (
+	MediaPoster   MediaKind = "poster"
+	MediaBackdrop MediaKind = "backdrop"
+)
+
+// MediaKinds lists every kind of image
+var MediaKinds = []MediaKind{MediaPoster, MediaBackdrop}
+
+// Valid reports whether k is a known kind
+func (k MediaKind) Valid() bool {
+	return k == MediaPoster || k == MediaBackdrop
+}
+
+// Column returns the column holding the blob key of the kind
+func (k MediaKind) Column() string {
+	return string(k) + "_key"
+}
+
+// MediaKey returns the blob key of the image of the kind, or ""
+func (m *Movie) MediaKey(kind MediaKind) string {
+	switch kind {
+	case MediaPoster:
+		return m.PosterKey
+	case MediaBackdrop:
+		return m.BackdropKey
+	}
+	return ""
+}
+
+// SetMediaKey sets the blob key of the image of the kind
+func (m *Movie) SetMediaKey(kind MediaKind, key string) {
+	switch kind {
+	case MediaPoster:
+		m.PosterKey = key
+	case MediaBackdrop:
+		m.BackdropKey = key
+	}
+}
+
+// ThumbnailKey returns the blob key of the thumbnail of the image stored under key,
+// e.g. movies/1/poster-1a2b-thumb.jpg for movies/1/poster-1a2b.jpg
+func ThumbnailKey(key string) string {
+	ext := path.Ext(key)
+	return strings.TrimSuffix(key, ext) + "-thumb" + ext
+}
+
+// BeforeSave rounds values the way the DATE and DECIMAL(3,1) columns store them,
+// so every database driver returns exactly what PostgreSQL would.
+func (m *Movie) BeforeSave(tx *gorm.DB) error {
+	m.ReleaseDate = m.ReleaseDate.UTC().Truncate(24 * time.Hour)
+	m.Rating = float32(math.Round(float64(m.Rating)*10) / 10)
+	return nil
 }
 
 func modelToProto(movie *Movie) *pb.Movie {
@@ -0,0 +1,38 @@
+// internal/model/translation.go
+package model
+
+import (
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// MovieTranslation holds the title and synopsis of a movie in a language. Empty fields fall
+// back to the original text.
+type MovieTranslation struct {
+	MovieID   uint      `json:"movie_id" gorm:"primaryKey;autoIncrement:false"`
+	Language  string    `json:"language" gorm:"primaryKey;size:35" validate:"required,max=35,bcp47_language_tag"`
+	Title     string    `json:"title" gorm:"size:255;not null;default:''" validate:"required_without=Synopsis,max=255"`
+	Synopsis  string    `json:"synopsis" gorm:"type:text;not null;default:''" validate:"max=10000"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GenreTranslation holds the name of a genre in a language
+type GenreTranslation struct {
+	Genre     string    `json:"genre" gorm:"primaryKey;size:100" validate:"required,max=100"`
+	Language  string    `json:"language" gorm:"primaryKey;size:35" validate:"required,max=35,bcp47_language_tag"`
+	Name      string    `json:"name" gorm:"size:100;not null" validate:"required,max=100"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CanonicalLanguage returns the canonical form of a BCP 47 language tag, e.g. "pt-BR" for
+// "pt-br", so each language is stored once. Tags that don't parse are returned as is.
+func CanonicalLanguage(tag string) string {
+	parsed, err := language.Parse(tag)
+	if err != nil {
+		return tag
+	}
+	return parsed.String()
+}
@@ -0,0 +1,30 @@
+// internal/model/validate.go
+package model
+
+import (
+	"regexp"
+
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/text/language"
+)
+
+// imdbIDRe matches IMDb title ids such as tt0068646
+var imdbIDRe = regexp.MustCompile(`^tt[0-9]{7,10}$`)
+
+// NewValidator returns a validator that knows the rules the models use beyond the built-in
+// ones: imdb_id for IMDb title ids and iso639 for ISO 639 language codes.
+func NewValidator() *validator.Validate {
+	validate := validator.New()
+	validate.RegisterValidation("imdb_id", func(fl validator.FieldLevel) bool {
+		return imdbIDRe.MatchString(fl.Field().String())
+	})
+	validate.RegisterValidation("iso639", func(fl validator.FieldLevel) bool {
+		code := fl.Field().String()
+		if len(code) < 2 || len(code) > 3 {
+			return false
+		}
+		base, err := language.ParseBase(code)
+		return err == nil && (base.String() == code || base.ISO3() == code)
+	})
+	return validate
+}
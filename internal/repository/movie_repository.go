@@ -3,18 +3,27 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"gorm.io/gorm"
 	"movie-project/internal/model"
 	"movie-project/pkg/logger"
+	"movie-project/pkg/metrics"
 )
 
 type IMovieRepository interface {
 	Create(ctx context.Context, movie *model.Movie) error
 	GetByID(ctx context.Context, id uint) (*model.Movie, error)
 	List(ctx context.Context, offset, limit int) ([]*model.Movie, int64, error)
-	Update(ctx context.Context, movie *model.Movie) error
+	// Update replaces the given fields of the movie, by their names in model.Movie, or all of them
+	// without fields. movie is set to the updated movie.
+	Update(ctx context.Context, movie *model.Movie, fields ...string) error
 	Delete(ctx context.Context, id uint) error
+	// CountByGenre returns the number of movies per genre, omitting genres without movies
+	CountByGenre(ctx context.Context) (map[string]int64, error)
+	// SetMediaKey sets the blob key of an image of a movie, "" removing it
+	SetMediaKey(ctx context.Context, id uint, kind model.MediaKind, key string) error
 }
 
 type MovieRepository struct {
@@ -27,6 +36,8 @@ func NewMovieRepository(db gorm.DB, logger logger.Logger) MovieRepository {
 }
 
 func (r *MovieRepository) Create(ctx context.Context, movie *model.Movie) error {
+	defer metrics.ObserveQuery("Create", time.Now())
+
 	result := r.db.WithContext(ctx).Create(movie)
 	if result.Error != nil {
 		r.logger.ErrorContext(ctx, "Failed to create movie", "error", result.Error)
@@ -36,6 +47,8 @@ func (r *MovieRepository) Create(ctx context.Context, movie *model.Movie) error
 }
 
 func (r *MovieRepository) GetByID(ctx context.Context, id uint) (*model.Movie, error) {
+	defer metrics.ObserveQuery("GetByID", time.Now())
+
 	var movie model.Movie
 	result := r.db.WithContext(ctx).First(&movie, id)
 	if result.Error != nil {
@@ -46,6 +59,8 @@ func (r *MovieRepository) GetByID(ctx context.Context, id uint) (*model.Movie, e
 }
 
 func (r *MovieRepository) List(ctx context.Context, offset, limit int) ([]*model.Movie, int64, error) {
+	defer metrics.ObserveQuery("List", time.Now())
+
 	var movies []*model.Movie
 	var total int64
 
@@ -62,33 +77,100 @@ func (r *MovieRepository) List(ctx context.Context, offset, limit int) ([]*model
 		return nil, 0, result.Error
 	}
 
-	r.logger.InfoContext(ctx, "Querying movies", "offset", offset, "limit", limit, "total", total)
+	r.logger.DebugContext(ctx, "Querying movies", "offset", offset, "limit", limit, "total", total)
 
-	result = r.db.WithContext(ctx).Offset(offset).Limit(limit).Find(&movies)
+	result = r.db.WithContext(ctx).Order("id").Offset(offset).Limit(limit).Find(&movies)
 	if result.Error != nil {
 		r.logger.ErrorContext(ctx, "Failed to list movies", "error", result.Error)
 		return nil, 0, result.Error
 	}
 
-	r.logger.InfoContext(ctx, "Retrieved movies", "count", len(movies), "total", total)
+	r.logger.DebugContext(ctx, "Retrieved movies", "count", len(movies), "total", total)
 
 	return movies, total, nil
 }
 
-func (r *MovieRepository) Update(ctx context.Context, movie *model.Movie) error {
-	result := r.db.WithContext(ctx).Save(movie)
-	if result.Error != nil {
-		r.logger.ErrorContext(ctx, "Failed to update movie", "error", result.Error, "id", movie.ID)
-		return result.Error
+func (r *MovieRepository) Update(ctx context.Context, movie *model.Movie, fields ...string) error {
+	defer metrics.ObserveQuery("Update", time.Now())
+
+	// Save would insert a missing row and reset created_at, so update all other columns explicitly.
+	// Images change through SetMediaKey only; their keys are read back to return a complete movie,
+	// and so are the fields a partial update leaves alone.
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(movie).Select("*")
+		if len(fields) > 0 {
+			query = tx.Model(movie).Select(fields)
+		}
+		result := query.Omit("id", "created_at", "poster_key", "backdrop_key").Updates(movie)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if len(fields) > 0 {
+			*movie = model.Movie{Model: gorm.Model{ID: movie.ID}}
+			return tx.Take(movie).Error
+		}
+		return tx.Model(movie).Select("poster_key", "backdrop_key").Take(movie).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		r.logger.WarnContext(ctx, "Movie to update not found", "id", movie.ID)
+		return err
+	}
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to update movie", "error", err, "id", movie.ID)
+		return err
 	}
 	return nil
 }
 
 func (r *MovieRepository) Delete(ctx context.Context, id uint) error {
+	defer metrics.ObserveQuery("Delete", time.Now())
+
 	result := r.db.WithContext(ctx).Delete(&model.Movie{}, id)
 	if result.Error != nil {
 		r.logger.ErrorContext(ctx, "Failed to delete movie", "error", result.Error, "id", id)
 		return result.Error
 	}
+	if result.RowsAffected == 0 {
+		r.logger.WarnContext(ctx, "Movie to delete not found", "id", id)
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *MovieRepository) CountByGenre(ctx context.Context) (map[string]int64, error) {
+	defer metrics.ObserveQuery("CountByGenre", time.Now())
+
+	var rows []struct {
+		Genre string
+		Count int64
+	}
+	result := r.db.WithContext(ctx).Model(&model.Movie{}).Select("genre, count(*) AS count").Group("genre").Scan(&rows)
+	if result.Error != nil {
+		r.logger.ErrorContext(ctx, "Failed to count movies by genre", "error", result.Error)
+		return nil, result.Error
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Genre] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *MovieRepository) SetMediaKey(ctx context.Context, id uint, kind model.MediaKind, key string) error {
+	defer metrics.ObserveQuery("SetMediaKey", time.Now())
+
+	result := r.db.WithContext(ctx).Model(&model.Movie{}).Where("id = ?", id).Update(kind.Column(), key)
+	if result.Error != nil {
+		r.logger.ErrorContext(ctx, "Failed to set media key", "error", result.Error, "id", id, "kind", kind)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		r.logger.WarnContext(ctx, "Movie to set media of not found", "id", id)
+		return gorm.ErrRecordNotFound
+	}
 	return nil
 }
@@ -0,0 +1,126 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"movie-project/config"
+	"movie-project/internal/model"
+	"movie-project/internal/repository"
+	"movie-project/internal/repository/repotest"
+	"movie-project/migrations"
+	"movie-project/pkg/database"
+	"movie-project/pkg/logger"
+)
+
+// testBackends returns a config per database driver under test. SQLite always
+// runs; PostgreSQL runs when TEST_POSTGRES is set, using the regular DB_* settings.
+func testBackends(t *testing.T) []config.Config {
+	backends := []config.Config{{
+		DBDriver: config.DriverSQLite,
+		DBPath:   filepath.Join(t.TempDir(), "movie.db"),
+	}}
+
+	if os.Getenv("TEST_POSTGRES") != "" {
+		cfg, err := config.LoadConfig()
+		require.NoError(t, err)
+		cfg.DBDriver = config.DriverPostgres
+		backends = append(backends, cfg)
+	}
+
+	return backends
+}
+
+// newTestRepository migrates a clean schema for cfg and returns a repository on top of it
+// together with the underlying connection.
+func newTestRepository(t *testing.T, cfg config.Config) (*repository.MovieRepository, *gorm.DB) {
+	m, err := migrations.New(cfg)
+	require.NoError(t, err)
+	if err := m.Down(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		require.NoError(t, err)
+	}
+	require.NoError(t, m.Up())
+	srcErr, dbErr := m.Close()
+	require.NoError(t, srcErr)
+	require.NoError(t, dbErr)
+
+	db, err := database.Open(cfg, logger.Discard())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	repo := repository.NewMovieRepository(*db, *logger.Discard())
+	return &repo, db
+}
+
+func forEachBackend(t *testing.T, test func(t *testing.T, repo *repository.MovieRepository, db *gorm.DB)) {
+	for _, cfg := range testBackends(t) {
+		t.Run(cfg.DBDriver, func(t *testing.T) {
+			repo, db := newTestRepository(t, cfg)
+			test(t, repo, db)
+		})
+	}
+}
+
+func TestMovieRepository_Conformance(t *testing.T) {
+	for _, cfg := range testBackends(t) {
+		t.Run(cfg.DBDriver, func(t *testing.T) {
+			repotest.RunConformance(t, func(t *testing.T) repository.IMovieRepository {
+				if cfg.DBDriver == config.DriverSQLite {
+					cfg.DBPath = filepath.Join(t.TempDir(), "movie.db")
+				}
+				repo, _ := newTestRepository(t, cfg)
+				return repo
+			})
+		})
+	}
+}
+
+func TestMovieRepository_NormalizesDecimalAndDate(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo *repository.MovieRepository, _ *gorm.DB) {
+		ctx := context.Background()
+		movie := &model.Movie{
+			Title:       "Stalker",
+			Director:    "Andrei Tarkovsky",
+			ReleaseDate: time.Date(1979, 5, 25, 18, 30, 0, 0, time.FixedZone("MSK", 3*60*60)),
+			Genre:       "Science Fiction",
+			Rating:      8.06,
+		}
+		require.NoError(t, repo.Create(ctx, movie))
+		require.NotZero(t, movie.ID)
+
+		got, err := repo.GetByID(ctx, movie.ID)
+		require.NoError(t, err)
+		require.Equal(t, "Stalker", got.Title)
+		require.Equal(t, float32(8.1), got.Rating)
+		require.True(t, time.Date(1979, 5, 25, 0, 0, 0, 0, time.UTC).Equal(got.ReleaseDate), got.ReleaseDate)
+		require.True(t, got.CreatedAt.Equal(movie.CreatedAt), got.CreatedAt)
+	})
+}
+
+func TestMovieRepository_DeleteIsSoft(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, repo *repository.MovieRepository, db *gorm.DB) {
+		ctx := context.Background()
+		movie := &model.Movie{Title: "Solaris", Director: "Andrei Tarkovsky", ReleaseDate: time.Now(), Genre: "Drama", Rating: 8}
+		require.NoError(t, repo.Create(ctx, movie))
+		require.NoError(t, repo.Delete(ctx, movie.ID))
+
+		_, err := repo.GetByID(ctx, movie.ID)
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		var deleted model.Movie
+		require.NoError(t, db.Unscoped().First(&deleted, movie.ID).Error)
+		require.True(t, deleted.DeletedAt.Valid)
+	})
+}
@@ -0,0 +1,355 @@
+// Package repotest provides a conformance suite for repository.IMovieRepository implementations.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"movie-project/internal/model"
+	"movie-project/internal/repository"
+)
+
+// Factory returns an empty repository; it is called once per test case.
+type Factory func(t *testing.T) repository.IMovieRepository
+
+// RunConformance runs the behaviour every IMovieRepository implementation must provide.
+func RunConformance(t *testing.T, newRepository Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repo repository.IMovieRepository)
+	}{
+		{"CreateAssignsID", testCreateAssignsID},
+		{"GetByID", testGetByID},
+		{"GetByIDNotFound", testGetByIDNotFound},
+		{"ListOrderedByID", testListOrderedByID},
+		{"ListPaginationBounds", testListPaginationBounds},
+		{"Update", testUpdate},
+		{"UpdateFields", testUpdateFields},
+		{"UpdateNotFound", testUpdateNotFound},
+		{"Delete", testDelete},
+		{"DeleteNotFound", testDeleteNotFound},
+		{"DeletedExcludedFromList", testDeletedExcludedFromList},
+		{"CountByGenre", testCountByGenre},
+		{"SetMediaKey", testSetMediaKey},
+		{"Metadata", testMetadata},
+		{"ExternalIDsUnique", testExternalIDsUnique},
+		{"ContextCancellation", testContextCancellation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepository(t))
+		})
+	}
+}
+
+// NewMovie returns a valid movie with the given title.
+func NewMovie(title string) *model.Movie {
+	return &model.Movie{
+		Title:       title,
+		Director:    "Director of " + title,
+		ReleaseDate: time.Date(2001, 9, 14, 0, 0, 0, 0, time.UTC),
+		Genre:       "Drama",
+		Rating:      7.5,
+	}
+}
+
+func createMovies(t *testing.T, repo repository.IMovieRepository, n int) []*model.Movie {
+	movies := make([]*model.Movie, n)
+	for i := range movies {
+		movies[i] = NewMovie(fmt.Sprintf("Movie %d", i+1))
+		require.NoError(t, repo.Create(context.Background(), movies[i]))
+	}
+	return movies
+}
+
+func titles(movies []*model.Movie) []string {
+	result := make([]string, len(movies))
+	for i, movie := range movies {
+		result[i] = movie.Title
+	}
+	return result
+}
+
+func testCreateAssignsID(t *testing.T, repo repository.IMovieRepository) {
+	first, second := NewMovie("First"), NewMovie("Second")
+	require.NoError(t, repo.Create(context.Background(), first))
+	require.NoError(t, repo.Create(context.Background(), second))
+
+	assert.NotZero(t, first.ID)
+	assert.Greater(t, second.ID, first.ID)
+	assert.False(t, first.CreatedAt.IsZero())
+	assert.False(t, first.UpdatedAt.IsZero())
+}
+
+func testGetByID(t *testing.T, repo repository.IMovieRepository) {
+	movie := NewMovie("Amelie")
+	require.NoError(t, repo.Create(context.Background(), movie))
+
+	got, err := repo.GetByID(context.Background(), movie.ID)
+	require.NoError(t, err)
+	assert.Equal(t, movie.ID, got.ID)
+	assert.Equal(t, movie.Title, got.Title)
+	assert.Equal(t, movie.Director, got.Director)
+	assert.Equal(t, movie.Genre, got.Genre)
+	assert.Equal(t, movie.Rating, got.Rating)
+	assert.True(t, movie.ReleaseDate.Equal(got.ReleaseDate), "release date %v, want %v", got.ReleaseDate, movie.ReleaseDate)
+	assert.True(t, movie.CreatedAt.Equal(got.CreatedAt), "created at %v, want %v", got.CreatedAt, movie.CreatedAt)
+}
+
+func testGetByIDNotFound(t *testing.T, repo repository.IMovieRepository) {
+	_, err := repo.GetByID(context.Background(), 4242)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func testListOrderedByID(t *testing.T, repo repository.IMovieRepository) {
+	createMovies(t, repo, 5)
+
+	movies, total, err := repo.List(context.Background(), 0, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, total)
+	assert.Equal(t, []string{"Movie 1", "Movie 2", "Movie 3", "Movie 4", "Movie 5"}, titles(movies))
+
+	movies, total, err = repo.List(context.Background(), 1, 3)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, total)
+	assert.Equal(t, []string{"Movie 2", "Movie 3", "Movie 4"}, titles(movies))
+}
+
+func testListPaginationBounds(t *testing.T, repo repository.IMovieRepository) {
+	createMovies(t, repo, 12)
+
+	movies, total, err := repo.List(context.Background(), -5, 2)
+	require.NoError(t, err, "negative offset is treated as zero")
+	assert.EqualValues(t, 12, total)
+	assert.Equal(t, []string{"Movie 1", "Movie 2"}, titles(movies))
+
+	movies, _, err = repo.List(context.Background(), 0, 0)
+	require.NoError(t, err, "non-positive limit falls back to the default page size")
+	assert.Len(t, movies, 10)
+
+	movies, total, err = repo.List(context.Background(), 10, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 12, total)
+	assert.Equal(t, []string{"Movie 11", "Movie 12"}, titles(movies))
+
+	movies, total, err = repo.List(context.Background(), 100, 10)
+	require.NoError(t, err, "offset past the end is not an error")
+	assert.EqualValues(t, 12, total)
+	assert.Empty(t, movies)
+}
+
+func testUpdate(t *testing.T, repo repository.IMovieRepository) {
+	movie := NewMovie("Original")
+	require.NoError(t, repo.Create(context.Background(), movie))
+	createdAt := movie.CreatedAt
+
+	update := NewMovie("Updated")
+	update.ID = movie.ID
+	update.Genre = "Comedy"
+	update.Rating = 9
+	require.NoError(t, repo.Update(context.Background(), update))
+
+	got, err := repo.GetByID(context.Background(), movie.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Updated", got.Title)
+	assert.Equal(t, "Comedy", got.Genre)
+	assert.Equal(t, float32(9), got.Rating)
+	assert.True(t, createdAt.Equal(got.CreatedAt), "update must not touch created at")
+
+	_, total, err := repo.List(context.Background(), 0, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total, "update must not insert")
+}
+
+func testUpdateFields(t *testing.T, repo repository.IMovieRepository) {
+	movie := NewMovie("Original")
+	movie.Tagline = "Kept"
+	movie.Certifications = model.Certifications{"US": "R"}
+	require.NoError(t, repo.Create(context.Background(), movie))
+
+	update := &model.Movie{Model: gorm.Model{ID: movie.ID}, Rating: 9, Certifications: model.Certifications{}}
+	require.NoError(t, repo.Update(context.Background(), update, "Rating", "Certifications"))
+	assert.Equal(t, "Original", update.Title, "update returns the fields it leaves alone")
+	assert.Equal(t, "Kept", update.Tagline)
+
+	got, err := repo.GetByID(context.Background(), movie.ID)
+	require.NoError(t, err)
+	assert.Equal(t, float32(9), got.Rating)
+	assert.Empty(t, got.Certifications, "given fields are replaced even when empty")
+	assert.Equal(t, "Original", got.Title)
+	assert.Equal(t, "Director of Original", got.Director)
+	assert.Equal(t, "Kept", got.Tagline)
+
+	missing := &model.Movie{Model: gorm.Model{ID: 4242}, Rating: 9}
+	assert.ErrorIs(t, repo.Update(context.Background(), missing, "Rating"), gorm.ErrRecordNotFound)
+}
+
+func testUpdateNotFound(t *testing.T, repo repository.IMovieRepository) {
+	movie := NewMovie("Ghost")
+	movie.ID = 4242
+	assert.ErrorIs(t, repo.Update(context.Background(), movie), gorm.ErrRecordNotFound)
+
+	_, total, err := repo.List(context.Background(), 0, 10)
+	require.NoError(t, err)
+	assert.Zero(t, total, "update of a missing movie must not insert it")
+}
+
+func testDelete(t *testing.T, repo repository.IMovieRepository) {
+	movie := NewMovie("Doomed")
+	require.NoError(t, repo.Create(context.Background(), movie))
+	require.NoError(t, repo.Delete(context.Background(), movie.ID))
+
+	_, err := repo.GetByID(context.Background(), movie.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func testDeleteNotFound(t *testing.T, repo repository.IMovieRepository) {
+	assert.ErrorIs(t, repo.Delete(context.Background(), 4242), gorm.ErrRecordNotFound)
+
+	movie := NewMovie("Deleted twice")
+	require.NoError(t, repo.Create(context.Background(), movie))
+	require.NoError(t, repo.Delete(context.Background(), movie.ID))
+	assert.ErrorIs(t, repo.Delete(context.Background(), movie.ID), gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, repo.Update(context.Background(), movie), gorm.ErrRecordNotFound)
+}
+
+func testDeletedExcludedFromList(t *testing.T, repo repository.IMovieRepository) {
+	movies := createMovies(t, repo, 3)
+	require.NoError(t, repo.Delete(context.Background(), movies[1].ID))
+
+	listed, total, err := repo.List(context.Background(), 0, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+	assert.Equal(t, []string{"Movie 1", "Movie 3"}, titles(listed))
+}
+
+func testCountByGenre(t *testing.T, repo repository.IMovieRepository) {
+	counts, err := repo.CountByGenre(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+
+	movies := createMovies(t, repo, 4)
+	movies[0].Genre = "Comedy"
+	require.NoError(t, repo.Update(context.Background(), movies[0]))
+	require.NoError(t, repo.Delete(context.Background(), movies[1].ID))
+
+	counts, err = repo.CountByGenre(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"Comedy": 1, "Drama": 2}, counts, "deleted movies are not counted")
+}
+
+func testSetMediaKey(t *testing.T, repo repository.IMovieRepository) {
+	movie := NewMovie("Illustrated")
+	require.NoError(t, repo.Create(context.Background(), movie))
+	require.NoError(t, repo.SetMediaKey(context.Background(), movie.ID, model.MediaPoster, "movies/1/poster.jpg"))
+	require.NoError(t, repo.SetMediaKey(context.Background(), movie.ID, model.MediaBackdrop, "movies/1/backdrop.jpg"))
+
+	update := NewMovie("Renamed")
+	update.ID = movie.ID
+	require.NoError(t, repo.Update(context.Background(), update))
+	assert.Equal(t, "movies/1/poster.jpg", update.PosterKey, "update returns the media keys")
+
+	require.NoError(t, repo.SetMediaKey(context.Background(), movie.ID, model.MediaBackdrop, ""))
+	got, err := repo.GetByID(context.Background(), movie.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", got.Title)
+	assert.Equal(t, "movies/1/poster.jpg", got.PosterKey, "update must not touch media keys")
+	assert.Empty(t, got.BackdropKey)
+
+	assert.ErrorIs(t, repo.SetMediaKey(context.Background(), 4242, model.MediaPoster, "movies/4242/poster.jpg"), gorm.ErrRecordNotFound)
+}
+
+func testMetadata(t *testing.T, repo repository.IMovieRepository) {
+	movie := NewMovie("Detailed")
+	movie.Synopsis = "A long story."
+	movie.Tagline = "Short."
+	movie.RuntimeMinutes = 175
+	movie.ProductionCountries = model.Countries{"US", "IT"}
+	movie.OriginalLanguage = "en"
+	movie.Certifications = model.Certifications{"US": "R", "DE": "16"}
+	movie.Budget = 6_000_000
+	movie.BoxOffice = 250_000_000
+	movie.ImdbID = "tt0068646"
+	movie.TmdbID = 238
+	require.NoError(t, repo.Create(context.Background(), movie))
+
+	got, err := repo.GetByID(context.Background(), movie.ID)
+	require.NoError(t, err)
+	assert.Equal(t, movie.Synopsis, got.Synopsis)
+	assert.Equal(t, movie.Tagline, got.Tagline)
+	assert.Equal(t, movie.RuntimeMinutes, got.RuntimeMinutes)
+	assert.Equal(t, movie.ProductionCountries, got.ProductionCountries)
+	assert.Equal(t, movie.OriginalLanguage, got.OriginalLanguage)
+	assert.Equal(t, movie.Certifications, got.Certifications)
+	assert.Equal(t, movie.Budget, got.Budget)
+	assert.Equal(t, movie.BoxOffice, got.BoxOffice)
+	assert.Equal(t, movie.ImdbID, got.ImdbID)
+	assert.Equal(t, movie.TmdbID, got.TmdbID)
+
+	update := NewMovie("Plain")
+	update.ID = movie.ID
+	require.NoError(t, repo.Update(context.Background(), update))
+	got, err = repo.GetByID(context.Background(), movie.ID)
+	require.NoError(t, err)
+	assert.Empty(t, got.ProductionCountries, "update replaces all fields")
+	assert.Empty(t, got.Certifications)
+	assert.Empty(t, got.ImdbID)
+}
+
+func testExternalIDsUnique(t *testing.T, repo repository.IMovieRepository) {
+	ctx := context.Background()
+	first := NewMovie("First")
+	first.ImdbID, first.TmdbID = "tt0000001", 1
+	require.NoError(t, repo.Create(ctx, first))
+
+	sameImdb := NewMovie("Same IMDb id")
+	sameImdb.ImdbID = "tt0000001"
+	assert.ErrorIs(t, repo.Create(ctx, sameImdb), gorm.ErrDuplicatedKey)
+	sameTmdb := NewMovie("Same TMDB id")
+	sameTmdb.TmdbID = 1
+	assert.ErrorIs(t, repo.Create(ctx, sameTmdb), gorm.ErrDuplicatedKey)
+
+	// Movies without external ids don't conflict
+	require.NoError(t, repo.Create(ctx, NewMovie("No ids")))
+	second := NewMovie("Second")
+	require.NoError(t, repo.Create(ctx, second))
+	second.ImdbID = "tt0000001"
+	assert.ErrorIs(t, repo.Update(ctx, second), gorm.ErrDuplicatedKey)
+
+	// Deleted movies release their ids
+	require.NoError(t, repo.Delete(ctx, first.ID))
+	require.NoError(t, repo.Update(ctx, second))
+}
+
+func testContextCancellation(t *testing.T, repo repository.IMovieRepository) {
+	movie := NewMovie("Existing")
+	require.NoError(t, repo.Create(context.Background(), movie))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assertCanceled := func(err error) {
+		t.Helper()
+		assert.True(t, errors.Is(err, context.Canceled), "expected context.Canceled, got %v", err)
+	}
+
+	assertCanceled(repo.Create(ctx, NewMovie("Never created")))
+	_, err := repo.GetByID(ctx, movie.ID)
+	assertCanceled(err)
+	_, _, err = repo.List(ctx, 0, 10)
+	assertCanceled(err)
+	assertCanceled(repo.Update(ctx, movie))
+	assertCanceled(repo.Delete(ctx, movie.ID))
+
+	_, total, err := repo.List(context.Background(), 0, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total, "canceled calls must not change data")
+}
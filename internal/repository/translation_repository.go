@@ -0,0 +1,155 @@
+// internal/repository/translation_repository.go
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"movie-project/internal/model"
+	"movie-project/pkg/logger"
+	"movie-project/pkg/metrics"
+)
+
+type ITranslationRepository interface {
+	// SetMovieTranslation creates the translation or replaces the one in the same language
+	SetMovieTranslation(ctx context.Context, translation *model.MovieTranslation) error
+	GetMovieTranslation(ctx context.Context, movieID uint, language string) (*model.MovieTranslation, error)
+	// ListMovieTranslations returns the translations of the movies, ordered by movie and language
+	ListMovieTranslations(ctx context.Context, movieIDs ...uint) ([]*model.MovieTranslation, error)
+	DeleteMovieTranslation(ctx context.Context, movieID uint, language string) error
+	// SetGenreTranslation creates the translation or replaces the one in the same language
+	SetGenreTranslation(ctx context.Context, translation *model.GenreTranslation) error
+	GetGenreTranslation(ctx context.Context, genre, language string) (*model.GenreTranslation, error)
+	// ListGenreTranslations returns the translations of the genres, ordered by genre and language
+	ListGenreTranslations(ctx context.Context, genres ...string) ([]*model.GenreTranslation, error)
+	DeleteGenreTranslation(ctx context.Context, genre, language string) error
+}
+
+type TranslationRepository struct {
+	db     gorm.DB
+	logger logger.Logger
+}
+
+func NewTranslationRepository(db gorm.DB, logger logger.Logger) TranslationRepository {
+	return TranslationRepository{db: db, logger: logger}
+}
+
+func (r *TranslationRepository) SetMovieTranslation(ctx context.Context, translation *model.MovieTranslation) error {
+	defer metrics.ObserveQuery("SetMovieTranslation", time.Now())
+
+	// Not every SQLite connection enforces foreign keys, so the movie is checked explicitly
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").Take(&model.Movie{}, translation.MovieID).Error; err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "movie_id"}, {Name: "language"}},
+			DoUpdates: clause.AssignmentColumns([]string{"title", "synopsis", "updated_at"}),
+		}).Create(translation).Error
+	})
+	if err != nil {
+		r.logger.ErrorContext(ctx, "Failed to set movie translation", "error", err, "id", translation.MovieID, "language", translation.Language)
+		return err
+	}
+	return nil
+}
+
+func (r *TranslationRepository) GetMovieTranslation(ctx context.Context, movieID uint, language string) (*model.MovieTranslation, error) {
+	defer metrics.ObserveQuery("GetMovieTranslation", time.Now())
+
+	var translation model.MovieTranslation
+	result := r.db.WithContext(ctx).Where("movie_id = ? AND language = ?", movieID, language).Take(&translation)
+	if result.Error != nil {
+		r.logger.ErrorContext(ctx, "Failed to get movie translation", "error", result.Error, "id", movieID, "language", language)
+		return nil, result.Error
+	}
+	return &translation, nil
+}
+
+func (r *TranslationRepository) ListMovieTranslations(ctx context.Context, movieIDs ...uint) ([]*model.MovieTranslation, error) {
+	defer metrics.ObserveQuery("ListMovieTranslations", time.Now())
+
+	var translations []*model.MovieTranslation
+	if len(movieIDs) == 0 {
+		return translations, nil
+	}
+	result := r.db.WithContext(ctx).Where("movie_id IN ?", movieIDs).Order("movie_id, language").Find(&translations)
+	if result.Error != nil {
+		r.logger.ErrorContext(ctx, "Failed to list movie translations", "error", result.Error)
+		return nil, result.Error
+	}
+	return translations, nil
+}
+
+func (r *TranslationRepository) DeleteMovieTranslation(ctx context.Context, movieID uint, language string) error {
+	defer metrics.ObserveQuery("DeleteMovieTranslation", time.Now())
+
+	result := r.db.WithContext(ctx).Where("movie_id = ? AND language = ?", movieID, language).Delete(&model.MovieTranslation{})
+	if result.Error != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete movie translation", "error", result.Error, "id", movieID, "language", language)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		r.logger.WarnContext(ctx, "Movie translation to delete not found", "id", movieID, "language", language)
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *TranslationRepository) SetGenreTranslation(ctx context.Context, translation *model.GenreTranslation) error {
+	defer metrics.ObserveQuery("SetGenreTranslation", time.Now())
+
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "genre"}, {Name: "language"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "updated_at"}),
+	}).Create(translation)
+	if result.Error != nil {
+		r.logger.ErrorContext(ctx, "Failed to set genre translation", "error", result.Error, "genre", translation.Genre, "language", translation.Language)
+		return result.Error
+	}
+	return nil
+}
+
+func (r *TranslationRepository) GetGenreTranslation(ctx context.Context, genre, language string) (*model.GenreTranslation, error) {
+	defer metrics.ObserveQuery("GetGenreTranslation", time.Now())
+
+	var translation model.GenreTranslation
+	result := r.db.WithContext(ctx).Where("genre = ? AND language = ?", genre, language).Take(&translation)
+	if result.Error != nil {
+		r.logger.ErrorContext(ctx, "Failed to get genre translation", "error", result.Error, "genre", genre, "language", language)
+		return nil, result.Error
+	}
+	return &translation, nil
+}
+
+func (r *TranslationRepository) ListGenreTranslations(ctx context.Context, genres ...string) ([]*model.GenreTranslation, error) {
+	defer metrics.ObserveQuery("ListGenreTranslations", time.Now())
+
+	var translations []*model.GenreTranslation
+	if len(genres) == 0 {
+		return translations, nil
+	}
+	result := r.db.WithContext(ctx).Where("genre IN ?", genres).Order("genre, language").Find(&translations)
+	if result.Error != nil {
+		r.logger.ErrorContext(ctx, "Failed to list genre translations", "error", result.Error)
+		return nil, result.Error
+	}
+	return translations, nil
+}
+
+func (r *TranslationRepository) DeleteGenreTranslation(ctx context.Context, genre, language string) error {
+	defer metrics.ObserveQuery("DeleteGenreTranslation", time.Now())
+
+	result := r.db.WithContext(ctx).Where("genre = ? AND language = ?", genre, language).Delete(&model.GenreTranslation{})
+	if result.Error != nil {
+		r.logger.ErrorContext(ctx, "Failed to delete genre translation", "error", result.Error, "genre", genre, "language", language)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		r.logger.WarnContext(ctx, "Genre translation to delete not found", "genre", genre, "language", language)
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"movie-project/internal/model"
+	"movie-project/internal/repository"
+	"movie-project/internal/repository/repotest"
+	"movie-project/pkg/logger"
+)
+
+func TestTranslationRepository_MovieTranslations(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, movies *repository.MovieRepository, db *gorm.DB) {
+		ctx := context.Background()
+		repo := repository.NewTranslationRepository(*db, *logger.Discard())
+		first, second := repotest.NewMovie("First"), repotest.NewMovie("Second")
+		require.NoError(t, movies.Create(ctx, first))
+		require.NoError(t, movies.Create(ctx, second))
+
+		require.NoError(t, repo.SetMovieTranslation(ctx, &model.MovieTranslation{MovieID: first.ID, Language: "fr", Title: "Premier"}))
+		require.NoError(t, repo.SetMovieTranslation(ctx, &model.MovieTranslation{MovieID: first.ID, Language: "de", Title: "Erster"}))
+		require.NoError(t, repo.SetMovieTranslation(ctx, &model.MovieTranslation{MovieID: second.ID, Language: "de", Title: "Zweiter"}))
+
+		// Setting a translation again replaces it
+		require.NoError(t, repo.SetMovieTranslation(ctx, &model.MovieTranslation{MovieID: first.ID, Language: "de", Title: "Der Erste", Synopsis: "Kurz"}))
+		got, err := repo.GetMovieTranslation(ctx, first.ID, "de")
+		require.NoError(t, err)
+		assert.Equal(t, "Der Erste", got.Title)
+		assert.Equal(t, "Kurz", got.Synopsis)
+
+		translations, err := repo.ListMovieTranslations(ctx, first.ID)
+		require.NoError(t, err)
+		require.Len(t, translations, 2)
+		assert.Equal(t, "de", translations[0].Language, "ordered by language")
+		assert.Equal(t, "fr", translations[1].Language)
+
+		translations, err = repo.ListMovieTranslations(ctx, second.ID, first.ID)
+		require.NoError(t, err)
+		assert.Len(t, translations, 3)
+		translations, err = repo.ListMovieTranslations(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, translations)
+
+		require.NoError(t, repo.DeleteMovieTranslation(ctx, first.ID, "fr"))
+		_, err = repo.GetMovieTranslation(ctx, first.ID, "fr")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, repo.DeleteMovieTranslation(ctx, first.ID, "fr"), gorm.ErrRecordNotFound)
+	})
+}
+
+func TestTranslationRepository_MovieMustExist(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, movies *repository.MovieRepository, db *gorm.DB) {
+		ctx := context.Background()
+		repo := repository.NewTranslationRepository(*db, *logger.Discard())
+
+		err := repo.SetMovieTranslation(ctx, &model.MovieTranslation{MovieID: 42, Language: "de", Title: "Fehlt"})
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		movie := repotest.NewMovie("Deleted")
+		require.NoError(t, movies.Create(ctx, movie))
+		require.NoError(t, movies.Delete(ctx, movie.ID))
+		err = repo.SetMovieTranslation(ctx, &model.MovieTranslation{MovieID: movie.ID, Language: "de", Title: "Gelöscht"})
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "soft-deleted movies are missing too")
+	})
+}
+
+func TestTranslationRepository_GenreTranslations(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, _ *repository.MovieRepository, db *gorm.DB) {
+		ctx := context.Background()
+		repo := repository.NewTranslationRepository(*db, *logger.Discard())
+
+		require.NoError(t, repo.SetGenreTranslation(ctx, &model.GenreTranslation{Genre: "Drama", Language: "fr", Name: "Drame"}))
+		require.NoError(t, repo.SetGenreTranslation(ctx, &model.GenreTranslation{Genre: "Drama", Language: "de", Name: "Drama"}))
+		require.NoError(t, repo.SetGenreTranslation(ctx, &model.GenreTranslation{Genre: "Comedy", Language: "de", Name: "Komödie"}))
+		require.NoError(t, repo.SetGenreTranslation(ctx, &model.GenreTranslation{Genre: "Comedy", Language: "de", Name: "Lustspiel"}))
+
+		got, err := repo.GetGenreTranslation(ctx, "Comedy", "de")
+		require.NoError(t, err)
+		assert.Equal(t, "Lustspiel", got.Name, "setting a translation again replaces it")
+
+		translations, err := repo.ListGenreTranslations(ctx, "Drama", "Comedy")
+		require.NoError(t, err)
+		require.Len(t, translations, 3)
+		assert.Equal(t, []string{"Comedy", "Drama", "Drama"}, []string{translations[0].Genre, translations[1].Genre, translations[2].Genre})
+		assert.Equal(t, []string{"de", "de", "fr"}, []string{translations[0].Language, translations[1].Language, translations[2].Language})
+
+		require.NoError(t, repo.DeleteGenreTranslation(ctx, "Drama", "fr"))
+		_, err = repo.GetGenreTranslation(ctx, "Drama", "fr")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, repo.DeleteGenreTranslation(ctx, "Drama", "fr"), gorm.ErrRecordNotFound)
+	})
+}
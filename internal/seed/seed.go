@@ -0,0 +1,150 @@
+// Package seed provides movies to populate development databases with: fake movies generated
+// from a seed and a bundled sample of real ones.
+package seed
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"gorm.io/gorm"
+
+	"movie-project/config"
+	"movie-project/internal/model"
+)
+
+//go:embed sample.json
+var sampleJSON []byte
+
+// Sample returns the bundled sample of real movies
+func Sample() ([]*model.Movie, error) {
+	var entries []struct {
+		Title       string  `json:"title"`
+		Director    string  `json:"director"`
+		ReleaseDate string  `json:"release_date"`
+		Genre       string  `json:"genre"`
+		Rating      float32 `json:"rating"`
+	}
+	if err := json.Unmarshal(sampleJSON, &entries); err != nil {
+		return nil, err
+	}
+
+	movies := make([]*model.Movie, len(entries))
+	for i, e := range entries {
+		releaseDate, err := time.Parse("2006-01-02", e.ReleaseDate)
+		if err != nil {
+			return nil, fmt.Errorf("sample movie %q: %w", e.Title, err)
+		}
+		movies[i] = &model.Movie{Title: e.Title, Director: e.Director, ReleaseDate: releaseDate, Genre: e.Genre, Rating: e.Rating}
+	}
+	return movies, nil
+}
+
+var (
+	adjectives = []string{"Last", "Silent", "Crimson", "Hidden", "Broken", "Endless", "Golden", "Forgotten",
+		"Burning", "Distant", "Midnight", "Wild", "Hollow", "Electric", "Quiet", "Restless", "Northern", "Lost"}
+	nouns = []string{"Horizon", "River", "Garden", "Signal", "Empire", "Summer", "Stranger", "Harbor", "Echo",
+		"Witness", "Kingdom", "Frontier", "Letter", "Orchard", "Machine", "Winter", "Voyage", "Promise"}
+	places = []string{"Kyoto", "Marseille", "the Valley", "Lagos", "the North", "Havana", "Montana", "Naples",
+		"the City", "Reykjavik", "Bombay", "the Desert", "Tangier", "Oslo", "the Coast", "Buenos Aires"}
+	titlePatterns = []func(r *rand.Rand) string{
+		func(r *rand.Rand) string { return "The " + pick(r, adjectives) + " " + pick(r, nouns) },
+		func(r *rand.Rand) string { return pick(r, nouns) + " of " + pick(r, places) },
+		func(r *rand.Rand) string { a, b := pickTwo(r, nouns); return a + " and the " + b },
+		func(r *rand.Rand) string { return pick(r, nouns) + " in " + pick(r, places) },
+		func(r *rand.Rand) string { a, b := pickTwo(r, nouns); return "The " + a + "'s " + b },
+		func(r *rand.Rand) string { return "The " + pick(r, nouns) },
+	}
+
+	firstNames = []string{"Agnès", "Akira", "Ana", "Bong", "Chloé", "David", "Elena", "Federico", "Greta",
+		"Hirokazu", "Ingmar", "Jane", "Kelly", "Lucrecia", "Mati", "Nuri", "Pedro", "Satyajit", "Sofia", "Wim"}
+	lastNames = []string{"Almodóvar", "Bigelow", "Campion", "Denis", "Ferrante", "Gerwig", "Haneke", "Iñárritu",
+		"Jarmusch", "Kaurismäki", "Lynch", "Martel", "Nolan", "Ozon", "Petzold", "Reichardt", "Sciamma", "Varda"}
+
+	// genres are weighted by how common they are, drama most of all
+	genres = []string{"Drama", "Drama", "Drama", "Comedy", "Comedy", "Thriller", "Thriller", "Action", "Action",
+		"Romance", "Crime", "Horror", "Science Fiction", "Documentary", "Animation", "Fantasy", "Adventure",
+		"Mystery", "Western", "War", "Musical"}
+)
+
+func pick(r *rand.Rand, values []string) string {
+	return values[r.IntN(len(values))]
+}
+
+// pickTwo returns two different values
+func pickTwo(r *rand.Rand, values []string) (string, string) {
+	i, j := r.IntN(len(values)), r.IntN(len(values)-1)
+	if j >= i {
+		j++
+	}
+	return values[i], values[j]
+}
+
+// Generator generates realistic fake movies. The same seed always yields the same movies.
+type Generator struct {
+	r *rand.Rand
+}
+
+// NewGenerator returns a generator of the movies for seed
+func NewGenerator(seed uint64) *Generator {
+	return &Generator{r: rand.New(rand.NewPCG(seed, seed))}
+}
+
+// Movie returns the next fake movie
+func (g *Generator) Movie() *model.Movie {
+	return &model.Movie{
+		Title:       titlePatterns[g.r.IntN(len(titlePatterns))](g.r),
+		Director:    pick(g.r, firstNames) + " " + pick(g.r, lastNames),
+		ReleaseDate: g.releaseDate(),
+		Genre:       pick(g.r, genres),
+		Rating:      g.rating(),
+	}
+}
+
+// Movies returns the next n fake movies
+func (g *Generator) Movies(n int) []*model.Movie {
+	movies := make([]*model.Movie, n)
+	for i := range movies {
+		movies[i] = g.Movie()
+	}
+	return movies
+}
+
+// releaseDate returns a date between 1920 and 2024, recent years being more likely
+func (g *Generator) releaseDate() time.Time {
+	year := max(1920, 2024-int(g.r.ExpFloat64()*15))
+	return time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, g.r.IntN(365))
+}
+
+// rating returns a rating around 6.5, rounded to one decimal like the database stores it
+func (g *Generator) rating() float32 {
+	rating := min(10, max(1, 6.5+g.r.NormFloat64()*1.2))
+	return float32(math.Round(rating*10) / 10)
+}
+
+// Truncate deletes all movies, soft-deleted ones included, with their translations and
+// restarts their ids at 1. Posters and backdrops uploaded for them stay in the media store;
+// clear MEDIA_DIR or the bucket to remove them.
+func Truncate(ctx context.Context, db *gorm.DB) error {
+	db = db.WithContext(ctx)
+	switch name := db.Dialector.Name(); name {
+	case config.DriverPostgres:
+		return db.Exec("TRUNCATE movies, movie_translations RESTART IDENTITY").Error
+	case config.DriverSQLite:
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("DELETE FROM movie_translations").Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("DELETE FROM movies").Error; err != nil {
+				return err
+			}
+			return tx.Exec("DELETE FROM sqlite_sequence WHERE name = 'movies'").Error
+		})
+	default:
+		return fmt.Errorf("unsupported database: %q", name)
+	}
+}
@@ -0,0 +1,71 @@
+package seed
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"movie-project/config"
+	"movie-project/internal/model"
+	"movie-project/migrations"
+	"movie-project/pkg/database"
+	"movie-project/pkg/logger"
+)
+
+func TestGenerator(t *testing.T) {
+	movies := NewGenerator(42).Movies(200)
+	assert.Equal(t, movies, NewGenerator(42).Movies(200), "the same seed yields the same movies")
+	assert.NotEqual(t, movies, NewGenerator(43).Movies(200))
+
+	validate := model.NewValidator()
+	titles := map[string]bool{}
+	for _, movie := range movies {
+		require.NoError(t, validate.Struct(movie), movie.Title)
+		assert.Equal(t, movie.ReleaseDate, movie.ReleaseDate.Truncate(24*time.Hour), "release dates are whole days")
+		titles[movie.Title] = true
+	}
+	assert.Greater(t, len(titles), 100, "titles vary")
+}
+
+func TestSample(t *testing.T) {
+	movies, err := Sample()
+	require.NoError(t, err)
+	assert.NotEmpty(t, movies)
+
+	validate := model.NewValidator()
+	for _, movie := range movies {
+		assert.NoError(t, validate.Struct(movie), movie.Title)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	cfg := config.Config{DBDriver: config.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "movie.db")}
+	db, err := database.Open(cfg, logger.Discard())
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	// The schema of the migrations, not of AutoMigrate, is what Truncate runs against
+	ctx := context.Background()
+	require.NoError(t, migrations.ApplyOnStart(ctx, cfg, sqlDB, logger.Discard()))
+	movies := NewGenerator(1).Movies(3)
+	require.NoError(t, db.Create(movies).Error)
+	require.NoError(t, db.Delete(movies[0]).Error)
+	require.NoError(t, db.Create(&model.MovieTranslation{MovieID: movies[1].ID, Language: "de", Title: "Der Fluss"}).Error)
+
+	require.NoError(t, Truncate(ctx, db))
+	var count int64
+	require.NoError(t, db.Unscoped().Model(&model.Movie{}).Count(&count).Error)
+	assert.Zero(t, count, "soft-deleted movies are removed too")
+	require.NoError(t, db.Model(&model.MovieTranslation{}).Count(&count).Error)
+	assert.Zero(t, count, "translations are removed with their movies")
+
+	movie := NewGenerator(2).Movie()
+	require.NoError(t, db.Create(movie).Error)
+	assert.Equal(t, uint(1), movie.ID, "ids restart at 1")
+}
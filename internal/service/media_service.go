@@ -0,0 +1,180 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"movie-project/internal/model"
+	"movie-project/internal/repository"
+	"movie-project/pkg/imaging"
+	"movie-project/pkg/logger"
+	"movie-project/pkg/storage"
+	"movie-project/pkg/tracing"
+)
+
+// ErrInvalidMedia is returned for uploads that are not a supported image, are too large,
+// or are of an unknown kind
+var ErrInvalidMedia = errors.New("invalid media")
+
+// MediaOptions configure the MediaService
+type MediaOptions struct {
+	MaxSize       int64 // largest accepted upload in bytes
+	ThumbnailSize int   // thumbnails fit in a square of this many pixels
+}
+
+// MediaService stores the images of movies and their thumbnails in a blob store
+type MediaService struct {
+	repo   repository.MovieRepository
+	store  storage.BlobStore
+	opts   MediaOptions
+	logger logger.Logger
+}
+
+func NewMediaService(repo repository.MovieRepository, store storage.BlobStore, opts MediaOptions, logger logger.Logger) MediaService {
+	return MediaService{repo: repo, store: store, opts: opts, logger: logger}
+}
+
+// MaxSize returns the largest accepted upload in bytes
+func (s *MediaService) MaxSize() int64 {
+	return s.opts.MaxSize
+}
+
+// Upload stores data as the image of the kind of a movie, together with a thumbnail, and
+// deletes the image it replaces. contentType may be empty; otherwise it must match data.
+func (s *MediaService) Upload(ctx context.Context, id uint, kind model.MediaKind, contentType string, data []byte) (movie *model.Movie, err error) {
+	ctx, span := tracing.Start(ctx, "MediaService.Upload",
+		attribute.Int("movie.id", int(id)), attribute.String("media.kind", string(kind)), attribute.Int("media.size", len(data)))
+	defer func() { tracing.End(span, err) }()
+
+	contentType, err = s.validate(kind, contentType, data)
+	if err != nil {
+		s.logger.WarnContext(ctx, "Invalid media upload", "error", err, "id", id, "kind", kind)
+		return nil, err
+	}
+	img, err := imaging.Decode(data)
+	if err != nil {
+		s.logger.WarnContext(ctx, "Invalid media upload", "error", err, "id", id, "kind", kind)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMedia, err)
+	}
+	thumbnail, err := imaging.Encode(imaging.Fit(img, s.opts.ThumbnailSize, s.opts.ThumbnailSize), contentType)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to create thumbnail", "error", err, "id", id, "kind", kind)
+		return nil, err
+	}
+
+	movie, err = s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys change with the content, so caches never serve a replaced image
+	sum := sha256.Sum256(data)
+	key := fmt.Sprintf("movies/%d/%s-%x%s", id, kind, sum[:8], imaging.Extensions[contentType])
+	if err := s.put(ctx, key, data, contentType); err != nil {
+		return nil, err
+	}
+	if err := s.put(ctx, model.ThumbnailKey(key), thumbnail, contentType); err != nil {
+		return nil, err
+	}
+
+	previous := movie.MediaKey(kind)
+	if err := s.repo.SetMediaKey(ctx, id, kind, key); err != nil {
+		if key != previous {
+			s.deleteBlobs(ctx, key)
+		}
+		return nil, err
+	}
+	if previous != "" && previous != key {
+		s.deleteBlobs(ctx, previous)
+	}
+
+	movie.SetMediaKey(kind, key)
+	s.logger.InfoContext(ctx, "Uploaded media", "id", id, "kind", kind, "key", key, "size", len(data))
+	return movie, nil
+}
+
+// validate checks an upload and returns its content type, detected from data
+func (s *MediaService) validate(kind model.MediaKind, contentType string, data []byte) (string, error) {
+	if !kind.Valid() {
+		return "", fmt.Errorf("%w: unknown kind %q, expected %q or %q", ErrInvalidMedia, kind, model.MediaPoster, model.MediaBackdrop)
+	}
+	if len(data) == 0 {
+		return "", fmt.Errorf("%w: empty upload", ErrInvalidMedia)
+	}
+	if int64(len(data)) > s.opts.MaxSize {
+		return "", fmt.Errorf("%w: %d bytes is more than the limit of %d", ErrInvalidMedia, len(data), s.opts.MaxSize)
+	}
+
+	detected := http.DetectContentType(data)
+	if _, ok := imaging.Extensions[detected]; !ok {
+		return "", fmt.Errorf("%w: content is %s, expected image/jpeg or image/png", ErrInvalidMedia, detected)
+	}
+	if contentType != "" {
+		declared, _, err := mime.ParseMediaType(contentType)
+		if err != nil || declared != detected {
+			return "", fmt.Errorf("%w: content type is %q, but the content is %s", ErrInvalidMedia, contentType, detected)
+		}
+	}
+	return detected, nil
+}
+
+// Delete removes the image of the kind of a movie and its thumbnail. Deleting a missing
+// image is not an error.
+func (s *MediaService) Delete(ctx context.Context, id uint, kind model.MediaKind) (movie *model.Movie, err error) {
+	ctx, span := tracing.Start(ctx, "MediaService.Delete", attribute.Int("movie.id", int(id)), attribute.String("media.kind", string(kind)))
+	defer func() { tracing.End(span, err) }()
+
+	if !kind.Valid() {
+		return nil, fmt.Errorf("%w: unknown kind %q, expected %q or %q", ErrInvalidMedia, kind, model.MediaPoster, model.MediaBackdrop)
+	}
+	movie, err = s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	key := movie.MediaKey(kind)
+	if key == "" {
+		return movie, nil
+	}
+
+	if err := s.repo.SetMediaKey(ctx, id, kind, ""); err != nil {
+		return nil, err
+	}
+	s.deleteBlobs(ctx, key)
+
+	movie.SetMediaKey(kind, "")
+	s.logger.InfoContext(ctx, "Deleted media", "id", id, "kind", kind, "key", key)
+	return movie, nil
+}
+
+// URL returns the URL of the blob stored under key, or "" for no key
+func (s *MediaService) URL(key string) string {
+	if key == "" {
+		return ""
+	}
+	return s.store.URL(key)
+}
+
+func (s *MediaService) put(ctx context.Context, key string, data []byte, contentType string) error {
+	if err := s.store.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to store media", "error", err, "key", key)
+		return err
+	}
+	return nil
+}
+
+// deleteBlobs deletes an image and its thumbnail. Failures only leave unreferenced blobs
+// behind, so they are logged rather than returned.
+func (s *MediaService) deleteBlobs(ctx context.Context, key string) {
+	for _, k := range []string{key, model.ThumbnailKey(key)} {
+		if err := s.store.Delete(ctx, k); err != nil {
+			s.logger.WarnContext(ctx, "Failed to delete media", "error", err, "key", k)
+		}
+	}
+}
@@ -4,14 +4,18 @@ package service
 import (
 	"context"
 	"errors"
+	"time"
+
 	"github.com/go-playground/validator/v10"
 
+	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
 
 	"movie-project/internal/model"
 	"movie-project/internal/repository"
 	"movie-project/pkg/logger"
 	"movie-project/pkg/metrics"
+	"movie-project/pkg/tracing"
 )
 
 type MovieService struct {
@@ -24,17 +28,20 @@ func NewMovieService(repo repository.MovieRepository, logger logger.Logger) Movi
 	return MovieService{
 		repo:     repo,
 		logger:   logger,
-		validate: validator.New(),
+		validate: model.NewValidator(),
 	}
 }
 
-func (s *MovieService) CreateMovie(ctx context.Context, movie *model.Movie) error {
+func (s *MovieService) CreateMovie(ctx context.Context, movie *model.Movie) (err error) {
+	ctx, span := tracing.Start(ctx, "MovieService.CreateMovie")
+	defer func() { tracing.End(span, err) }()
+
 	if err := s.validate.Struct(movie); err != nil {
 		s.logger.ErrorContext(ctx, "Invalid movie data", "error", err)
 		return err
 	}
 
-	err := s.repo.Create(ctx, movie)
+	err = s.repo.Create(ctx, movie)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "Failed to create movie", "error", err)
 		return err
@@ -45,8 +52,11 @@ func (s *MovieService) CreateMovie(ctx context.Context, movie *model.Movie) erro
 	return nil
 }
 
-func (s *MovieService) GetMovie(ctx context.Context, id uint) (*model.Movie, error) {
-	movie, err := s.repo.GetByID(ctx, id)
+func (s *MovieService) GetMovie(ctx context.Context, id uint) (movie *model.Movie, err error) {
+	ctx, span := tracing.Start(ctx, "MovieService.GetMovie", attribute.Int("movie.id", int(id)))
+	defer func() { tracing.End(span, err) }()
+
+	movie, err = s.repo.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			s.logger.WarnContext(ctx, "Movie not found", "id", id)
@@ -57,11 +67,14 @@ func (s *MovieService) GetMovie(ctx context.Context, id uint) (*model.Movie, err
 	}
 
 	metrics.MovieRetrievals.Inc()
-	s.logger.InfoContext(ctx, "Retrieved movie", "id", id)
+	s.logger.DebugContext(ctx, "Retrieved movie", "id", id)
 	return movie, nil
 }
 
-func (s *MovieService) ListMovies(ctx context.Context, page, pageSize int) ([]*model.Movie, int64, error) {
+func (s *MovieService) ListMovies(ctx context.Context, page, pageSize int) (movies []*model.Movie, total int64, err error) {
+	ctx, span := tracing.Start(ctx, "MovieService.ListMovies", attribute.Int("page", page), attribute.Int("page_size", pageSize))
+	defer func() { tracing.End(span, err) }()
+
 	if page < 1 {
 		page = 1
 	}
@@ -71,25 +84,35 @@ func (s *MovieService) ListMovies(ctx context.Context, page, pageSize int) ([]*m
 
 	offset := (page - 1) * pageSize
 
-	s.logger.InfoContext(ctx, "Listing movies", "page", page, "pageSize", pageSize, "offset", offset)
+	s.logger.DebugContext(ctx, "Listing movies", "page", page, "pageSize", pageSize, "offset", offset)
 
-	movies, total, err := s.repo.List(ctx, offset, pageSize)
+	movies, total, err = s.repo.List(ctx, offset, pageSize)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "Failed to list movies", "error", err, "page", page, "pageSize", pageSize)
 		return nil, 0, err
 	}
 
-	s.logger.InfoContext(ctx, "Listed movies", "page", page, "pageSize", pageSize, "total", total, "retrieved", len(movies))
+	s.logger.DebugContext(ctx, "Listed movies", "page", page, "pageSize", pageSize, "total", total, "retrieved", len(movies))
 	return movies, total, nil
 }
 
-func (s *MovieService) UpdateMovie(ctx context.Context, movie *model.Movie) error {
-	if err := s.validate.Struct(movie); err != nil {
+// UpdateMovie replaces the given fields of the movie, by their names in model.Movie, or all of
+// them without fields. Only the replaced fields are validated; movie is set to the updated movie.
+func (s *MovieService) UpdateMovie(ctx context.Context, movie *model.Movie, fields ...string) (err error) {
+	ctx, span := tracing.Start(ctx, "MovieService.UpdateMovie", attribute.Int("movie.id", int(movie.ID)))
+	defer func() { tracing.End(span, err) }()
+
+	if len(fields) > 0 {
+		err = s.validate.StructPartial(movie, fields...)
+	} else {
+		err = s.validate.Struct(movie)
+	}
+	if err != nil {
 		s.logger.ErrorContext(ctx, "Invalid movie data for update", "error", err)
 		return err
 	}
 
-	err := s.repo.Update(ctx, movie)
+	err = s.repo.Update(ctx, movie, fields...)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "Failed to update movie", "error", err, "id", movie.ID)
 		return err
@@ -100,8 +123,11 @@ func (s *MovieService) UpdateMovie(ctx context.Context, movie *model.Movie) erro
 	return nil
 }
 
-func (s *MovieService) DeleteMovie(ctx context.Context, id uint) error {
-	err := s.repo.Delete(ctx, id)
+func (s *MovieService) DeleteMovie(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "MovieService.DeleteMovie", attribute.Int("movie.id", int(id)))
+	defer func() { tracing.End(span, err) }()
+
+	err = s.repo.Delete(ctx, id)
 	if err != nil {
 		s.logger.ErrorContext(ctx, "Failed to delete movie", "error", err, "id", id)
 		return err
@@ -111,3 +137,29 @@ func (s *MovieService) DeleteMovie(ctx context.Context, id uint) error {
 	s.logger.InfoContext(ctx, "Deleted movie", "id", id)
 	return nil
 }
+
+// RefreshMetrics updates the business gauges, such as movies per genre, from the database
+func (s *MovieService) RefreshMetrics(ctx context.Context) error {
+	counts, err := s.repo.CountByGenre(ctx)
+	if err != nil {
+		return err
+	}
+	metrics.SetMoviesByGenre(counts)
+	return nil
+}
+
+// RunMetricsRefresh refreshes the business gauges now and then every interval until ctx is done
+func (s *MovieService) RunMetricsRefresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.RefreshMetrics(ctx); err != nil && ctx.Err() == nil {
+			s.logger.WarnContext(ctx, "Failed to refresh metrics", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
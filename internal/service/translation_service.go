@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-playground/validator/v10"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/text/language"
+	"gorm.io/gorm"
+
+	"movie-project/internal/model"
+	"movie-project/internal/repository"
+	"movie-project/pkg/logger"
+	"movie-project/pkg/tracing"
+)
+
+// ErrTranslationNotFound is returned for translations that don't exist
+var ErrTranslationNotFound = errors.New("translation not found")
+
+// Localization is the text of a movie in the languages that best match a request
+type Localization struct {
+	// Language of the translation of Title and Synopsis, "" when both are the original ones
+	Language string
+	Title    string
+	Synopsis string
+	Genre    string
+}
+
+// TranslationService manages the translations of movies and genres and localizes movies
+type TranslationService struct {
+	repo     repository.TranslationRepository
+	movies   repository.MovieRepository
+	logger   logger.Logger
+	validate *validator.Validate
+}
+
+func NewTranslationService(repo repository.TranslationRepository, movies repository.MovieRepository, logger logger.Logger) TranslationService {
+	return TranslationService{
+		repo:     repo,
+		movies:   movies,
+		logger:   logger,
+		validate: model.NewValidator(),
+	}
+}
+
+// SetMovieTranslation creates or replaces the translation of a movie in a language
+func (s *TranslationService) SetMovieTranslation(ctx context.Context, translation *model.MovieTranslation) (err error) {
+	ctx, span := tracing.Start(ctx, "TranslationService.SetMovieTranslation",
+		attribute.Int("movie.id", int(translation.MovieID)), attribute.String("language", translation.Language))
+	defer func() { tracing.End(span, err) }()
+
+	translation.Language = model.CanonicalLanguage(translation.Language)
+	if err := s.validate.Struct(translation); err != nil {
+		s.logger.WarnContext(ctx, "Invalid movie translation", "error", err)
+		return err
+	}
+
+	if err := s.repo.SetMovieTranslation(ctx, translation); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "Set movie translation", "id", translation.MovieID, "language", translation.Language)
+	return nil
+}
+
+func (s *TranslationService) GetMovieTranslation(ctx context.Context, movieID uint, lang string) (translation *model.MovieTranslation, err error) {
+	ctx, span := tracing.Start(ctx, "TranslationService.GetMovieTranslation", attribute.Int("movie.id", int(movieID)), attribute.String("language", lang))
+	defer func() { tracing.End(span, err) }()
+
+	translation, err = s.repo.GetMovieTranslation(ctx, movieID, model.CanonicalLanguage(lang))
+	return translation, translationNotFound(err)
+}
+
+// ListMovieTranslations returns the translations of a movie ordered by language
+func (s *TranslationService) ListMovieTranslations(ctx context.Context, movieID uint) (translations []*model.MovieTranslation, err error) {
+	ctx, span := tracing.Start(ctx, "TranslationService.ListMovieTranslations", attribute.Int("movie.id", int(movieID)))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.movies.GetByID(ctx, movieID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMovieTranslations(ctx, movieID)
+}
+
+func (s *TranslationService) DeleteMovieTranslation(ctx context.Context, movieID uint, lang string) (err error) {
+	ctx, span := tracing.Start(ctx, "TranslationService.DeleteMovieTranslation", attribute.Int("movie.id", int(movieID)), attribute.String("language", lang))
+	defer func() { tracing.End(span, err) }()
+
+	lang = model.CanonicalLanguage(lang)
+	if err := s.repo.DeleteMovieTranslation(ctx, movieID, lang); err != nil {
+		return translationNotFound(err)
+	}
+	s.logger.InfoContext(ctx, "Deleted movie translation", "id", movieID, "language", lang)
+	return nil
+}
+
+// SetGenreTranslation creates or replaces the name of a genre in a language
+func (s *TranslationService) SetGenreTranslation(ctx context.Context, translation *model.GenreTranslation) (err error) {
+	ctx, span := tracing.Start(ctx, "TranslationService.SetGenreTranslation",
+		attribute.String("genre", translation.Genre), attribute.String("language", translation.Language))
+	defer func() { tracing.End(span, err) }()
+
+	translation.Language = model.CanonicalLanguage(translation.Language)
+	if err := s.validate.Struct(translation); err != nil {
+		s.logger.WarnContext(ctx, "Invalid genre translation", "error", err)
+		return err
+	}
+
+	if err := s.repo.SetGenreTranslation(ctx, translation); err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "Set genre translation", "genre", translation.Genre, "language", translation.Language)
+	return nil
+}
+
+func (s *TranslationService) GetGenreTranslation(ctx context.Context, genre, lang string) (translation *model.GenreTranslation, err error) {
+	ctx, span := tracing.Start(ctx, "TranslationService.GetGenreTranslation", attribute.String("genre", genre), attribute.String("language", lang))
+	defer func() { tracing.End(span, err) }()
+
+	translation, err = s.repo.GetGenreTranslation(ctx, genre, model.CanonicalLanguage(lang))
+	return translation, translationNotFound(err)
+}
+
+// ListGenreTranslations returns the translations of a genre ordered by language
+func (s *TranslationService) ListGenreTranslations(ctx context.Context, genre string) (translations []*model.GenreTranslation, err error) {
+	ctx, span := tracing.Start(ctx, "TranslationService.ListGenreTranslations", attribute.String("genre", genre))
+	defer func() { tracing.End(span, err) }()
+
+	return s.repo.ListGenreTranslations(ctx, genre)
+}
+
+func (s *TranslationService) DeleteGenreTranslation(ctx context.Context, genre, lang string) (err error) {
+	ctx, span := tracing.Start(ctx, "TranslationService.DeleteGenreTranslation", attribute.String("genre", genre), attribute.String("language", lang))
+	defer func() { tracing.End(span, err) }()
+
+	lang = model.CanonicalLanguage(lang)
+	if err := s.repo.DeleteGenreTranslation(ctx, genre, lang); err != nil {
+		return translationNotFound(err)
+	}
+	s.logger.InfoContext(ctx, "Deleted genre translation", "genre", genre, "language", lang)
+	return nil
+}
+
+// Localize returns the text of each movie in the languages that best match prefs, most
+// preferred first. The title and synopsis come from the same translation; the genre is
+// matched on its own. Text without a matching translation is the original one.
+func (s *TranslationService) Localize(ctx context.Context, prefs []language.Tag, movies ...*model.Movie) (localized []Localization, err error) {
+	ctx, span := tracing.Start(ctx, "TranslationService.Localize", attribute.Int("movies", len(movies)))
+	defer func() { tracing.End(span, err) }()
+
+	localized = make([]Localization, len(movies))
+	for i, movie := range movies {
+		localized[i] = Localization{Title: movie.Title, Synopsis: movie.Synopsis, Genre: movie.Genre}
+	}
+	if len(prefs) == 0 || len(movies) == 0 {
+		return localized, nil
+	}
+
+	ids := make([]uint, len(movies))
+	var genres []string
+	seen := make(map[string]bool)
+	for i, movie := range movies {
+		ids[i] = movie.ID
+		if !seen[movie.Genre] {
+			seen[movie.Genre] = true
+			genres = append(genres, movie.Genre)
+		}
+	}
+	movieTranslations, err := s.repo.ListMovieTranslations(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+	genreTranslations, err := s.repo.ListGenreTranslations(ctx, genres...)
+	if err != nil {
+		return nil, err
+	}
+	byMovie := make(map[uint][]*model.MovieTranslation)
+	for _, t := range movieTranslations {
+		byMovie[t.MovieID] = append(byMovie[t.MovieID], t)
+	}
+	byGenre := make(map[string][]*model.GenreTranslation)
+	for _, t := range genreTranslations {
+		byGenre[t.Genre] = append(byGenre[t.Genre], t)
+	}
+
+	for i, movie := range movies {
+		if t, ok := bestMatch(prefs, byMovie[movie.ID], func(t *model.MovieTranslation) string { return t.Language }); ok {
+			localized[i].Language = t.Language
+			if t.Title != "" {
+				localized[i].Title = t.Title
+			}
+			if t.Synopsis != "" {
+				localized[i].Synopsis = t.Synopsis
+			}
+		}
+		if t, ok := bestMatch(prefs, byGenre[movie.Genre], func(t *model.GenreTranslation) string { return t.Language }); ok {
+			localized[i].Genre = t.Name
+		}
+	}
+	return localized, nil
+}
+
+// bestMatch returns the candidate whose language best matches prefs, if any matches well
+func bestMatch[T any](prefs []language.Tag, candidates []T, lang func(T) string) (T, bool) {
+	var zero T
+	if len(candidates) == 0 {
+		return zero, false
+	}
+	// The matcher falls back to its first tag, which stands for the original text. Low
+	// confidence matches, such as another script of the language, are not readable enough.
+	tags := make([]language.Tag, 1, len(candidates)+1)
+	tags[0] = language.Und
+	for _, candidate := range candidates {
+		tags = append(tags, language.Make(lang(candidate)))
+	}
+	_, index, confidence := language.NewMatcher(tags).Match(prefs...)
+	if index == 0 || confidence < language.High {
+		return zero, false
+	}
+	return candidates[index-1], true
+}
+
+// translationNotFound replaces the error of a missing translation with ErrTranslationNotFound
+func translationNotFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrTranslationNotFound
+	}
+	return err
+}
@@ -0,0 +1,116 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4"
+
+	"movie-project/config"
+	"movie-project/pkg/logger"
+)
+
+// advisoryLockID identifies the PostgreSQL advisory lock held while applying migrations on startup
+const advisoryLockID = 7_245_104_315
+
+// LatestVersion returns the highest migration version available for cfg.DBDriver
+func LatestVersion(cfg config.Config) (uint, error) {
+	src, err := Source(cfg)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	latest, err := src.First()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		next, err := src.Next(latest)
+		if errors.Is(err, os.ErrNotExist) {
+			return latest, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		latest = next
+	}
+}
+
+// ApplyOnStart brings the schema up to date before the server starts serving. On PostgreSQL
+// the whole check-and-migrate sequence runs under an advisory lock, so replicas starting at
+// the same time apply migrations once and the others wait for it. It refuses to continue
+// when the schema is dirty or newer than the migrations embedded into this binary.
+func ApplyOnStart(ctx context.Context, cfg config.Config, db *sql.DB, log *logger.Logger) error {
+	if cfg.DBDriver == config.DriverPostgres {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		log.InfoContext(ctx, "Waiting for migration lock")
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockID); err != nil {
+			return fmt.Errorf("acquire migration lock: %w", err)
+		}
+		defer func() {
+			if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockID); err != nil {
+				log.ErrorContext(ctx, "Failed to release migration lock", "error", err)
+			}
+		}()
+	}
+
+	latest, err := LatestVersion(cfg)
+	if err != nil {
+		return err
+	}
+
+	m, err := New(cfg)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("schema is dirty at version %d, fix it manually and run migrate force", version)
+	}
+	if version > latest {
+		return fmt.Errorf("schema version %d is newer than the latest known migration %d", version, latest)
+	}
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+
+	log.InfoContext(ctx, "Schema is up to date", "from", version, "to", latest)
+	return nil
+}
+
+// CheckVersion returns an error unless the schema in db is clean and at least at version latest.
+// Newer schemas pass, so running replicas stay ready while a rolling deploy migrates ahead of them.
+// It reads the golang-migrate bookkeeping table directly, so it is cheap enough for readiness probes.
+func CheckVersion(ctx context.Context, db *sql.DB, latest uint) error {
+	var version int64
+	var dirty bool
+	err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.New("no migrations applied")
+	}
+	if err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	if dirty {
+		return fmt.Errorf("schema is dirty at version %d", version)
+	}
+	if version < int64(latest) {
+		return fmt.Errorf("schema is at version %d, expected at least %d", version, latest)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package migrations_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"movie-project/config"
+	"movie-project/migrations"
+	"movie-project/pkg/database"
+	"movie-project/pkg/logger"
+)
+
+func TestApplyOnStart(t *testing.T) {
+	cfg := config.Config{DBDriver: config.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "movie.db")}
+	db, err := database.Open(cfg, logger.Discard())
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	latest, err := migrations.LatestVersion(cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, migrations.ApplyOnStart(context.Background(), cfg, sqlDB, logger.Discard()))
+	require.True(t, db.Migrator().HasTable("movies"))
+
+	// Applying again is a no-op
+	require.NoError(t, migrations.ApplyOnStart(context.Background(), cfg, sqlDB, logger.Discard()))
+
+	m, err := migrations.New(cfg)
+	require.NoError(t, err)
+	require.NoError(t, m.Force(int(latest)+1))
+	err = migrations.ApplyOnStart(context.Background(), cfg, sqlDB, logger.Discard())
+	require.ErrorContains(t, err, "newer than the latest known migration")
+
+	require.NoError(t, db.Exec("UPDATE schema_migrations SET version = ?, dirty = ?", latest, true).Error)
+	err = migrations.ApplyOnStart(context.Background(), cfg, sqlDB, logger.Discard())
+	require.ErrorContains(t, err, "schema is dirty")
+
+	m.Close()
+}
+
+func TestCheckVersion(t *testing.T) {
+	cfg := config.Config{DBDriver: config.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "movie.db")}
+	db, err := database.Open(cfg, logger.Discard())
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	ctx := context.Background()
+
+	latest, err := migrations.LatestVersion(cfg)
+	require.NoError(t, err)
+
+	require.Error(t, migrations.CheckVersion(ctx, sqlDB, latest), "no bookkeeping table yet")
+
+	m, err := migrations.New(cfg)
+	require.NoError(t, err)
+	defer m.Close()
+	require.NoError(t, m.Steps(1))
+	require.ErrorContains(t, migrations.CheckVersion(ctx, sqlDB, latest), "expected")
+
+	require.NoError(t, m.Up())
+	require.NoError(t, migrations.CheckVersion(ctx, sqlDB, latest))
+	require.NoError(t, migrations.CheckVersion(ctx, sqlDB, latest-1), "a newer schema, migrated by the next release")
+
+	require.NoError(t, db.Exec("UPDATE schema_migrations SET dirty = ?", true).Error)
+	require.ErrorContains(t, migrations.CheckVersion(ctx, sqlDB, latest), "dirty")
+}
@@ -0,0 +1,178 @@
+package migrations
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"movie-project/internal/model"
+)
+
+// Models lists the GORM models whose tables are created by the migrations
+var Models = []any{&model.Movie{}, &model.MovieTranslation{}, &model.GenreTranslation{}}
+
+// Drift describes one difference between a GORM model and the live database schema
+type Drift struct {
+	Table   string
+	Column  string
+	Problem string
+}
+
+func (d Drift) String() string {
+	if d.Column == "" {
+		return fmt.Sprintf("%s: %s", d.Table, d.Problem)
+	}
+	return fmt.Sprintf("%s.%s: %s", d.Table, d.Column, d.Problem)
+}
+
+var (
+	typeFamilies = map[string]string{
+		"varchar": "string", "character varying": "string", "text": "string",
+		"char": "string", "character": "string", "bpchar": "string",
+		"int": "integer", "integer": "integer", "int2": "integer", "int4": "integer", "int8": "integer",
+		"smallint": "integer", "bigint": "integer", "serial": "integer", "bigserial": "integer",
+		"decimal": "decimal", "numeric": "decimal",
+		"real": "float", "float": "float", "float4": "float", "float8": "float",
+		"double": "float", "double precision": "float",
+		"bool": "boolean", "boolean": "boolean",
+		"date":      "date",
+		"timestamp": "timestamp", "timestamptz": "timestamp", "datetime": "timestamp",
+		"timestamp with time zone": "timestamp", "timestamp without time zone": "timestamp",
+	}
+	dataTypeFamilies = map[schema.DataType]string{
+		schema.String: "string", schema.Int: "integer", schema.Uint: "integer",
+		schema.Float: "float", schema.Bool: "boolean", schema.Time: "timestamp",
+	}
+	decimalSizeRe = regexp.MustCompile(`\(\s*(\d+)\s*,\s*(\d+)\s*\)`)
+)
+
+// typeFamily reduces a database type such as "VARCHAR(255)" or "int4" to a comparable family
+func typeFamily(databaseType string) string {
+	name := strings.ToLower(strings.TrimSpace(databaseType))
+	if i := strings.IndexByte(name, '('); i >= 0 {
+		name = strings.TrimSpace(name[:i])
+	}
+	if family, ok := typeFamilies[name]; ok {
+		return family
+	}
+	return name
+}
+
+// CheckDrift compares the tables of models in the live database with their GORM definitions
+// and reports missing or unexpected columns, type, size and nullability mismatches and missing
+// indexes. A field is expected to be NOT NULL when it is a primary key, is tagged not null or
+// is validated as required.
+func CheckDrift(db *gorm.DB, models ...any) ([]Drift, error) {
+	var drifts []Drift
+	for _, m := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m); err != nil {
+			return nil, err
+		}
+		modelSchema := stmt.Schema
+		table := modelSchema.Table
+
+		if !db.Migrator().HasTable(m) {
+			drifts = append(drifts, Drift{Table: table, Problem: "table is missing"})
+			continue
+		}
+
+		columnTypes, err := db.Migrator().ColumnTypes(m)
+		if err != nil {
+			return nil, err
+		}
+		columns := make(map[string]gorm.ColumnType, len(columnTypes))
+		for _, columnType := range columnTypes {
+			columns[columnType.Name()] = columnType
+		}
+
+		for _, field := range modelSchema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			columnType, ok := columns[field.DBName]
+			if !ok {
+				drifts = append(drifts, Drift{Table: table, Column: field.DBName, Problem: "column is missing"})
+				continue
+			}
+			delete(columns, field.DBName)
+			for _, problem := range compareColumn(field, columnType) {
+				drifts = append(drifts, Drift{Table: table, Column: field.DBName, Problem: problem})
+			}
+		}
+
+		for name := range columns {
+			drifts = append(drifts, Drift{Table: table, Column: name, Problem: "column is not in the model"})
+		}
+
+		indexes := modelSchema.ParseIndexes()
+		names := make([]string, 0, len(indexes))
+		for name := range indexes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !db.Migrator().HasIndex(m, name) {
+				drifts = append(drifts, Drift{Table: table, Problem: fmt.Sprintf("index %s is missing", name)})
+			}
+		}
+	}
+
+	sort.SliceStable(drifts, func(i, j int) bool {
+		return drifts[i].Table < drifts[j].Table ||
+			drifts[i].Table == drifts[j].Table && drifts[i].Column < drifts[j].Column
+	})
+	return drifts, nil
+}
+
+func compareColumn(field *schema.Field, columnType gorm.ColumnType) []string {
+	var problems []string
+
+	expectedType := field.TagSettings["TYPE"]
+	expectedFamily := dataTypeFamilies[field.DataType]
+	if expectedType != "" {
+		expectedFamily = typeFamily(expectedType)
+	}
+	actualFamily := typeFamily(columnType.DatabaseTypeName())
+	if expectedFamily != "" && expectedFamily != actualFamily {
+		problems = append(problems, fmt.Sprintf("type is %s, model expects %s", columnType.DatabaseTypeName(), expectedFamily))
+	}
+
+	if field.Size > 0 && expectedFamily == "string" {
+		if length, ok := columnType.Length(); ok && length != int64(field.Size) {
+			problems = append(problems, fmt.Sprintf("length is %d, model expects %d", length, field.Size))
+		}
+	}
+
+	if match := decimalSizeRe.FindStringSubmatch(expectedType); match != nil {
+		precision, _ := strconv.ParseInt(match[1], 10, 64)
+		scale, _ := strconv.ParseInt(match[2], 10, 64)
+		if actualPrecision, actualScale, ok := columnType.DecimalSize(); ok && (actualPrecision != precision || actualScale != scale) {
+			problems = append(problems, fmt.Sprintf("decimal size is (%d,%d), model expects (%d,%d)", actualPrecision, actualScale, precision, scale))
+		}
+	}
+
+	// Primary keys are never NULL, but not every driver reports them as such
+	if !field.PrimaryKey {
+		expectNotNull := field.NotNull || isRequired(field)
+		if nullable, ok := columnType.Nullable(); ok && nullable && expectNotNull {
+			problems = append(problems, "column is nullable, model requires a value")
+		}
+	}
+
+	return problems
+}
+
+func isRequired(field *schema.Field) bool {
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
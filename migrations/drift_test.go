@@ -0,0 +1,125 @@
+package migrations_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"movie-project/config"
+	"movie-project/migrations"
+	"movie-project/pkg/database"
+	"movie-project/pkg/logger"
+)
+
+// migratedDB applies all migrations for cfg to a clean database
+func migratedDB(t *testing.T, cfg config.Config) *gorm.DB {
+	m, err := migrations.New(cfg)
+	require.NoError(t, err)
+	if err := m.Drop(); err != nil {
+		require.NoError(t, err)
+	}
+	m.Close()
+
+	m, err = migrations.New(cfg)
+	require.NoError(t, err)
+	require.NoError(t, m.Up())
+	m.Close()
+
+	db, err := database.Open(cfg, logger.Discard())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestMigrationsMatchModels(t *testing.T) {
+	backends := []config.Config{{DBDriver: config.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "movie.db")}}
+	if os.Getenv("TEST_POSTGRES") != "" {
+		cfg, err := config.LoadConfig()
+		require.NoError(t, err)
+		cfg.DBDriver = config.DriverPostgres
+		backends = append(backends, cfg)
+	}
+
+	for _, cfg := range backends {
+		t.Run(cfg.DBDriver, func(t *testing.T) {
+			drifts, err := migrations.CheckDrift(migratedDB(t, cfg), migrations.Models...)
+			require.NoError(t, err)
+			assert.Empty(t, drifts)
+		})
+	}
+}
+
+type driftedMovie struct {
+	gorm.Model
+	Title       string    `gorm:"size:100;not null"`
+	ReleaseDate time.Time `gorm:"type:date"`
+	Genre       string    `validate:"required"`
+	Rating      float32   `gorm:"type:decimal(3,1)"`
+	Poster      string
+}
+
+func (driftedMovie) TableName() string { return "movies" }
+
+func TestCheckDriftReportsMismatches(t *testing.T) {
+	cfg := config.Config{DBDriver: config.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "movie.db")}
+	db, err := database.Open(cfg, logger.Discard())
+	require.NoError(t, err)
+	require.NoError(t, db.Exec(`CREATE TABLE movies (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title VARCHAR(255),
+		release_date DATETIME,
+		genre VARCHAR(100),
+		rating DECIMAL(3,1),
+		director VARCHAR(255),
+		created_at DATETIME,
+		updated_at DATETIME,
+		deleted_at DATETIME
+	)`).Error)
+
+	drifts, err := migrations.CheckDrift(db, &driftedMovie{})
+	require.NoError(t, err)
+
+	var problems []string
+	for _, drift := range drifts {
+		problems = append(problems, drift.String())
+	}
+	assert.ElementsMatch(t, []string{
+		"movies: index idx_movies_deleted_at is missing",
+		"movies.director: column is not in the model",
+		"movies.genre: column is nullable, model requires a value",
+		"movies.poster: column is missing",
+		"movies.release_date: type is DATETIME, model expects date",
+		"movies.title: column is nullable, model requires a value",
+		"movies.title: length is 255, model expects 100",
+	}, problems)
+}
+
+func TestSQLiteRebuildKeepsRows(t *testing.T) {
+	cfg := config.Config{DBDriver: config.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "movie.db")}
+	m, err := migrations.New(cfg)
+	require.NoError(t, err)
+	defer m.Close()
+	require.NoError(t, m.Migrate(1))
+
+	db, err := database.Open(cfg, logger.Discard())
+	require.NoError(t, err)
+	require.NoError(t, db.Exec("INSERT INTO movies (title, director) VALUES ('Untitled', 'Unknown')").Error)
+
+	require.NoError(t, m.Up())
+	var count int64
+	require.NoError(t, db.Table("movies").Where("genre = '' AND rating = 0").Count(&count).Error)
+	assert.EqualValues(t, 1, count)
+
+	require.NoError(t, m.Migrate(1))
+	require.NoError(t, db.Table("movies").Count(&count).Error)
+	assert.EqualValues(t, 1, count)
+}
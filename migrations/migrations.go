@@ -0,0 +1,67 @@
+// Package migrations embeds the SQL migrations of every supported database driver.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"path/filepath"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+
+	"movie-project/config"
+)
+
+// FS holds one directory of migrations per driver, e.g. postgres/001_create_movies_table.up.sql
+//
+//go:embed postgres/*.sql sqlite/*.sql
+var FS embed.FS
+
+// Drivers lists the database drivers that have migrations
+var Drivers = []string{config.DriverPostgres, config.DriverSQLite}
+
+// Source opens the migrations for cfg.DBDriver. They are read from cfg.MigrationsDir
+// when it is set and from the copy embedded into the binary otherwise.
+func Source(cfg config.Config) (source.Driver, error) {
+	if !hasDriver(cfg.DBDriver) {
+		return nil, fmt.Errorf("no migrations for database driver: %q", cfg.DBDriver)
+	}
+
+	if cfg.MigrationsDir != "" {
+		dir, err := filepath.Abs(filepath.Join(cfg.MigrationsDir, cfg.DBDriver))
+		if err != nil {
+			return nil, err
+		}
+		return (&file.File{}).Open(fmt.Sprintf("file://%s", filepath.ToSlash(dir)))
+	}
+
+	return iofs.New(FS, cfg.DBDriver)
+}
+
+// New returns a migrate instance for the database configured in cfg
+func New(cfg config.Config) (*migrate.Migrate, error) {
+	src, err := Source(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := migrate.NewWithSourceInstance("migrations", src, cfg.GetMigrateURL())
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+func hasDriver(driver string) bool {
+	for _, d := range Drivers {
+		if d == driver {
+			return true
+		}
+	}
+	return false
+}
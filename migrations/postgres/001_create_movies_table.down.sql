@@ -0,0 +1,2 @@
+-- migrations/postgres/001_create_movies_table.down.sql
+DROP TABLE IF EXISTS movies;
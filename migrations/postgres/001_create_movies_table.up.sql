@@ -1,4 +1,4 @@
--- migrations/001_create_movies_table.sql
+-- migrations/postgres/001_create_movies_table.up.sql
 CREATE TABLE IF NOT EXISTS movies (
                                       id SERIAL PRIMARY KEY,
                                       title VARCHAR(255) NOT NULL,
@@ -0,0 +1,7 @@
+-- migrations/postgres/002_align_movies_with_model.down.sql
+DROP INDEX IF EXISTS idx_movies_deleted_at;
+
+ALTER TABLE movies
+    ALTER COLUMN release_date DROP NOT NULL,
+    ALTER COLUMN genre DROP NOT NULL,
+    ALTER COLUMN rating DROP NOT NULL;
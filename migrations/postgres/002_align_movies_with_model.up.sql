@@ -0,0 +1,12 @@
+-- migrations/postgres/002_align_movies_with_model.up.sql
+-- NULLs are read by the application as zero values, backfill them with exactly those
+UPDATE movies SET release_date = '0001-01-01' WHERE release_date IS NULL;
+UPDATE movies SET genre = '' WHERE genre IS NULL;
+UPDATE movies SET rating = 0 WHERE rating IS NULL;
+
+ALTER TABLE movies
+    ALTER COLUMN release_date SET NOT NULL,
+    ALTER COLUMN genre SET NOT NULL,
+    ALTER COLUMN rating SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_movies_deleted_at ON movies(deleted_at);
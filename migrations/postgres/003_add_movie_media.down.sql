@@ -0,0 +1,3 @@
+-- migrations/postgres/003_add_movie_media.down.sql
+ALTER TABLE movies DROP COLUMN backdrop_key;
+ALTER TABLE movies DROP COLUMN poster_key;
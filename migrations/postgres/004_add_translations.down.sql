@@ -0,0 +1,3 @@
+-- migrations/postgres/004_add_translations.down.sql
+DROP TABLE genre_translations;
+DROP TABLE movie_translations;
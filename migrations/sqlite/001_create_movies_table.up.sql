@@ -0,0 +1,16 @@
+-- migrations/sqlite/001_create_movies_table.up.sql
+CREATE TABLE IF NOT EXISTS movies (
+                                      id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                      title VARCHAR(255) NOT NULL,
+                                      director VARCHAR(255) NOT NULL,
+                                      release_date DATE,
+                                      genre VARCHAR(100),
+                                      rating DECIMAL(3,1),
+                                      created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                      updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                      deleted_at DATETIME
+);
+
+CREATE INDEX idx_movies_title ON movies(title);
+CREATE INDEX idx_movies_director ON movies(director);
+CREATE INDEX idx_movies_release_date ON movies(release_date);
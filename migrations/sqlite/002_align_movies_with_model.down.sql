@@ -0,0 +1,21 @@
+-- migrations/sqlite/002_align_movies_with_model.down.sql
+CREATE TABLE movies_old (
+                            id INTEGER PRIMARY KEY AUTOINCREMENT,
+                            title VARCHAR(255) NOT NULL,
+                            director VARCHAR(255) NOT NULL,
+                            release_date DATE,
+                            genre VARCHAR(100),
+                            rating DECIMAL(3,1),
+                            created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                            updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                            deleted_at DATETIME
+);
+
+INSERT INTO movies_old SELECT id, title, director, release_date, genre, rating, created_at, updated_at, deleted_at FROM movies;
+
+DROP TABLE movies;
+ALTER TABLE movies_old RENAME TO movies;
+
+CREATE INDEX idx_movies_title ON movies(title);
+CREATE INDEX idx_movies_director ON movies(director);
+CREATE INDEX idx_movies_release_date ON movies(release_date);
@@ -0,0 +1,27 @@
+-- migrations/sqlite/002_align_movies_with_model.up.sql
+-- SQLite cannot alter column constraints, so the table is rebuilt.
+-- NULLs are read by the application as zero values, backfill them with exactly those
+CREATE TABLE movies_new (
+                            id INTEGER PRIMARY KEY AUTOINCREMENT,
+                            title VARCHAR(255) NOT NULL,
+                            director VARCHAR(255) NOT NULL,
+                            release_date DATE NOT NULL,
+                            genre VARCHAR(100) NOT NULL,
+                            rating DECIMAL(3,1) NOT NULL,
+                            created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                            updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                            deleted_at DATETIME
+);
+
+INSERT INTO movies_new (id, title, director, release_date, genre, rating, created_at, updated_at, deleted_at)
+SELECT id, title, director, COALESCE(release_date, '0001-01-01 00:00:00+00:00'), COALESCE(genre, ''), COALESCE(rating, 0),
+       created_at, updated_at, deleted_at
+FROM movies;
+
+DROP TABLE movies;
+ALTER TABLE movies_new RENAME TO movies;
+
+CREATE INDEX idx_movies_title ON movies(title);
+CREATE INDEX idx_movies_director ON movies(director);
+CREATE INDEX idx_movies_release_date ON movies(release_date);
+CREATE INDEX idx_movies_deleted_at ON movies(deleted_at);
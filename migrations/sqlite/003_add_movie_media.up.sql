@@ -0,0 +1,3 @@
+-- migrations/sqlite/003_add_movie_media.up.sql
+ALTER TABLE movies ADD COLUMN poster_key VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE movies ADD COLUMN backdrop_key VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,19 @@
+-- migrations/sqlite/004_add_translations.up.sql
+CREATE TABLE movie_translations (
+                                    movie_id INTEGER NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+                                    language VARCHAR(35) NOT NULL,
+                                    title VARCHAR(255) NOT NULL DEFAULT '',
+                                    synopsis TEXT NOT NULL DEFAULT '',
+                                    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                    PRIMARY KEY (movie_id, language)
+);
+
+CREATE TABLE genre_translations (
+                                    genre VARCHAR(100) NOT NULL,
+                                    language VARCHAR(35) NOT NULL,
+                                    name VARCHAR(100) NOT NULL,
+                                    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                                    PRIMARY KEY (genre, language)
+);
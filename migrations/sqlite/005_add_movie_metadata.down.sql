@@ -0,0 +1,13 @@
+-- migrations/sqlite/005_add_movie_metadata.down.sql
+DROP INDEX idx_movies_tmdb_id;
+DROP INDEX idx_movies_imdb_id;
+ALTER TABLE movies DROP COLUMN tmdb_id;
+ALTER TABLE movies DROP COLUMN imdb_id;
+ALTER TABLE movies DROP COLUMN box_office;
+ALTER TABLE movies DROP COLUMN budget;
+ALTER TABLE movies DROP COLUMN certifications;
+ALTER TABLE movies DROP COLUMN original_language;
+ALTER TABLE movies DROP COLUMN production_countries;
+ALTER TABLE movies DROP COLUMN runtime_minutes;
+ALTER TABLE movies DROP COLUMN tagline;
+ALTER TABLE movies DROP COLUMN synopsis;
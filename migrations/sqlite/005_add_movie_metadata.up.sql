@@ -0,0 +1,15 @@
+-- migrations/sqlite/005_add_movie_metadata.up.sql
+ALTER TABLE movies ADD COLUMN synopsis TEXT NOT NULL DEFAULT '';
+ALTER TABLE movies ADD COLUMN tagline VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE movies ADD COLUMN runtime_minutes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE movies ADD COLUMN production_countries VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE movies ADD COLUMN original_language VARCHAR(3) NOT NULL DEFAULT '';
+ALTER TABLE movies ADD COLUMN certifications TEXT NOT NULL DEFAULT '';
+ALTER TABLE movies ADD COLUMN budget BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE movies ADD COLUMN box_office BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE movies ADD COLUMN imdb_id VARCHAR(12) NOT NULL DEFAULT '';
+ALTER TABLE movies ADD COLUMN tmdb_id BIGINT NOT NULL DEFAULT 0;
+
+-- Movies without an external id, and deleted ones, don't take part in the uniqueness
+CREATE UNIQUE INDEX idx_movies_imdb_id ON movies(imdb_id) WHERE imdb_id <> '' AND deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_movies_tmdb_id ON movies(tmdb_id) WHERE tmdb_id <> 0 AND deleted_at IS NULL;
@@ -0,0 +1,141 @@
+// Package accesslog writes one structured log line per gRPC call and HTTP request.
+// The request id and method are added by the logger from the request context, and every line
+// names its user ("anonymous" without a valid bearer token), so the interceptors belong after
+// requestid and auth in the chain.
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Redacted replaces the values of redacted headers, metadata, query parameters and payload fields
+const Redacted = "[REDACTED]"
+
+// Options configures the access log
+type Options struct {
+	// Redact lists header, metadata, query parameter and payload field names whose values are
+	// never logged. Names are matched case-insensitively.
+	Redact []string
+	// Payloads adds the gRPC request message to the log line, with redacted fields masked
+	Payloads bool
+	// Calls taking longer than SlowThreshold are logged at warn level; 0 disables the check.
+	// SlowMethods overrides the threshold per gRPC method.
+	SlowThreshold time.Duration
+	SlowMethods   map[string]time.Duration
+}
+
+// ParseSlowMethods parses "Method=duration" entries, e.g. "ListMovies=2s". Method is either a
+// full gRPC method name ("/movie.MovieService/ListMovies") or just the method ("ListMovies").
+func ParseSlowMethods(entries []string) (map[string]time.Duration, error) {
+	thresholds := make(map[string]time.Duration, len(entries))
+	for _, entry := range entries {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		method, value, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(method) == "" {
+			return nil, fmt.Errorf("invalid slow method threshold %q: expected Method=duration", entry)
+		}
+		threshold, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || threshold < 0 {
+			return nil, fmt.Errorf("invalid slow method threshold %q: %q is not a duration", entry, value)
+		}
+		thresholds[strings.TrimSpace(method)] = threshold
+	}
+	return thresholds, nil
+}
+
+// config is Options prepared for lookups
+type config struct {
+	redact        map[string]bool
+	payloads      bool
+	slowThreshold time.Duration
+	slowMethods   map[string]time.Duration
+}
+
+func newConfig(opts Options) config {
+	redact := make(map[string]bool, len(opts.Redact))
+	for _, name := range opts.Redact {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			redact[name] = true
+		}
+	}
+	return config{
+		redact:        redact,
+		payloads:      opts.Payloads,
+		slowThreshold: opts.SlowThreshold,
+		slowMethods:   opts.SlowMethods,
+	}
+}
+
+// gatewayPrefix is added by grpc-gateway to HTTP headers it forwards as gRPC metadata
+const gatewayPrefix = "grpcgateway-"
+
+func (c config) redacted(name string) bool {
+	name = strings.ToLower(name)
+	return c.redact[name] || c.redact[strings.TrimPrefix(name, gatewayPrefix)]
+}
+
+// slow reports whether a call to method taking duration exceeds its threshold
+func (c config) slow(method string, duration time.Duration) bool {
+	threshold, ok := c.slowMethods[method]
+	if !ok {
+		if i := strings.LastIndexByte(method, '/'); i >= 0 {
+			threshold, ok = c.slowMethods[method[i+1:]]
+		}
+	}
+	if !ok {
+		threshold = c.slowThreshold
+	}
+	return threshold > 0 && duration > threshold
+}
+
+// values returns headers or metadata with redacted values masked. Multiple values are joined with ", ".
+func (c config) values(fields map[string][]string) map[string]string {
+	out := make(map[string]string, len(fields))
+	for name, values := range fields {
+		if c.redacted(name) {
+			out[name] = Redacted
+			continue
+		}
+		out[name] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+// payload returns msg as JSON-like values with redacted fields masked at any depth
+func (c config) payload(msg proto.Message) any {
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil
+	}
+	return c.redactValue(value)
+}
+
+func (c config) redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for name, field := range v {
+			if c.redacted(name) {
+				v[name] = Redacted
+			} else {
+				v[name] = c.redactValue(field)
+			}
+		}
+	case []any:
+		for i := range v {
+			v[i] = c.redactValue(v[i])
+		}
+	}
+	return value
+}
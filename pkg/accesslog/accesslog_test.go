@@ -0,0 +1,205 @@
+package accesslog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"movie-project/pkg/auth"
+	"movie-project/pkg/logger"
+)
+
+const jwtSecret = "0123456789abcdef0123456789abcdef"
+
+// newToken issues a bearer token the auth interceptors accept
+func newToken(t *testing.T, subject string) string {
+	token, err := auth.NewToken(jwtSecret, subject, time.Hour)
+	require.NoError(t, err)
+	return token
+}
+
+// newTestLogger logs JSON to a file and returns a function reading the records written so far
+func newTestLogger(t *testing.T) (*logger.Logger, func() []map[string]any) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	log, err := logger.NewLogger(logger.Options{Output: path})
+	require.NoError(t, err)
+	t.Cleanup(func() { log.Close() })
+
+	read := func() []map[string]any {
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var records []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+			if line == "" {
+				continue
+			}
+			var record map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &record))
+			records = append(records, record)
+		}
+		return records
+	}
+	return log, read
+}
+
+func TestParseSlowMethods(t *testing.T) {
+	thresholds, err := ParseSlowMethods([]string{"ListMovies=2s", " /movie.MovieService/GetMovie = 100ms ", ""})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{"ListMovies": 2 * time.Second, "/movie.MovieService/GetMovie": 100 * time.Millisecond}, thresholds)
+
+	for _, invalid := range []string{"ListMovies", "=1s", "ListMovies=fast", "ListMovies=-1s"} {
+		_, err := ParseSlowMethods([]string{invalid})
+		assert.Error(t, err, invalid)
+	}
+
+	cfg := newConfig(Options{SlowThreshold: time.Second, SlowMethods: thresholds})
+	assert.True(t, cfg.slow("/movie.MovieService/DeleteMovie", 2*time.Second))
+	assert.False(t, cfg.slow("/movie.MovieService/ListMovies", 1500*time.Millisecond), "matched by short name")
+	assert.True(t, cfg.slow("/movie.MovieService/GetMovie", 200*time.Millisecond), "matched by full name")
+	assert.False(t, newConfig(Options{}).slow("/movie.MovieService/GetMovie", time.Hour), "disabled by default")
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	log, read := newTestLogger(t)
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		auth.UnaryServerInterceptor(auth.NewVerifier(jwtSecret)),
+		UnaryServerInterceptor(log, Options{
+			Redact:      []string{"Authorization", "service"},
+			Payloads:    true,
+			SlowMethods: map[string]time.Duration{"Check": time.Hour},
+		}),
+	))
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("movie", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(s, healthServer)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	client := healthpb.NewHealthClient(conn)
+
+	token := newToken(t, "alice")
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token,
+		"grpcgateway-authorization", "Bearer "+token, "x-client", "cli")
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: "movie"})
+	require.NoError(t, err)
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	records := read()
+	require.Len(t, records, 2)
+
+	ok := records[0]
+	assert.Equal(t, "gRPC call", ok["msg"])
+	assert.Equal(t, "INFO", ok["level"])
+	assert.Equal(t, "OK", ok["code"])
+	assert.Equal(t, "alice", ok["user"])
+	assert.Equal(t, "bufconn", ok["peer"])
+	assert.EqualValues(t, 7, ok["request_size"])
+	assert.EqualValues(t, 2, ok["response_size"])
+	assert.Contains(t, ok, "duration_ms")
+	md := ok["metadata"].(map[string]any)
+	assert.Equal(t, Redacted, md["authorization"])
+	assert.Equal(t, Redacted, md["grpcgateway-authorization"], "headers forwarded by the gateway")
+	assert.Equal(t, "cli", md["x-client"])
+	assert.Equal(t, map[string]any{"service": Redacted}, ok["request"], "payload fields are redacted too")
+	assert.NotContains(t, ok, "error")
+
+	notFound := records[1]
+	assert.Equal(t, "NotFound", notFound["code"])
+	assert.Equal(t, "anonymous", notFound["user"], "calls without a token are logged as anonymous")
+	assert.Equal(t, "unknown service", notFound["error"])
+}
+
+func TestUnaryServerInterceptor_Levels(t *testing.T) {
+	log, read := newTestLogger(t)
+	info := &grpc.UnaryServerInfo{FullMethod: "/movie.MovieService/ListMovies"}
+	slowHandler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		time.Sleep(2 * time.Millisecond)
+		return nil, nil
+	}
+	failingHandler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Internal, "boom")
+	}
+
+	interceptor := UnaryServerInterceptor(log, Options{SlowThreshold: time.Millisecond})
+	interceptor(context.Background(), nil, info, slowHandler)
+	interceptor(context.Background(), nil, info, failingHandler)
+	UnaryServerInterceptor(log, Options{SlowThreshold: time.Millisecond, SlowMethods: map[string]time.Duration{"ListMovies": time.Minute}})(
+		context.Background(), nil, info, slowHandler)
+
+	records := read()
+	require.Len(t, records, 3)
+	assert.Equal(t, "WARN", records[0]["level"], "slow calls")
+	assert.Equal(t, "ERROR", records[1]["level"], "server failures")
+	assert.Equal(t, "INFO", records[2]["level"], "per-method threshold overrides the default")
+}
+
+func TestMiddleware(t *testing.T) {
+	log, read := newTestLogger(t)
+	handler := auth.Middleware(auth.NewVerifier(jwtSecret))(Middleware(log, Options{Redact: []string{"authorization", "password"}})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(io.Discard, r.Body)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":1}`))
+		})))
+
+	token := newToken(t, "alice")
+	req := httptest.NewRequest(http.MethodPost, "/v1/movies?password=hunter2&lang=en", strings.NewReader(`{"title":"Heat"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("User-Agent", "test")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	req = httptest.NewRequest(http.MethodGet, "/v1/movies", nil)
+	req.Header.Set("Authorization", "Bearer forged-token")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	records := read()
+	require.Len(t, records, 2)
+	assert.Equal(t, "anonymous", records[1]["user"], "invalid tokens are logged as anonymous")
+	record := records[0]
+	assert.Equal(t, "alice", record["user"])
+	assert.Equal(t, "HTTP request", record["msg"])
+	assert.Equal(t, "INFO", record["level"])
+	assert.EqualValues(t, http.StatusCreated, record["status"])
+	assert.Equal(t, "/v1/movies", record["path"])
+	assert.Equal(t, "lang=en&password=%5BREDACTED%5D", record["query"])
+	assert.EqualValues(t, 16, record["request_size"])
+	assert.EqualValues(t, 8, record["response_size"])
+	headers := record["headers"].(map[string]any)
+	assert.Equal(t, Redacted, headers["Authorization"])
+	assert.Equal(t, "test", headers["User-Agent"])
+
+	for _, record := range records {
+		content, _ := json.Marshal(record)
+		assert.NotContains(t, string(content), token)
+		assert.NotContains(t, string(content), "forged-token")
+		assert.NotContains(t, string(content), "hunter2")
+	}
+}
@@ -0,0 +1,233 @@
+package accesslog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"movie-project/pkg/auth"
+	"movie-project/pkg/logger"
+)
+
+// anonymous is the user of calls without an authenticated subject
+const anonymous = "anonymous"
+
+// UnaryServerInterceptor logs every unary call with its user, peer, status code, duration and message sizes
+func UnaryServerInterceptor(log *logger.Logger, opts Options) grpc.UnaryServerInterceptor {
+	cfg := newConfig(opts)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		duration := time.Since(start)
+
+		attrs := grpcAttrs(ctx, cfg, err, duration)
+		attrs = append(attrs, slog.Int("request_size", messageSize(req)), slog.Int("response_size", messageSize(resp)))
+		if msg, ok := req.(proto.Message); ok && cfg.payloads {
+			attrs = append(attrs, slog.Any("request", cfg.payload(msg)))
+		}
+		log.LogAttrs(ctx, grpcLevel(cfg, info.FullMethod, err, duration), "gRPC call", attrs...)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor logs every stream when it ends, with the number and total size of messages
+// received and sent
+func StreamServerInterceptor(log *logger.Logger, opts Options) grpc.StreamServerInterceptor {
+	cfg := newConfig(opts)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		stream := &countingStream{ServerStream: ss}
+		err := handler(srv, stream)
+		duration := time.Since(start)
+
+		ctx := ss.Context()
+		attrs := grpcAttrs(ctx, cfg, err, duration)
+		attrs = append(attrs,
+			slog.Int("messages_received", stream.received),
+			slog.Int("messages_sent", stream.sent),
+			slog.Int("request_size", stream.receivedBytes),
+			slog.Int("response_size", stream.sentBytes),
+		)
+		log.LogAttrs(ctx, grpcLevel(cfg, info.FullMethod, err, duration), "gRPC stream", attrs...)
+		return err
+	}
+}
+
+func grpcAttrs(ctx context.Context, cfg config, err error, duration time.Duration) []slog.Attr {
+	st := status.Convert(err)
+	attrs := []slog.Attr{
+		slog.String("code", st.Code().String()),
+		durationAttr(duration),
+		userAttr(ctx),
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		attrs = append(attrs, slog.String("peer", p.Addr.String()))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", st.Message()))
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		attrs = append(attrs, slog.Any("metadata", cfg.values(md)))
+	}
+	return attrs
+}
+
+// grpcLevel logs server failures at error level and slow calls at warn level
+func grpcLevel(cfg config, method string, err error, duration time.Duration) slog.Level {
+	switch status.Code(err) {
+	case codes.Unknown, codes.Internal, codes.DataLoss:
+		return slog.LevelError
+	}
+	if cfg.slow(method, duration) {
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
+func messageSize(msg interface{}) int {
+	if m, ok := msg.(proto.Message); ok {
+		return proto.Size(m)
+	}
+	return 0
+}
+
+type countingStream struct {
+	grpc.ServerStream
+	received, sent           int
+	receivedBytes, sentBytes int
+}
+
+func (s *countingStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received++
+		s.receivedBytes += messageSize(m)
+	}
+	return err
+}
+
+func (s *countingStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent++
+		s.sentBytes += messageSize(m)
+	}
+	return err
+}
+
+// Middleware logs every HTTP request with its user, peer, status code, duration and body sizes.
+// Responses with a 5xx status are logged at error level and slow requests at warn level.
+func Middleware(log *logger.Logger, opts Options) func(http.Handler) http.Handler {
+	cfg := newConfig(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			body := &countingBody{ReadCloser: r.Body}
+			if r.Body != nil {
+				r.Body = body
+			}
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r)
+			duration := time.Since(start)
+
+			attrs := []slog.Attr{
+				slog.Int("status", rw.status),
+				durationAttr(duration),
+				userAttr(r.Context()),
+				slog.String("peer", r.RemoteAddr),
+				slog.String("path", r.URL.Path),
+				slog.Int64("request_size", body.n),
+				slog.Int64("response_size", rw.n),
+				slog.Any("headers", cfg.values(r.Header)),
+			}
+			if r.URL.RawQuery != "" {
+				attrs = append(attrs, slog.String("query", cfg.query(r.URL.Query())))
+			}
+
+			level := slog.LevelInfo
+			switch {
+			case rw.status >= http.StatusInternalServerError:
+				level = slog.LevelError
+			case cfg.slowThreshold > 0 && duration > cfg.slowThreshold:
+				level = slog.LevelWarn
+			}
+			log.LogAttrs(r.Context(), level, "HTTP request", attrs...)
+		})
+	}
+}
+
+// query encodes the query parameters with redacted values masked
+func (c config) query(values url.Values) string {
+	for name := range values {
+		if c.redacted(name) {
+			values[name] = []string{Redacted}
+		}
+	}
+	return values.Encode()
+}
+
+// userAttr names the caller on every line, so anonymous calls can be told from lines missing the field
+func userAttr(ctx context.Context) slog.Attr {
+	if subject := auth.SubjectFromContext(ctx); subject != "" {
+		return slog.String("user", subject)
+	}
+	return slog.String("user", anonymous)
+}
+
+func durationAttr(d time.Duration) slog.Attr {
+	return slog.Float64("duration_ms", float64(d)/float64(time.Millisecond))
+}
+
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	n           int64
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.status, rw.wroteHeader = code, true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	rw.wroteHeader = true
+	n, err := rw.ResponseWriter.Write(p)
+	rw.n += int64(n)
+	return n, err
+}
+
+// Flush keeps streaming responses of the gateway working through the wrapper
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
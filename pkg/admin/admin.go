@@ -0,0 +1,74 @@
+// Package admin serves operational endpoints (metrics, profiling, runtime controls) that must not be
+// reachable by API clients. Serve it on a separate, private listener.
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"movie-project/pkg/logger"
+)
+
+// Options configures the admin handler
+type Options struct {
+	// Token, when set, must be sent by callers as "Authorization: Bearer <token>"
+	Token  string
+	Logger *logger.Logger
+	// Config returns the configuration to show on /config, with secrets already masked
+	Config func() any
+}
+
+// NewHandler returns the admin endpoints:
+//
+//	/metrics        Prometheus metrics
+//	/debug/pprof/   runtime profiles
+//	/debug/vars     expvar variables
+//	/log/level      current log level, PUT {"level":"debug"} to change it
+//	/config         effective configuration
+func NewHandler(opts Options) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	if opts.Logger != nil {
+		mux.Handle("/log/level", opts.Logger.LevelHandler())
+	}
+	if opts.Config != nil {
+		mux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(opts.Config())
+		})
+	}
+
+	if opts.Token == "" {
+		return mux
+	}
+	return requireToken(opts.Token, mux)
+}
+
+// requireToken rejects requests without the bearer token with 401 Unauthorized
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,65 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"movie-project/config"
+	"movie-project/pkg/logger"
+)
+
+func get(handler http.Handler, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestNewHandler(t *testing.T) {
+	cfg := config.Config{DBHost: "db.internal", DBPassword: "hunter2", AdminToken: "s3cret"}
+	handler := NewHandler(Options{
+		Logger: logger.Discard(),
+		Config: func() any { return cfg.Masked() },
+	})
+
+	for _, path := range []string{"/metrics", "/debug/pprof/", "/debug/pprof/cmdline", "/debug/vars", "/log/level", "/config"} {
+		rec := get(handler, path, "")
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+	}
+	assert.Contains(t, get(handler, "/metrics", "").Body.String(), "go_goroutines")
+	assert.Contains(t, get(handler, "/debug/vars", "").Body.String(), "memstats")
+
+	var settings map[string]any
+	require.NoError(t, json.Unmarshal(get(handler, "/config", "").Body.Bytes(), &settings))
+	assert.Equal(t, "db.internal", settings["DB_HOST"])
+	assert.Equal(t, "****", settings["DB_PASSWORD"])
+	assert.Equal(t, "****", settings["ADMIN_TOKEN"])
+	assert.Equal(t, "", settings["JWT_SECRET"], "empty secrets are shown as empty")
+	assert.NotContains(t, get(handler, "/config", "").Body.String(), "hunter2")
+}
+
+func TestNewHandler_Token(t *testing.T) {
+	handler := NewHandler(Options{Token: "s3cret", Logger: logger.Discard()})
+
+	rec := get(handler, "/metrics", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `Bearer realm="admin"`, rec.Header().Get("WWW-Authenticate"))
+	assert.Equal(t, http.StatusUnauthorized, get(handler, "/debug/pprof/", "wrong").Code)
+	assert.Equal(t, http.StatusOK, get(handler, "/metrics", "s3cret").Code)
+
+	req := httptest.NewRequest(http.MethodPut, "/log/level", strings.NewReader(`{"level":"debug"}`))
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"level":"debug"}`, rec.Body.String())
+}
@@ -0,0 +1,143 @@
+package auth_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	"movie-project/pkg/auth"
+	"movie-project/pkg/client"
+	"movie-project/pkg/logger"
+	pb "movie-project/proto/movie"
+)
+
+const secret = "0123456789abcdef0123456789abcdef"
+
+func TestVerifier(t *testing.T) {
+	v := auth.NewVerifier(secret)
+
+	token, err := auth.NewToken(secret, "alice", time.Hour)
+	require.NoError(t, err)
+	subject, err := v.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", subject)
+
+	forged, err := auth.NewToken("another-secret", "alice", time.Hour)
+	require.NoError(t, err)
+	expired, err := auth.NewToken(secret, "alice", -time.Hour)
+	require.NoError(t, err)
+	header, claims, _ := strings.Cut(token, ".")
+	unsigned := `eyJhbGciOiJub25lIn0.` + strings.Split(claims, ".")[0] + "."
+
+	for name, token := range map[string]string{
+		"wrong secret":  forged,
+		"expired":       expired,
+		"unsigned":      unsigned,
+		"tampered":      header + ".eyJzdWIiOiJtYWxsb3J5In0." + strings.Split(token, ".")[2],
+		"not a token":   "s3cret",
+		"empty":         "",
+		"extra segment": token + ".x",
+	} {
+		_, err := v.Verify(token)
+		assert.ErrorIs(t, err, auth.ErrInvalidToken, name)
+	}
+}
+
+// loggingServer logs every call, so the test sees the fields the server's logger adds
+type loggingServer struct {
+	pb.UnimplementedMovieServiceServer
+	log *logger.Logger
+}
+
+func (s *loggingServer) GetMovie(ctx context.Context, req *pb.GetMovieRequest) (*pb.Movie, error) {
+	s.log.InfoContext(ctx, "GetMovie")
+	return &pb.Movie{Id: req.Id}, nil
+}
+
+// TestUnaryServerInterceptor sends the token the way moviectl does and checks the server logs its subject
+func TestUnaryServerInterceptor(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "server.log")
+	log, err := logger.NewLogger(logger.Options{Output: logFile})
+	require.NoError(t, err)
+	t.Cleanup(func() { log.Close() })
+
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(auth.UnaryServerInterceptor(auth.NewVerifier(secret))))
+	pb.RegisterMovieServiceServer(s, &loggingServer{log: log})
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	call := func(token string) map[string]any {
+		t.Helper()
+		c, err := client.New("passthrough:///bufnet", client.Options{
+			Token: token,
+			DialOptions: []grpc.DialOption{grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return lis.DialContext(ctx)
+			})},
+		})
+		require.NoError(t, err)
+		defer c.Close()
+		_, err = c.GetMovie(context.Background(), 1)
+		require.NoError(t, err)
+		return lastRecord(t, logFile)
+	}
+
+	token, err := auth.NewToken(secret, "alice", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", call(token)["user_id"])
+
+	forged, err := auth.NewToken("another-secret", "mallory", time.Hour)
+	require.NoError(t, err)
+	assert.NotContains(t, call(forged), "user_id", "invalid tokens are anonymous")
+	assert.NotContains(t, call(""), "user_id")
+}
+
+func TestMiddleware(t *testing.T) {
+	var seen string
+	handler := auth.Middleware(auth.NewVerifier(secret))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = auth.SubjectFromContext(r.Context())
+	}))
+	token, err := auth.NewToken(secret, "alice", time.Hour)
+	require.NoError(t, err)
+
+	for authorization, want := range map[string]string{
+		"Bearer " + token: "alice",
+		token:             "",
+		"Bearer s3cret":   "",
+		"":                "",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/movies", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, want, seen, authorization)
+	}
+}
+
+func lastRecord(t *testing.T, path string) map[string]any {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var line string
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		line = scanner.Text()
+	}
+	var record map[string]any
+	require.NoError(t, json.Unmarshal([]byte(line), &record))
+	return record
+}
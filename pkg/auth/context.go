@@ -0,0 +1,16 @@
+package auth
+
+import "context"
+
+type subjectKey struct{}
+
+// ContextWithSubject returns a copy of ctx carrying the authenticated subject (user id)
+func ContextWithSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, subjectKey{}, subject)
+}
+
+// SubjectFromContext returns the authenticated subject, or "" for anonymous requests
+func SubjectFromContext(ctx context.Context) string {
+	subject, _ := ctx.Value(subjectKey{}).(string)
+	return subject
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// MetadataKey is the gRPC metadata key carrying the bearer token, forwarded by the gateway
+// from the HTTP Authorization header
+const MetadataKey = "authorization"
+
+// authenticate stores the subject of a valid bearer token in ctx. Calls without a token or with
+// an invalid one continue as anonymous, the service identifies callers but doesn't authorize them.
+func authenticate(ctx context.Context, v *Verifier, authorization string) context.Context {
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok {
+		return ctx
+	}
+	subject, err := v.Verify(strings.TrimSpace(token))
+	if err != nil {
+		return ctx
+	}
+	return ContextWithSubject(ctx, subject)
+}
+
+// Middleware identifies HTTP callers by the bearer token of the Authorization header
+func Middleware(v *Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(authenticate(r.Context(), v, r.Header.Get("Authorization"))))
+		})
+	}
+}
+
+// UnaryServerInterceptor identifies gRPC callers by the bearer token of the authorization metadata
+func UnaryServerInterceptor(v *Verifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(authenticate(ctx, v, fromMetadata(ctx)), req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor
+func StreamServerInterceptor(v *Verifier) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := authenticate(ss.Context(), v, fromMetadata(ss.Context()))
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func fromMetadata(ctx context.Context) string {
+	if values := metadata.ValueFromIncomingContext(ctx, MetadataKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,220 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+
+	"movie-project/pkg/logger"
+)
+
+// Reloader keeps a certificate, its key and an optional CA bundle loaded from disk
+// and swaps them in when the files change, so running listeners pick up renewed
+// certificates without a restart.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+	logger   *logger.Logger
+
+	mu     sync.RWMutex
+	cert   *tls.Certificate
+	caPool *x509.CertPool
+}
+
+// NewReloader loads certFile/keyFile and, when set, the PEM CA bundle caFile
+func NewReloader(certFile, keyFile, caFile string, log *logger.Logger) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, caFile: caFile, logger: log}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the files again. On error the previously loaded certificates stay in use.
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+
+	var caPool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("read CA bundle: %w", err)
+		}
+		caPool = x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in CA bundle %s", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.caPool = &cert, caPool
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, r.caPool
+}
+
+// Watch reloads the certificates whenever one of the files changes until ctx is done.
+// Directories are watched rather than files, so atomic replacements (rename, Kubernetes
+// secret symlink swaps) are noticed as well.
+func (r *Reloader) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	files := map[string]bool{}
+	dirs := map[string]bool{}
+	for _, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		if file == "" {
+			continue
+		}
+		file = filepath.Clean(file)
+		files[file] = true
+		dirs[filepath.Dir(file)] = true
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return err
+		}
+	}
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				name := filepath.Clean(event.Name)
+				if !files[name] && !strings.HasPrefix(filepath.Base(name), "..") {
+					continue
+				}
+				if err := r.Reload(); err != nil {
+					r.logger.Warn("Failed to reload certificates, keeping the current ones", "error", err, "file", name)
+					continue
+				}
+				r.logger.Info("Reloaded certificates", "file", name)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				r.logger.Error("Certificate watcher failed", "error", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// ServerConfig returns a TLS config for listeners. clientAuth is one of tls.NoClientCert,
+// tls.VerifyClientCertIfGiven or tls.RequireAndVerifyClientCert; client certificates are
+// verified against the CA bundle as it is at the time of the handshake, resumed sessions included.
+func (r *Reloader) ServerConfig(clientAuth tls.ClientAuthType) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			return cert, nil
+		},
+	}
+	if clientAuth == tls.NoClientCert {
+		return cfg
+	}
+
+	// ClientCAs is static, so verification against the current pool is done by hand. VerifyConnection
+	// runs on resumed sessions too, unlike VerifyPeerCertificate, so a session established before the
+	// CA bundle changed is checked against the new one.
+	cfg.ClientAuth = tls.RequireAnyClientCert
+	if clientAuth == tls.VerifyClientCertIfGiven {
+		cfg.ClientAuth = tls.RequestClientCert
+	}
+	cfg.VerifyConnection = func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 && cfg.ClientAuth == tls.RequestClientCert {
+			return nil
+		}
+		_, caPool := r.current()
+		return verifyChain(rawCerts(state), caPool, "", x509.ExtKeyUsageClientAuth)
+	}
+	return cfg
+}
+
+// ClientConfig returns a TLS config for dialing serverName that presents the loaded certificate.
+// Servers are verified against the CA bundle when one is configured and the system roots otherwise.
+func (r *Reloader) ClientConfig(serverName string) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			return cert, nil
+		},
+	}
+	if r.caFile != "" {
+		// RootCAs is static, so verification against the current pool is done by hand
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(state tls.ConnectionState) error {
+			_, caPool := r.current()
+			return verifyChain(rawCerts(state), caPool, serverName, x509.ExtKeyUsageServerAuth)
+		}
+	}
+	return cfg
+}
+
+// rawCerts returns the DER encoding of the certificates the peer presented
+func rawCerts(state tls.ConnectionState) [][]byte {
+	raw := make([][]byte, len(state.PeerCertificates))
+	for i, cert := range state.PeerCertificates {
+		raw[i] = cert.Raw
+	}
+	return raw
+}
+
+func verifyChain(rawCerts [][]byte, roots *x509.CertPool, dnsName string, usage x509.ExtKeyUsage) error {
+	if len(rawCerts) == 0 {
+		return errors.New("no peer certificate")
+	}
+	if roots == nil {
+		return errors.New("no CA bundle configured to verify the peer")
+	}
+
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		certs[i] = cert
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		DNSName:       dnsName,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	})
+	return err
+}
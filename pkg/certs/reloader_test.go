@@ -0,0 +1,232 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"movie-project/pkg/logger"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue writes a certificate and key for localhost signed by the CA
+func (ca testCA) issue(t *testing.T, certFile, keyFile string, serial int64, usage x509.ExtKeyUsage) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	// Write to temporary files and rename, the way certificate managers replace files
+	write := func(path string, block *pem.Block) {
+		tmp := path + ".tmp"
+		require.NoError(t, os.WriteFile(tmp, pem.EncodeToMemory(block), 0o600))
+		require.NoError(t, os.Rename(tmp, path))
+	}
+	write(keyFile, &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	write(certFile, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// serve accepts TLS connections until the test ends and completes their handshakes
+func serve(t *testing.T, cfg *tls.Config) string {
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { lis.Close() })
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+	return lis.Addr().String()
+}
+
+func handshake(addr string, cfg *tls.Config) (*x509.Certificate, error) {
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: time.Second}, "tcp", addr, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// With TLS 1.3 a rejected client certificate surfaces on the first read
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return conn.ConnectionState().PeerCertificates[0], nil
+}
+
+func TestReloader_ReloadsChangedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0o600))
+	ca.issue(t, certFile, keyFile, 100, x509.ExtKeyUsageServerAuth)
+
+	reloader, err := NewReloader(certFile, keyFile, caFile, logger.Discard())
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, reloader.Watch(ctx))
+
+	addr := serve(t, reloader.ServerConfig(tls.NoClientCert))
+	clientCfg := reloader.ClientConfig("localhost")
+
+	cert, err := handshake(addr, clientCfg)
+	require.NoError(t, err)
+	assert.EqualValues(t, 100, cert.SerialNumber.Int64())
+
+	ca.issue(t, certFile, keyFile, 200, x509.ExtKeyUsageServerAuth)
+	assert.Eventually(t, func() bool {
+		cert, err := handshake(addr, clientCfg)
+		return err == nil && cert.SerialNumber.Int64() == 200
+	}, 5*time.Second, 20*time.Millisecond)
+
+	// A broken file keeps the current certificate in use
+	require.NoError(t, os.WriteFile(certFile, []byte("garbage"), 0o600))
+	time.Sleep(100 * time.Millisecond)
+	cert, err = handshake(addr, clientCfg)
+	require.NoError(t, err)
+	assert.EqualValues(t, 200, cert.SerialNumber.Int64())
+}
+
+func TestReloader_RequireClientCert(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0o600))
+	ca.issue(t, filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), 1, x509.ExtKeyUsageServerAuth)
+	ca.issue(t, filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key"), 2, x509.ExtKeyUsageClientAuth)
+
+	serverCerts, err := NewReloader(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), caFile, logger.Discard())
+	require.NoError(t, err)
+	clientCerts, err := NewReloader(filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key"), caFile, logger.Discard())
+	require.NoError(t, err)
+
+	addr := serve(t, serverCerts.ServerConfig(tls.RequireAndVerifyClientCert))
+
+	_, err = handshake(addr, clientCerts.ClientConfig("localhost"))
+	assert.NoError(t, err, "client certificate issued by the CA is accepted")
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	_, err = handshake(addr, &tls.Config{RootCAs: roots, ServerName: "localhost"})
+	assert.Error(t, err, "client without certificate is rejected")
+
+	otherCA := newTestCA(t)
+	otherCA.issue(t, filepath.Join(dir, "other.crt"), filepath.Join(dir, "other.key"), 3, x509.ExtKeyUsageClientAuth)
+	otherCert, err := tls.LoadX509KeyPair(filepath.Join(dir, "other.crt"), filepath.Join(dir, "other.key"))
+	require.NoError(t, err)
+	_, err = handshake(addr, &tls.Config{RootCAs: roots, ServerName: "localhost", Certificates: []tls.Certificate{otherCert}})
+	assert.Error(t, err, "client certificate from another CA is rejected")
+
+	_, err = handshake(addr, clientCerts.ClientConfig("movies.example.com"))
+	assert.Error(t, err, "server name is verified")
+
+	optional := serve(t, serverCerts.ServerConfig(tls.VerifyClientCertIfGiven))
+	_, err = handshake(optional, &tls.Config{RootCAs: roots, ServerName: "localhost"})
+	assert.NoError(t, err, "client certificate is optional")
+	_, err = handshake(optional, clientCerts.ClientConfig("localhost"))
+	assert.NoError(t, err)
+	_, err = handshake(optional, &tls.Config{RootCAs: roots, ServerName: "localhost", Certificates: []tls.Certificate{otherCert}})
+	assert.Error(t, err, "a given client certificate is still verified")
+}
+
+// TestReloader_ResumedSessionUsesCurrentCA checks client certificates of resumed sessions, which
+// skip the certificate exchange, against the CA bundle as it is now rather than when first verified
+func TestReloader_ResumedSessionUsesCurrentCA(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0o600))
+	ca.issue(t, filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), 1, x509.ExtKeyUsageServerAuth)
+	ca.issue(t, filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key"), 2, x509.ExtKeyUsageClientAuth)
+
+	serverCerts, err := NewReloader(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), caFile, logger.Discard())
+	require.NoError(t, err)
+	clientCerts, err := NewReloader(filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key"), caFile, logger.Discard())
+	require.NoError(t, err)
+
+	addr := serve(t, serverCerts.ServerConfig(tls.RequireAndVerifyClientCert))
+	clientCfg := clientCerts.ClientConfig("localhost")
+	clientCfg.ClientSessionCache = tls.NewLRUClientSessionCache(1)
+
+	resumed := func() (bool, error) {
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: time.Second}, "tcp", addr, clientCfg)
+		if err != nil {
+			return false, err
+		}
+		defer conn.Close()
+		// Reading receives the session ticket, or the rejection of the client certificate
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if _, err := conn.Read(make([]byte, 1)); err != nil && !errors.Is(err, io.EOF) {
+			return false, err
+		}
+		return conn.ConnectionState().DidResume, nil
+	}
+
+	_, err = resumed()
+	require.NoError(t, err)
+	didResume, err := resumed()
+	require.NoError(t, err)
+	require.True(t, didResume, "the second connection resumes the session")
+
+	// Rotate the CA bundle of the server, the client certificate is no longer trusted
+	require.NoError(t, os.WriteFile(caFile, newTestCA(t).pem, 0o600))
+	require.NoError(t, serverCerts.Reload())
+	_, err = resumed()
+	assert.Error(t, err, "resumed sessions are verified against the current CA bundle")
+}
@@ -0,0 +1,143 @@
+// Package client is the Go SDK of the movie service. It wraps the generated MovieServiceClient
+// with connection setup, bearer token authentication, retries of unavailable calls, pagination
+// and typed errors:
+//
+//	c, err := client.New("movies.internal:50051", client.Options{Token: token, TLS: &tls.Config{}})
+//	if err != nil {
+//		return err
+//	}
+//	defer c.Close()
+//
+//	it := c.Movies(ctx)
+//	for it.Next() {
+//		fmt.Println(it.Movie().Title)
+//	}
+//	if err := it.Err(); errors.Is(err, client.ErrUnavailable) {
+//		...
+//	}
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	pb "movie-project/proto/movie"
+)
+
+// Defaults of Options
+const (
+	DefaultMaxRetries      = 3
+	DefaultRetryBackoff    = 100 * time.Millisecond
+	DefaultMaxRetryBackoff = 2 * time.Second
+	DefaultPageSize        = 50
+)
+
+// Options configures a Client. The zero value connects without TLS and authentication.
+type Options struct {
+	// Token is sent with every call as "authorization: Bearer <token>"
+	Token string
+	// TokenSource, when set, is asked for the token of every call instead of using Token,
+	// e.g. to refresh expiring tokens
+	TokenSource func(ctx context.Context) (string, error)
+
+	// TLS enables TLS with the given configuration; nil connects in plain text
+	TLS *tls.Config
+
+	// MaxRetries is how often calls failing with codes.Unavailable are retried, DefaultMaxRetries
+	// if zero; negative disables retries. CreateMovie is never retried, it isn't idempotent.
+	// DeleteMovie succeeds when a retry finds the movie gone, since the response of an earlier
+	// attempt that deleted it may have been lost.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, doubling up to MaxRetryBackoff
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+
+	// PageSize is the number of movies Movies fetches per call, DefaultPageSize if zero
+	PageSize int
+
+	// DialOptions are added to the options of the connection, e.g. a custom dialer
+	DialOptions []grpc.DialOption
+}
+
+// Client calls the movie service. It is safe for concurrent use.
+type Client struct {
+	conn     *grpc.ClientConn
+	movies   pb.MovieServiceClient
+	pageSize int
+}
+
+// New returns a client of the movie service at target, e.g. "localhost:50051". The connection
+// is established on the first call and re-established whenever it breaks.
+func New(target string, opts Options) (*Client, error) {
+	creds := insecure.NewCredentials()
+	if opts.TLS != nil {
+		creds = credentials.NewTLS(opts.TLS)
+	}
+
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(
+			newRetrier(opts).unaryClientInterceptor,
+			tokenInterceptor(opts),
+		),
+	}
+	conn, err := grpc.NewClient(target, append(dialOpts, opts.DialOptions...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	return &Client{conn: conn, movies: pb.NewMovieServiceClient(conn), pageSize: pageSize}, nil
+}
+
+// Close closes the connection. Calls in flight fail with codes.Canceled.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// MovieService returns the generated client on the same connection, for call options not
+// available here. Its calls are authenticated and retried the same way and fail with an *Error.
+func (c *Client) MovieService() pb.MovieServiceClient {
+	return c.movies
+}
+
+// CreateMovie creates a movie and returns it with its id
+func (c *Client) CreateMovie(ctx context.Context, req *pb.CreateMovieRequest) (*pb.Movie, error) {
+	return c.movies.CreateMovie(ctx, req)
+}
+
+// GetMovie returns the movie with the given id, or an error matching ErrNotFound
+func (c *Client) GetMovie(ctx context.Context, id int64) (*pb.Movie, error) {
+	return c.movies.GetMovie(ctx, &pb.GetMovieRequest{Id: id})
+}
+
+// ListMovies returns one page of movies, numbered from 1, and the total number of movies
+func (c *Client) ListMovies(ctx context.Context, pageNumber, pageSize int32) (*pb.ListMoviesResponse, error) {
+	return c.movies.ListMovies(ctx, &pb.ListMoviesRequest{PageNumber: pageNumber, PageSize: pageSize})
+}
+
+// UpdateMovie replaces the fields of the movie with the id of req named by its update mask, or
+// all of them without one
+func (c *Client) UpdateMovie(ctx context.Context, req *pb.UpdateMovieRequest) (*pb.Movie, error) {
+	return c.movies.UpdateMovie(ctx, req)
+}
+
+// DeleteMovie deletes the movie with the given id, or returns an error matching ErrNotFound.
+// After retries, a movie that is already gone counts as deleted.
+func (c *Client) DeleteMovie(ctx context.Context, id int64) error {
+	_, err := c.movies.DeleteMovie(ctx, &pb.DeleteMovieRequest{Id: id})
+	return err
+}
+
+// Movies returns an iterator over all movies, fetching them page by page as it advances
+func (c *Client) Movies(ctx context.Context) *MovieIterator {
+	return &MovieIterator{ctx: ctx, client: c, pageSize: int32(c.pageSize), next: 1}
+}
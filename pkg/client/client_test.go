@@ -0,0 +1,278 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "movie-project/proto/movie"
+)
+
+// fakeServer serves movies from memory and fails the first calls of a method as told
+type fakeServer struct {
+	pb.UnimplementedMovieServiceServer
+
+	mu     sync.Mutex
+	movies []*pb.Movie
+	// failures are the errors returned, in order, before calls of a method succeed
+	failures map[string][]error
+	calls    map[string]int
+	tokens   []string
+}
+
+func (s *fakeServer) call(ctx context.Context, method string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[method]++
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.tokens = append(s.tokens, md.Get("authorization")...)
+	if errs := s.failures[method]; len(errs) > 0 {
+		s.failures[method] = errs[1:]
+		return errs[0]
+	}
+	return nil
+}
+
+func (s *fakeServer) CreateMovie(ctx context.Context, req *pb.CreateMovieRequest) (*pb.Movie, error) {
+	if err := s.call(ctx, "CreateMovie"); err != nil {
+		return nil, err
+	}
+	return &pb.Movie{Id: 1, Title: req.Title}, nil
+}
+
+func (s *fakeServer) GetMovie(ctx context.Context, req *pb.GetMovieRequest) (*pb.Movie, error) {
+	if err := s.call(ctx, "GetMovie"); err != nil {
+		return nil, err
+	}
+	for _, movie := range s.movies {
+		if movie.Id == req.Id {
+			return movie, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "Movie not found: record not found")
+}
+
+func (s *fakeServer) ListMovies(ctx context.Context, req *pb.ListMoviesRequest) (*pb.ListMoviesResponse, error) {
+	if err := s.call(ctx, "ListMovies"); err != nil {
+		return nil, err
+	}
+	start := min(int((req.PageNumber-1)*req.PageSize), len(s.movies))
+	end := min(start+int(req.PageSize), len(s.movies))
+	return &pb.ListMoviesResponse{Movies: s.movies[start:end], TotalCount: int32(len(s.movies))}, nil
+}
+
+// DeleteMovie fails as told after deleting the movie, like a server whose response is lost
+func (s *fakeServer) DeleteMovie(ctx context.Context, req *pb.DeleteMovieRequest) (*pb.DeleteMovieResponse, error) {
+	s.mu.Lock()
+	found := false
+	for i, movie := range s.movies {
+		if movie.Id == req.Id {
+			s.movies = append(s.movies[:i:i], s.movies[i+1:]...)
+			found = true
+			break
+		}
+	}
+	s.mu.Unlock()
+	if err := s.call(ctx, "DeleteMovie"); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, status.Errorf(codes.NotFound, "Movie not found: record not found")
+	}
+	return &pb.DeleteMovieResponse{Success: true}, nil
+}
+
+func newTestClient(t *testing.T, server *fakeServer, opts Options) *Client {
+	if server.calls == nil {
+		server.calls = map[string]int{}
+	}
+	lis := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	pb.RegisterMovieServiceServer(grpcServer, server)
+	go grpcServer.Serve(lis)
+
+	opts.RetryBackoff = time.Millisecond
+	opts.DialOptions = append(opts.DialOptions, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}))
+	c, err := New("passthrough:///bufnet", opts)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		c.Close()
+		grpcServer.Stop()
+	})
+	return c
+}
+
+func movies(n int) []*pb.Movie {
+	movies := make([]*pb.Movie, n)
+	for i := range movies {
+		movies[i] = &pb.Movie{Id: int64(i + 1), Title: fmt.Sprintf("Movie %d", i+1)}
+	}
+	return movies
+}
+
+func TestClient_Token(t *testing.T) {
+	server := &fakeServer{movies: movies(1)}
+	c := newTestClient(t, server, Options{Token: "s3cret"})
+	_, err := c.GetMovie(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bearer s3cret"}, server.tokens)
+
+	server = &fakeServer{movies: movies(1)}
+	issued := 0
+	c = newTestClient(t, server, Options{TokenSource: func(context.Context) (string, error) {
+		issued++
+		return fmt.Sprintf("token-%d", issued), nil
+	}})
+	_, err = c.GetMovie(context.Background(), 1)
+	require.NoError(t, err)
+	_, err = c.GetMovie(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-2"}, server.tokens)
+
+	c = newTestClient(t, &fakeServer{}, Options{TokenSource: func(context.Context) (string, error) {
+		return "", errors.New("expired")
+	}})
+	_, err = c.GetMovie(context.Background(), 1)
+	assert.ErrorContains(t, err, "expired")
+}
+
+func TestClient_Retries(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+	server := &fakeServer{movies: movies(1), failures: map[string][]error{
+		"GetMovie":    {unavailable, unavailable},
+		"CreateMovie": {unavailable},
+		"ListMovies":  {unavailable, unavailable, unavailable},
+	}}
+	c := newTestClient(t, server, Options{MaxRetries: 2})
+
+	movie, err := c.GetMovie(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Movie 1", movie.Title)
+	assert.Equal(t, 3, server.calls["GetMovie"])
+
+	_, err = c.CreateMovie(context.Background(), &pb.CreateMovieRequest{Title: "New"})
+	assert.ErrorIs(t, err, ErrUnavailable, "creates are not retried")
+	assert.Equal(t, 1, server.calls["CreateMovie"])
+
+	_, err = c.ListMovies(context.Background(), 1, 10)
+	assert.ErrorIs(t, err, ErrUnavailable, "retries are limited")
+	assert.Equal(t, 3, server.calls["ListMovies"])
+
+	server = &fakeServer{failures: map[string][]error{"GetMovie": {unavailable, unavailable}}}
+	c = newTestClient(t, server, Options{MaxRetries: -1})
+	_, err = c.GetMovie(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, 1, server.calls["GetMovie"])
+}
+
+func TestClient_RetriedDelete(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "connection reset")
+	server := &fakeServer{movies: movies(2), failures: map[string][]error{"DeleteMovie": {unavailable}}}
+	c := newTestClient(t, server, Options{})
+
+	// The first attempt deletes the movie but its response is lost, the retry finds it gone
+	require.NoError(t, c.DeleteMovie(context.Background(), 1))
+	assert.Equal(t, 2, server.calls["DeleteMovie"])
+	resp, err := c.MovieService().DeleteMovie(context.Background(), &pb.DeleteMovieRequest{Id: 1})
+	assert.ErrorIs(t, err, ErrNotFound, "without retries a missing movie is still an error")
+	assert.Nil(t, resp)
+
+	server.failures["DeleteMovie"] = []error{unavailable}
+	resp, err = c.MovieService().DeleteMovie(context.Background(), &pb.DeleteMovieRequest{Id: 2})
+	require.NoError(t, err)
+	assert.True(t, resp.Success)
+	assert.Len(t, server.movies, 0)
+}
+
+func TestClient_Errors(t *testing.T) {
+	server := &fakeServer{}
+	c := newTestClient(t, server, Options{})
+
+	_, err := c.GetMovie(context.Background(), 4242)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NotErrorIs(t, err, ErrInternal)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	var callErr *Error
+	require.ErrorAs(t, err, &callErr)
+	assert.Equal(t, "Movie not found: record not found", callErr.Message)
+
+	// rate limited like pkg/ratelimit does it
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		grpc.SetHeader(ctx, metadata.Pairs("retry-after", "3"))
+		return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded, retry after 3s")
+	}))
+	pb.RegisterMovieServiceServer(grpcServer, server)
+	lis := bufconn.Listen(1024 * 1024)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+	limited, err := New("passthrough:///bufnet", Options{DialOptions: []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+	}})
+	require.NoError(t, err)
+	defer limited.Close()
+
+	_, err = limited.ListMovies(context.Background(), 1, 10)
+	assert.ErrorIs(t, err, ErrRateLimited)
+	require.ErrorAs(t, err, &callErr)
+	assert.Equal(t, 3*time.Second, callErr.RetryAfter)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.GetMovie(ctx, 1)
+	assert.ErrorIs(t, err, ErrCanceled)
+}
+
+func TestMovieIterator(t *testing.T) {
+	for _, n := range []int{0, 3, 7, 9} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			server := &fakeServer{movies: movies(n)}
+			c := newTestClient(t, server, Options{PageSize: 3})
+
+			var ids []int64
+			it := c.Movies(context.Background())
+			for it.Next() {
+				ids = append(ids, it.Movie().Id)
+			}
+			require.NoError(t, it.Err())
+			assert.Len(t, ids, n)
+			for i, id := range ids {
+				assert.Equal(t, int64(i+1), id)
+			}
+			assert.Equal(t, int32(n), it.Total())
+			assert.Equal(t, max(1, (n+2)/3), server.calls["ListMovies"], "no extra call after the last page")
+			assert.False(t, it.Next())
+		})
+	}
+}
+
+func TestMovieIterator_Error(t *testing.T) {
+	server := &fakeServer{movies: movies(5), failures: map[string][]error{"ListMovies": {
+		nil, status.Error(codes.Internal, "boom"),
+	}}}
+	c := newTestClient(t, server, Options{PageSize: 3})
+
+	var ids []int64
+	it := c.Movies(context.Background())
+	for it.Next() {
+		ids = append(ids, it.Movie().Id)
+	}
+	assert.Equal(t, []int64{1, 2, 3}, ids)
+	assert.ErrorIs(t, it.Err(), ErrInternal)
+	assert.Nil(t, it.Movie())
+}
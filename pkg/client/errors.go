@@ -0,0 +1,89 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Errors matched by the errors of failed calls with errors.Is
+var (
+	ErrInvalidArgument  = errors.New("invalid argument")
+	ErrNotFound         = errors.New("not found")
+	ErrUnauthenticated  = errors.New("unauthenticated")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrRateLimited      = errors.New("rate limited")
+	ErrDeadlineExceeded = errors.New("deadline exceeded")
+	ErrCanceled         = errors.New("canceled")
+	ErrUnavailable      = errors.New("service unavailable")
+	ErrInternal         = errors.New("internal error")
+)
+
+var codeErrors = map[codes.Code]error{
+	codes.InvalidArgument:   ErrInvalidArgument,
+	codes.NotFound:          ErrNotFound,
+	codes.Unauthenticated:   ErrUnauthenticated,
+	codes.PermissionDenied:  ErrPermissionDenied,
+	codes.ResourceExhausted: ErrRateLimited,
+	codes.DeadlineExceeded:  ErrDeadlineExceeded,
+	codes.Canceled:          ErrCanceled,
+	codes.Unavailable:       ErrUnavailable,
+	codes.Internal:          ErrInternal,
+	codes.Unknown:           ErrInternal,
+	codes.DataLoss:          ErrInternal,
+}
+
+// Error is the error of a failed call
+type Error struct {
+	Code    codes.Code
+	Message string
+	// RetryAfter is how long the server asked to wait before retrying, for rate limited calls
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("movie service: %s: %s", e.Code, e.Message)
+}
+
+// Is reports whether target is the Err variable for the code of e
+func (e *Error) Is(target error) bool {
+	err, ok := codeErrors[e.Code]
+	return ok && err == target
+}
+
+// GRPCStatus returns the status of the call, so that status.Code works on e
+func (e *Error) GRPCStatus() *status.Status {
+	return status.New(e.Code, e.Message)
+}
+
+// wrapError turns the status error of a call into an *Error, taking the retry-after header of
+// rate limited calls from the response header
+func wrapError(err error, header metadata.MD) error {
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	wrapped := &Error{Code: s.Code(), Message: s.Message()}
+	if s.Code() == codes.ResourceExhausted {
+		wrapped.RetryAfter = retryAfter(header)
+	}
+	return wrapped
+}
+
+// retryAfter parses the retry-after header sent with rate limited calls
+func retryAfter(header metadata.MD) time.Duration {
+	values := header.Get("retry-after")
+	if len(values) == 0 {
+		return 0
+	}
+	seconds, err := strconv.Atoi(values[0])
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "movie-project/proto/movie"
+)
+
+// retrier retries calls failing with codes.Unavailable and turns the final error into an *Error
+type retrier struct {
+	maxRetries int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+func newRetrier(opts Options) retrier {
+	r := retrier{maxRetries: opts.MaxRetries, backoff: opts.RetryBackoff, maxBackoff: opts.MaxRetryBackoff}
+	if r.maxRetries == 0 {
+		r.maxRetries = DefaultMaxRetries
+	}
+	if r.backoff <= 0 {
+		r.backoff = DefaultRetryBackoff
+	}
+	if r.maxBackoff <= 0 {
+		r.maxBackoff = DefaultMaxRetryBackoff
+	}
+	return r
+}
+
+func (r retrier) unaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	var header metadata.MD
+	opts = append(opts, grpc.Header(&header))
+
+	delay := r.backoff
+	for attempt := 0; ; attempt++ {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil {
+			return nil
+		}
+		if attempt > 0 && method == pb.MovieService_DeleteMovie_FullMethodName && status.Code(err) == codes.NotFound {
+			// an earlier attempt may have deleted the movie and lost the response; either way it's gone
+			if resp, ok := reply.(*pb.DeleteMovieResponse); ok {
+				resp.Success = true
+			}
+			return nil
+		}
+		if status.Code(err) != codes.Unavailable || attempt >= r.maxRetries || method == pb.MovieService_CreateMovie_FullMethodName {
+			return wrapError(err, header)
+		}
+
+		// jitter keeps clients that failed together from retrying in lockstep
+		wait := delay/2 + rand.N(delay/2+1)
+		select {
+		case <-ctx.Done():
+			return wrapError(status.FromContextError(ctx.Err()).Err(), nil)
+		case <-time.After(wait):
+		}
+		delay = min(2*delay, r.maxBackoff)
+	}
+}
+
+// tokenInterceptor adds the bearer token to every attempt of a call
+func tokenInterceptor(opts Options) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		token := opts.Token
+		if opts.TokenSource != nil {
+			var err error
+			if token, err = opts.TokenSource(ctx); err != nil {
+				return fmt.Errorf("failed to get token: %w", err)
+			}
+		}
+		if token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+		}
+		return invoker(ctx, method, req, reply, cc, callOpts...)
+	}
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+
+	pb "movie-project/proto/movie"
+)
+
+// MovieIterator walks through all movies in id order. Movies created or deleted while iterating
+// may shift pages, so a movie can be skipped or seen twice.
+type MovieIterator struct {
+	ctx      context.Context
+	client   *Client
+	pageSize int32
+
+	page    []*pb.Movie
+	current *pb.Movie
+	next    int32 // number of the page to fetch next
+	total   int32
+	seen    int32
+	done    bool
+	err     error
+}
+
+// Next advances to the next movie, fetching the next page when needed. It returns false when
+// there are no more movies or a call failed; check Err to tell them apart.
+func (it *MovieIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	for len(it.page) == 0 {
+		if it.done {
+			it.current = nil
+			return false
+		}
+		it.fetch()
+		if it.err != nil {
+			it.current = nil
+			return false
+		}
+	}
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+func (it *MovieIterator) fetch() {
+	resp, err := it.client.ListMovies(it.ctx, it.next, it.pageSize)
+	if err != nil {
+		it.err = err
+		return
+	}
+	it.next++
+	it.page = resp.Movies
+	it.total = resp.TotalCount
+	it.seen += int32(len(resp.Movies))
+	// a short page is the last one, and so is the page reaching the total
+	it.done = int32(len(resp.Movies)) < it.pageSize || it.seen >= it.total
+}
+
+// Movie returns the current movie
+func (it *MovieIterator) Movie() *pb.Movie {
+	return it.current
+}
+
+// Total returns the number of movies reported by the last page fetched
+func (it *MovieIterator) Total() int32 {
+	return it.total
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *MovieIterator) Err() error {
+	return it.err
+}
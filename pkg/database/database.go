@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"movie-project/config"
+	"movie-project/pkg/logger"
+	"movie-project/pkg/tracing"
+)
+
+// Delays between connection attempts at startup, doubling from the first to the last
+const (
+	minRetryDelay = 500 * time.Millisecond
+	maxRetryDelay = 5 * time.Second
+)
+
+// Open connects to the database selected by cfg.DBDriver. It retries with exponential backoff
+// until the database answers or cfg.DBConnectTimeout has passed, so the server can start
+// alongside its database. Queries go to the read replicas of cfg.DBReplicaDSNs, if any.
+func Open(cfg config.Config, log *logger.Logger) (*gorm.DB, error) {
+	dialector, err := dialector(cfg.DBDriver, cfg.GetDSN())
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, gormConfig())
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	configurePool(sqlDB, cfg)
+	if err := connect(sqlDB, cfg.DBConnectTimeout, log); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		return nil, err
+	}
+	if cfg.DBStatementTimeout > 0 {
+		if err := db.Use(StatementTimeout(cfg.DBStatementTimeout)); err != nil {
+			return nil, err
+		}
+	}
+	if len(cfg.DBReplicaDSNs) > 0 {
+		replicas, err := openReplicas(cfg, log)
+		if err != nil {
+			return nil, err
+		}
+		replicas.Check(context.Background(), cfg.HealthCheckTimeout)
+		if err := db.Use(replicas); err != nil {
+			replicas.Close()
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
+
+func dialector(driver, dsn string) (gorm.Dialector, error) {
+	switch driver {
+	case config.DriverPostgres:
+		return postgres.Open(dsn), nil
+	case config.DriverSQLite:
+		return sqlite.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %q", driver)
+	}
+}
+
+func gormConfig() *gorm.Config {
+	return &gorm.Config{
+		// PostgreSQL keeps microseconds in UTC sessions (see Config.GetDSN), keep SQLite timestamps in line
+		NowFunc: func() time.Time { return time.Now().UTC().Truncate(time.Microsecond) },
+		// connect pings with retries instead
+		DisableAutomaticPing: true,
+		// report unique and foreign key violations as gorm.ErrDuplicatedKey and gorm.ErrForeignKeyViolated
+		TranslateError: true,
+	}
+}
+
+func configurePool(sqlDB *sql.DB, cfg config.Config) {
+	if cfg.DBDriver == config.DriverSQLite {
+		// SQLite allows a single writer; serialize access instead of failing with "database is locked"
+		sqlDB.SetMaxOpenConns(1)
+		return
+	}
+	sqlDB.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.DBConnMaxIdleTime)
+}
+
+// connect pings the database until it answers, waiting longer after every failure, and gives
+// up with the last error once timeout has passed. A zero timeout pings once.
+func connect(sqlDB *sql.DB, timeout time.Duration, log *logger.Logger) error {
+	deadline := time.Now().Add(timeout)
+	delay := minRetryDelay
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), maxRetryDelay)
+		err := sqlDB.PingContext(ctx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if time.Now().Add(delay).After(deadline) {
+			return fmt.Errorf("database not reachable after %d attempts: %w", attempt, err)
+		}
+		log.Warn("Database not reachable, retrying", "attempt", attempt, "retry_in", delay.String(), "error", err)
+		time.Sleep(delay)
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
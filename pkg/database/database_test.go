@@ -0,0 +1,106 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"movie-project/config"
+	"movie-project/pkg/logger"
+)
+
+// countForever counts until interrupted
+const countForever = "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT count(*) FROM c"
+
+func TestStatementTimeout(t *testing.T) {
+	db, err := Open(config.Config{
+		DBDriver:           config.DriverSQLite,
+		DBPath:             filepath.Join(t.TempDir(), "movie.db"),
+		DBStatementTimeout: 50 * time.Millisecond,
+	}, logger.Discard())
+	require.NoError(t, err)
+
+	var count int64
+	err = db.Raw(countForever).Find(&count).Error
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, db.Exec(countForever).Error, context.DeadlineExceeded)
+
+	require.NoError(t, db.Raw("SELECT 1").Find(&count).Error, "later statements get a fresh timeout")
+	assert.Equal(t, int64(1), count)
+}
+
+// createTitles creates a database holding a movies table with a single title
+func createTitles(t *testing.T, path, title string) {
+	t.Helper()
+	db, err := Open(config.Config{DBDriver: config.DriverSQLite, DBPath: path}, logger.Discard())
+	require.NoError(t, err)
+	require.NoError(t, db.Exec("CREATE TABLE movies (title TEXT)").Error)
+	require.NoError(t, db.Exec("INSERT INTO movies (title) VALUES (?)", title).Error)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+}
+
+func titles(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	var titles []string
+	require.NoError(t, db.Table("movies").Order("title").Pluck("title", &titles).Error)
+	return titles
+}
+
+func TestReplicaSet(t *testing.T) {
+	dir := t.TempDir()
+	primary, replica := filepath.Join(dir, "primary.db"), filepath.Join(dir, "replica.db")
+	createTitles(t, primary, "primary")
+	createTitles(t, replica, "replica")
+
+	db, err := Open(config.Config{
+		DBDriver:           config.DriverSQLite,
+		DBPath:             primary,
+		DBReplicaDSNs:      []string{replica},
+		HealthCheckTimeout: time.Second,
+	}, logger.Discard())
+	require.NoError(t, err)
+	replicas := Replicas(db)
+	require.NotNil(t, replicas)
+	assert.Equal(t, 1, replicas.Healthy())
+	require.Len(t, replicas.DBs(), 1)
+	assert.Same(t, replicas.replicas[0].db, replicas.DBs()[0])
+
+	assert.Equal(t, []string{"replica"}, titles(t, db), "queries go to the replica")
+
+	require.NoError(t, db.Exec("INSERT INTO movies (title) VALUES ('written')").Error)
+	assert.Equal(t, []string{"replica"}, titles(t, db), "writes go to the primary")
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		assert.Equal(t, []string{"primary", "written"}, titles(t, tx), "transactions stay on the primary")
+		return nil
+	}))
+
+	require.NoError(t, replicas.replicas[0].db.Close())
+	replicas.Check(context.Background(), time.Second)
+	assert.Equal(t, 0, replicas.Healthy())
+	assert.Equal(t, []string{"primary", "written"}, titles(t, db), "queries fall back to the primary")
+
+	plain, err := Open(config.Config{DBDriver: config.DriverSQLite, DBPath: primary}, logger.Discard())
+	require.NoError(t, err)
+	assert.Nil(t, Replicas(plain))
+}
+
+func TestConnect_Retries(t *testing.T) {
+	sqlDB, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "missing", "movie.db"))
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	start := time.Now()
+	err = connect(sqlDB, 600*time.Millisecond, logger.Discard())
+	assert.ErrorContains(t, err, "after 2 attempts")
+	assert.GreaterOrEqual(t, time.Since(start), minRetryDelay)
+
+	assert.ErrorContains(t, connect(sqlDB, 0, logger.Discard()), "after 1 attempts")
+}
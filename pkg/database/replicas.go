@@ -0,0 +1,177 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+
+	"movie-project/config"
+	"movie-project/pkg/logger"
+)
+
+// replicasName is the plugin name of ReplicaSet
+const replicasName = "replicas"
+
+// ReplicaSet is a GORM plugin that sends queries to read replicas in turn. Writes, raw
+// statements, locking reads and everything inside a transaction use the primary, and so do
+// queries while no replica is healthy. Run keeps the health of the replicas up to date.
+type ReplicaSet struct {
+	replicas []*replica
+	next     atomic.Uint64
+	log      *logger.Logger
+}
+
+type replica struct {
+	// index is the position in DB_REPLICA_DSNS; DSNs carry credentials and are never logged
+	index   int
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// openReplicas opens a connection pool per replica with the same settings as the primary. It
+// doesn't connect: unreachable replicas are found by Check.
+func openReplicas(cfg config.Config, log *logger.Logger) (*ReplicaSet, error) {
+	r := &ReplicaSet{log: log}
+	for i, dsn := range cfg.DBReplicaDSNs {
+		dialector, err := dialector(cfg.DBDriver, dsn)
+		if err != nil {
+			return nil, err
+		}
+		db, err := gorm.Open(dialector, gormConfig())
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		configurePool(sqlDB, cfg)
+		replica := &replica{index: i, db: sqlDB}
+		// assume healthy, so that the first Check logs the replicas that are not
+		replica.healthy.Store(true)
+		r.replicas = append(r.replicas, replica)
+	}
+	return r, nil
+}
+
+// Replicas returns the ReplicaSet of a database opened with read replicas, or nil
+func Replicas(db *gorm.DB) *ReplicaSet {
+	r, _ := db.Config.Plugins[replicasName].(*ReplicaSet)
+	return r
+}
+
+// Name implements gorm.Plugin
+func (r *ReplicaSet) Name() string {
+	return replicasName
+}
+
+// Initialize implements gorm.Plugin
+func (r *ReplicaSet) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	for _, err := range []error{
+		callbacks.Query().Before("gorm:query").Register("replicas:query", r.route),
+		callbacks.Row().Before("gorm:row").Register("replicas:row", r.route),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// route switches the statement to a healthy replica unless it must see the primary
+func (r *ReplicaSet) route(db *gorm.DB) {
+	if _, inTransaction := db.Statement.ConnPool.(gorm.TxCommitter); inTransaction {
+		return
+	}
+	if _, locking := db.Statement.Clauses["FOR"]; locking {
+		return
+	}
+	if replica := r.pick(); replica != nil {
+		db.Statement.ConnPool = replica.db
+	}
+}
+
+// pick returns the next healthy replica in round-robin order, or nil when there is none
+func (r *ReplicaSet) pick() *replica {
+	n := uint64(len(r.replicas))
+	start := r.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		if replica := r.replicas[(start+i)%n]; replica.healthy.Load() {
+			return replica
+		}
+	}
+	return nil
+}
+
+// DBs returns the connection pools of the replicas, in the order of DB_REPLICA_DSNS
+func (r *ReplicaSet) DBs() []*sql.DB {
+	dbs := make([]*sql.DB, len(r.replicas))
+	for i, replica := range r.replicas {
+		dbs[i] = replica.db
+	}
+	return dbs
+}
+
+// Healthy returns the number of replicas that answered the last Check
+func (r *ReplicaSet) Healthy() int {
+	healthy := 0
+	for _, replica := range r.replicas {
+		if replica.healthy.Load() {
+			healthy++
+		}
+	}
+	return healthy
+}
+
+// Check pings every replica, waiting at most timeout for each, and logs replicas that change
+// health
+func (r *ReplicaSet) Check(ctx context.Context, timeout time.Duration) {
+	var wg sync.WaitGroup
+	for _, replica := range r.replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pingCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			err := replica.db.PingContext(pingCtx)
+			switch healthy := err == nil; {
+			case healthy && !replica.healthy.Swap(true):
+				r.log.Info("Read replica is healthy", "replica", replica.index)
+			case !healthy && replica.healthy.Swap(false):
+				r.log.Warn("Read replica is unhealthy", "replica", replica.index, "error", err, "healthy_replicas", r.Healthy())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Run checks the replicas every interval until ctx is done
+func (r *ReplicaSet) Run(ctx context.Context, interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Check(ctx, timeout)
+		}
+	}
+}
+
+// Close closes the connection pools of the replicas
+func (r *ReplicaSet) Close() error {
+	var errs []error
+	for _, replica := range r.replicas {
+		errs = append(errs, replica.db.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// timeoutKey stores the parent context and cancel function in the gorm.DB instance between the
+// before and after callbacks
+const timeoutKey = "timeout:context"
+
+type statementContext struct {
+	parent context.Context
+	cancel context.CancelFunc
+}
+
+// StatementTimeout is a GORM plugin that cancels every statement still running after the given
+// duration, on top of any deadline of the request context. Row and Rows results are read after
+// the callbacks return, so statements run through Row are not limited.
+type StatementTimeout time.Duration
+
+// Name implements gorm.Plugin
+func (StatementTimeout) Name() string {
+	return "timeout"
+}
+
+// Initialize implements gorm.Plugin. The callbacks wrap all others, transactions and tracing
+// spans included.
+func (t StatementTimeout) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	for _, err := range []error{
+		callbacks.Create().Before("*").Register("timeout:before_create", t.start),
+		callbacks.Create().After("*").Register("timeout:after_create", stop),
+		callbacks.Query().Before("*").Register("timeout:before_query", t.start),
+		callbacks.Query().After("*").Register("timeout:after_query", stop),
+		callbacks.Update().Before("*").Register("timeout:before_update", t.start),
+		callbacks.Update().After("*").Register("timeout:after_update", stop),
+		callbacks.Delete().Before("*").Register("timeout:before_delete", t.start),
+		callbacks.Delete().After("*").Register("timeout:after_delete", stop),
+		callbacks.Raw().Before("*").Register("timeout:before_raw", t.start),
+		callbacks.Raw().After("*").Register("timeout:after_raw", stop),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t StatementTimeout) start(db *gorm.DB) {
+	if db.Statement == nil || db.Statement.Context == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(db.Statement.Context, time.Duration(t))
+	db.InstanceSet(timeoutKey, statementContext{parent: db.Statement.Context, cancel: cancel})
+	db.Statement.Context = ctx
+}
+
+func stop(db *gorm.DB) {
+	value, ok := db.InstanceGet(timeoutKey)
+	if !ok {
+		return
+	}
+	state := value.(statementContext)
+	state.cancel()
+	db.Statement.Context = state.parent
+}
@@ -0,0 +1,71 @@
+// Package deadline bounds how long unary gRPC calls may run. The deadline reaches the repository
+// through the request context, so the database queries of a call that ran out of time are
+// cancelled as well instead of holding on to connections.
+package deadline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ParseMethods parses per-method timeouts given as "Method=duration", where Method is a full
+// ("/movie.MovieService/ListMovies") or short ("ListMovies") method name. A zero duration leaves
+// calls of the method without a server-side deadline.
+func ParseMethods(entries []string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration, len(entries))
+	for _, entry := range entries {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		method, value, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(method) == "" {
+			return nil, fmt.Errorf("invalid method timeout %q: expected Method=duration", entry)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || timeout < 0 {
+			return nil, fmt.Errorf("invalid method timeout %q: %q is not a duration", entry, value)
+		}
+		timeouts[strings.TrimSpace(method)] = timeout
+	}
+	return timeouts, nil
+}
+
+// UnaryServerInterceptor runs calls with the timeout of their method, or defaultTimeout, unless the
+// caller set an earlier deadline. A zero timeout sets none. Calls that fail after running out of
+// time return codes.DeadlineExceeded, which the gateway answers with 504 Gateway Timeout.
+// Streams are left alone: health watches and reflection are meant to stay open.
+func UnaryServerInterceptor(defaultTimeout time.Duration, methods map[string]time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if timeout := timeoutFor(info.FullMethod, defaultTimeout, methods); timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) && status.Code(err) != codes.DeadlineExceeded {
+			// whatever failed, it failed because the call ran out of time
+			return nil, status.Errorf(codes.DeadlineExceeded, "%s: deadline exceeded", info.FullMethod)
+		}
+		return resp, err
+	}
+}
+
+func timeoutFor(method string, defaultTimeout time.Duration, methods map[string]time.Duration) time.Duration {
+	if timeout, ok := methods[method]; ok {
+		return timeout
+	}
+	if i := strings.LastIndexByte(method, '/'); i >= 0 {
+		if timeout, ok := methods[method[i+1:]]; ok {
+			return timeout
+		}
+	}
+	return defaultTimeout
+}
@@ -0,0 +1,73 @@
+package deadline
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseMethods(t *testing.T) {
+	methods, err := ParseMethods([]string{"ListMovies=5s", "/movie.MovieService/GetMovie=0", " "})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{"ListMovies": 5 * time.Second, "/movie.MovieService/GetMovie": 0}, methods)
+
+	for _, invalid := range []string{"ListMovies", "=5s", "ListMovies=soon", "ListMovies=-1s"} {
+		_, err := ParseMethods([]string{invalid})
+		assert.Error(t, err, invalid)
+	}
+}
+
+// remaining returns a handler reporting how much time its context has left, 0 without a deadline
+func remaining(left *time.Duration) grpc.UnaryHandler {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		*left = 0
+		if deadline, ok := ctx.Deadline(); ok {
+			*left = time.Until(deadline)
+		}
+		return "ok", nil
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor(10*time.Second, map[string]time.Duration{
+		"ListMovies":                   time.Second,
+		"/movie.MovieService/GetMovie": 0,
+	})
+	call := func(ctx context.Context, method string) time.Duration {
+		var left time.Duration
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, remaining(&left))
+		require.NoError(t, err)
+		return left
+	}
+
+	assert.InDelta(t, 10*time.Second, call(context.Background(), "/movie.MovieService/CreateMovie"), float64(time.Second))
+	assert.InDelta(t, time.Second, call(context.Background(), "/movie.MovieService/ListMovies"), float64(100*time.Millisecond))
+	assert.Zero(t, call(context.Background(), "/movie.MovieService/GetMovie"), "a zero timeout sets no deadline")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.LessOrEqual(t, call(ctx, "/movie.MovieService/CreateMovie"), 100*time.Millisecond, "earlier deadlines of the caller are kept")
+}
+
+func TestUnaryServerInterceptor_DeadlineExceeded(t *testing.T) {
+	interceptor := UnaryServerInterceptor(10*time.Millisecond, nil)
+	info := &grpc.UnaryServerInfo{FullMethod: "/movie.MovieService/ListMovies"}
+
+	_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		<-ctx.Done()
+		return nil, status.Errorf(codes.Internal, "Failed to list movies: %v", ctx.Err())
+	})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+
+	_, err = interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	assert.Equal(t, codes.Unknown, status.Code(err), "errors within the deadline are kept")
+}
@@ -0,0 +1,174 @@
+// Package health reports liveness and readiness over grpc.health.v1 and plain HTTP endpoints.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Check returns an error when a dependency is not usable
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name     string
+	check    Check
+	services []string // empty: every service depends on the check
+}
+
+// Checker runs readiness checks and publishes their outcome as per-service gRPC health status.
+// The overall status (service "") is SERVING only when every check passes.
+type Checker struct {
+	grpc     *grpchealth.Server
+	services []string
+	timeout  time.Duration
+
+	mu           sync.Mutex
+	checks       []namedCheck
+	results      map[string]error // of the last Update, nil before the first one
+	shuttingDown atomic.Bool
+}
+
+// NewChecker creates a checker for the given fully qualified gRPC service names.
+// Every check is given at most timeout to complete.
+func NewChecker(timeout time.Duration, services ...string) *Checker {
+	c := &Checker{grpc: grpchealth.NewServer(), services: services, timeout: timeout}
+	for _, service := range services {
+		c.grpc.SetServingStatus(service, healthpb.HealthCheckResponse_SERVING)
+	}
+	return c
+}
+
+// AddCheck registers a readiness check. Failing checks mark the given services NOT_SERVING,
+// or all of them when none are given.
+func (c *Checker) AddCheck(name string, check Check, services ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, namedCheck{name: name, check: check, services: services})
+}
+
+// Register exposes the grpc.health.v1 service on s
+func (c *Checker) Register(s *grpc.Server) {
+	healthpb.RegisterHealthServer(s, c.grpc)
+}
+
+// Update runs all checks, publishes the resulting gRPC status and returns the error of each check by name
+func (c *Checker) Update(ctx context.Context) map[string]error {
+	c.mu.Lock()
+	checks := c.checks
+	c.mu.Unlock()
+
+	results := make(map[string]error, len(checks))
+	failing := make(map[string]bool, len(c.services))
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		err := check.check(checkCtx)
+		cancel()
+
+		results[check.name] = err
+		if err == nil {
+			continue
+		}
+		failing[""] = true
+		services := check.services
+		if len(services) == 0 {
+			services = c.services
+		}
+		for _, service := range services {
+			failing[service] = true
+		}
+	}
+
+	c.mu.Lock()
+	c.results = results
+	c.mu.Unlock()
+
+	// After Shutdown the gRPC health server ignores updates and keeps reporting NOT_SERVING
+	for _, service := range append([]string{""}, c.services...) {
+		status := healthpb.HealthCheckResponse_SERVING
+		if failing[service] {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		c.grpc.SetServingStatus(service, status)
+	}
+	return results
+}
+
+// Run updates the status every interval until ctx is done
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	c.Update(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Update(ctx)
+		}
+	}
+}
+
+// Shutdown marks every service NOT_SERVING for good, so load balancers stop sending traffic
+// while in-flight requests drain
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+	c.grpc.Shutdown()
+}
+
+type response struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// LiveHandler answers liveness probes. It succeeds as long as the process can serve HTTP.
+func (c *Checker) LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, response{Status: "ok"})
+	})
+}
+
+// ReadyHandler answers readiness probes with 200 when every check passed on the last Update and
+// 503 otherwise. Probes don't run the checks themselves, so they can't overload the dependencies.
+func (c *Checker) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.shuttingDown.Load() {
+			writeJSON(w, http.StatusServiceUnavailable, response{Status: "shutting down"})
+			return
+		}
+
+		c.mu.Lock()
+		results := c.results
+		c.mu.Unlock()
+		if results == nil {
+			writeJSON(w, http.StatusServiceUnavailable, response{Status: "starting"})
+			return
+		}
+
+		resp := response{Status: "ok", Checks: map[string]string{}}
+		code := http.StatusOK
+		for name, err := range results {
+			if err != nil {
+				resp.Status, code = "unavailable", http.StatusServiceUnavailable
+				resp.Checks[name] = err.Error()
+				continue
+			}
+			resp.Checks[name] = "ok"
+		}
+		writeJSON(w, code, resp)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, resp response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
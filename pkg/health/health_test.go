@@ -0,0 +1,109 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newHealthClient(t *testing.T, c *Checker) healthpb.HealthClient {
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	c.Register(s)
+	go s.Serve(lis)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+		s.Stop()
+	})
+	return healthpb.NewHealthClient(conn)
+}
+
+func grpcStatus(t *testing.T, client healthpb.HealthClient, service string) healthpb.HealthCheckResponse_ServingStatus {
+	resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	require.NoError(t, err)
+	return resp.Status
+}
+
+func ready(t *testing.T, c *Checker) (int, response) {
+	rec := httptest.NewRecorder()
+	c.ReadyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var resp response
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return rec.Code, resp
+}
+
+func TestChecker(t *testing.T) {
+	c := NewChecker(time.Second, "movie.MovieService", "media.MediaService")
+	var dbErr error
+	c.AddCheck("database", func(ctx context.Context) error { return dbErr }, "movie.MovieService")
+	client := newHealthClient(t, c)
+
+	code, resp := ready(t, c)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "starting", resp.Status, "not ready before the first update")
+
+	c.Update(context.Background())
+	code, resp = ready(t, c)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, response{Status: "ok", Checks: map[string]string{"database": "ok"}}, resp)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, grpcStatus(t, client, ""))
+
+	dbErr = errors.New("connection refused")
+	code, _ = ready(t, c)
+	assert.Equal(t, http.StatusOK, code, "probes serve the result of the last update")
+	c.Update(context.Background())
+	code, resp = ready(t, c)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "connection refused", resp.Checks["database"])
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, grpcStatus(t, client, ""))
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, grpcStatus(t, client, "movie.MovieService"))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, grpcStatus(t, client, "media.MediaService"), "only dependent services fail")
+
+	dbErr = nil
+	c.Update(context.Background())
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, grpcStatus(t, client, "movie.MovieService"))
+
+	c.Shutdown()
+	code, resp = ready(t, c)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "shutting down", resp.Status)
+	c.Update(context.Background())
+	for _, service := range []string{"", "movie.MovieService", "media.MediaService"} {
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, grpcStatus(t, client, service), "status stays down after shutdown")
+	}
+
+	rec := httptest.NewRecorder()
+	c.LiveHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code, "liveness is unaffected by shutdown")
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	c := NewChecker(10*time.Millisecond, "movie.MovieService")
+	c.AddCheck("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	results := c.Update(context.Background())
+	assert.ErrorIs(t, results["slow"], context.DeadlineExceeded)
+}
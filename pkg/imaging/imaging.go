@@ -0,0 +1,117 @@
+// Package imaging decodes uploaded images and scales them down into thumbnails, using only
+// the image formats of the standard library.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"math"
+)
+
+// Extensions maps the supported content types to the file extension of their images
+var Extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// MaxPixels bounds the size of decoded images, rejecting images that are small files but
+// would take gigabytes of memory once decoded
+const MaxPixels = 50_000_000
+
+// ErrTooManyPixels is returned for images larger than MaxPixels
+var ErrTooManyPixels = errors.New("image has too many pixels")
+
+// Decode decodes a JPEG or PNG image, checking its dimensions before decoding the pixels
+func Decode(data []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, fmt.Errorf("image is empty: %dx%d", cfg.Width, cfg.Height)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrTooManyPixels, cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// Encode encodes img in the format of contentType
+func Encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	case "image/png":
+		err = png.Encode(&buf, img)
+	default:
+		return nil, fmt.Errorf("unsupported content type %q", contentType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Fit scales img down to fit within maxWidth x maxHeight, keeping its aspect ratio. Images
+// that already fit are returned as they are. Every pixel of the result is the average of the
+// pixels of img it covers, which keeps thumbnails smooth where sampling would alias.
+func Fit(img image.Image, maxWidth, maxHeight int) image.Image {
+	bounds := img.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	if srcWidth <= maxWidth && srcHeight <= maxHeight {
+		return img
+	}
+	scale := math.Min(float64(maxWidth)/float64(srcWidth), float64(maxHeight)/float64(srcHeight))
+	width := max(1, int(math.Round(float64(srcWidth)*scale)))
+	height := max(1, int(math.Round(float64(srcHeight)*scale)))
+
+	// columns[x] is the column of the result source column x falls into
+	columns := make([]int, srcWidth)
+	for x := range columns {
+		columns[x] = x * width / srcWidth
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	sums := make([]uint64, width*4)
+	counts := make([]uint64, width)
+	flush := func(y int) {
+		for x, n := range counts {
+			if n == 0 {
+				continue
+			}
+			offset := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				dst.Pix[offset+c] = uint8(sums[x*4+c] / n >> 8)
+				sums[x*4+c] = 0
+			}
+			counts[x] = 0
+		}
+	}
+
+	row := 0
+	for sy := 0; sy < srcHeight; sy++ {
+		if y := sy * height / srcHeight; y != row {
+			flush(row)
+			row = y
+		}
+		for sx := 0; sx < srcWidth; sx++ {
+			// premultiplied 16-bit values, so transparent pixels do not darken their neighbours
+			r, g, b, a := img.At(bounds.Min.X+sx, bounds.Min.Y+sy).RGBA()
+			x := columns[sx]
+			sums[x*4] += uint64(r)
+			sums[x*4+1] += uint64(g)
+			sums[x*4+2] += uint64(b)
+			sums[x*4+3] += uint64(a)
+			counts[x]++
+		}
+	}
+	flush(row)
+	return dst
+}
@@ -0,0 +1,71 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stripes returns an image of vertical black and white stripes, one pixel wide
+func stripes(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x%2 == 0 {
+				img.Set(x, y, color.White)
+			} else {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+	return img
+}
+
+func TestFit(t *testing.T) {
+	for _, tt := range []struct {
+		width, height int
+		want          image.Point
+	}{
+		{1000, 1500, image.Pt(200, 300)},
+		{1920, 1080, image.Pt(300, 169)},
+		{300, 300, image.Pt(300, 300)},
+		{120, 80, image.Pt(120, 80)},
+		{3000, 2, image.Pt(300, 1)},
+	} {
+		got := Fit(stripes(tt.width, tt.height), 300, 300)
+		assert.Equal(t, tt.want, got.Bounds().Size(), "%dx%d", tt.width, tt.height)
+	}
+
+	small := stripes(10, 10)
+	assert.Same(t, small, Fit(small, 300, 300), "images that fit are not copied")
+
+	// stripes average to gray rather than aliasing to black or white
+	thumb := Fit(stripes(400, 400), 100, 100)
+	r, g, b, a := thumb.At(50, 50).RGBA()
+	assert.InDelta(t, 0x7fff, r, 0x200)
+	assert.Equal(t, r, g)
+	assert.Equal(t, r, b)
+	assert.EqualValues(t, 0xffff, a)
+
+	offset := stripes(20, 20).SubImage(image.Rect(10, 10, 20, 20))
+	assert.Equal(t, image.Pt(5, 5), Fit(offset, 5, 5).Bounds().Size())
+}
+
+func TestDecodeEncode(t *testing.T) {
+	img := stripes(40, 20)
+	for _, contentType := range []string{"image/jpeg", "image/png"} {
+		data, err := Encode(img, contentType)
+		require.NoError(t, err)
+		decoded, err := Decode(data)
+		require.NoError(t, err, contentType)
+		assert.Equal(t, img.Bounds(), decoded.Bounds(), contentType)
+	}
+
+	_, err := Encode(img, "image/gif")
+	assert.Error(t, err)
+	_, err = Decode([]byte("not an image"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,63 @@
+// Package inprocess provides a net.Listener whose connections never leave the process, for
+// clients such as the REST gateway that call a gRPC server running alongside them.
+package inprocess
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// Listener hands out the server ends of in-memory connections created by DialContext
+type Listener struct {
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+// Listen returns a listener that accepts connections until it is closed
+func Listen() *Listener {
+	return &Listener{conns: make(chan net.Conn), done: make(chan struct{})}
+}
+
+// Accept waits for the next connection
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stops accepting connections. Connections already accepted stay open.
+func (l *Listener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *Listener) Addr() net.Addr {
+	return addr{}
+}
+
+// DialContext connects to the listener, waiting until the connection is accepted or ctx is done
+func (l *Listener) DialContext(ctx context.Context) (net.Conn, error) {
+	server, client := net.Pipe()
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.done:
+		server.Close()
+		client.Close()
+		return nil, net.ErrClosed
+	case <-ctx.Done():
+		server.Close()
+		client.Close()
+		return nil, ctx.Err()
+	}
+}
+
+type addr struct{}
+
+func (addr) Network() string { return "inprocess" }
+func (addr) String() string  { return "inprocess" }
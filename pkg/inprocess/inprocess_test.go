@@ -0,0 +1,76 @@
+package inprocess
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestListener_ServesGRPC(t *testing.T) {
+	lis := Listen()
+	s := grpc.NewServer()
+	healthpb.RegisterHealthServer(s, grpchealth.NewServer())
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///inprocess",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+}
+
+func TestListener_Close(t *testing.T) {
+	lis := Listen()
+	require.NoError(t, lis.Close())
+	require.NoError(t, lis.Close(), "closing twice is harmless")
+
+	_, err := lis.Accept()
+	assert.ErrorIs(t, err, net.ErrClosed)
+	_, err = lis.DialContext(context.Background())
+	assert.ErrorIs(t, err, net.ErrClosed)
+}
+
+func TestListener_DialWaitsForAccept(t *testing.T) {
+	lis := Listen()
+	defer lis.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := lis.DialContext(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "nobody accepts")
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+	client, err := lis.DialContext(context.Background())
+	require.NoError(t, err)
+	defer client.Close()
+	server := <-accepted
+	defer server.Close()
+
+	go client.Write([]byte("ping"))
+	buf := make([]byte, 4)
+	_, err = server.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+}
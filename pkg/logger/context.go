@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"movie-project/pkg/auth"
+)
+
+type attrsKey struct{}
+
+// ContextWith returns a copy of ctx carrying args, as key-value pairs or slog.Attr values,
+// that are added to every record logged with the returned context
+func ContextWith(ctx context.Context, args ...any) context.Context {
+	parent := attrsFromContext(ctx)
+	attrs := make([]slog.Attr, len(parent), len(parent)+len(args)/2)
+	copy(attrs, parent)
+	record := slog.Record{}
+	record.Add(args...)
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	return context.WithValue(ctx, attrsKey{}, attrs)
+}
+
+func attrsFromContext(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// contextHandler adds request scoped fields to every record: attributes stored with ContextWith
+// (request id, method), the authenticated user and the ids of the current trace span
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	r.AddAttrs(attrsFromContext(ctx)...)
+	if subject := auth.SubjectFromContext(ctx); subject != "" {
+		r.AddAttrs(slog.String("user_id", subject))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
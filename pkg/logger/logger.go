@@ -3,59 +3,169 @@ package logger
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Supported values of Options.Format
+const (
+	FormatJSON = "json"
+	FormatText = "text"
 )
 
+// Options configures NewLogger. The zero value logs JSON at info level to stdout.
+type Options struct {
+	Level  string // debug, info, warn or error
+	Format string // json or text
+	// Output is stdout, stderr or a file path. Files are rotated by size.
+	Output     string
+	MaxSizeMB  int
+	MaxBackups int
+	MaxAgeDays int
+	Compress   bool
+	AddSource  bool
+	// Debug records with the same message are sampled per SampleInterval: the first SampleInitial
+	// are logged, then every SampleThereafter-th. Sampling is off when SampleInitial is 0.
+	SampleInitial    int
+	SampleThereafter int
+	SampleInterval   time.Duration
+}
+
 type Logger struct {
 	*slog.Logger
+	level  *slog.LevelVar
+	closer io.Closer
 }
 
-func NewLogger() *Logger {
-	opts := &slog.HandlerOptions{
-		Level:     slog.LevelInfo,
-		AddSource: true,
+func NewLogger(opts Options) (*Logger, error) {
+	level := new(slog.LevelVar)
+	if opts.Level != "" {
+		if err := level.UnmarshalText([]byte(opts.Level)); err != nil {
+			return nil, fmt.Errorf("invalid log level %q", opts.Level)
+		}
+	}
+
+	var out io.Writer
+	var closer io.Closer
+	switch opts.Output {
+	case "", "stdout":
+		out = os.Stdout
+	case "stderr":
+		out = os.Stderr
+	default:
+		file := &lumberjack.Logger{
+			Filename:   opts.Output,
+			MaxSize:    opts.MaxSizeMB,
+			MaxBackups: opts.MaxBackups,
+			MaxAge:     opts.MaxAgeDays,
+			Compress:   opts.Compress,
+		}
+		out, closer = file, file
 	}
-	handler := slog.NewJSONHandler(os.Stdout, opts)
-	logger := slog.New(handler)
 
-	return &Logger{logger}
+	handlerOpts := &slog.HandlerOptions{
+		Level:     level,
+		AddSource: opts.AddSource,
+	}
+	var handler slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "", FormatJSON:
+		handler = slog.NewJSONHandler(out, handlerOpts)
+	case FormatText:
+		handler = slog.NewTextHandler(out, handlerOpts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q", opts.Format)
+	}
+
+	handler = contextHandler{handler}
+	if opts.SampleInitial > 0 {
+		handler = newSamplingHandler(handler, opts.SampleInitial, opts.SampleThereafter, opts.SampleInterval)
+	}
+
+	return &Logger{Logger: slog.New(handler), level: level, closer: closer}, nil
+}
+
+// Discard returns a logger that drops everything, for tests
+func Discard() *Logger {
+	return &Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil)), level: new(slog.LevelVar)}
+}
+
+// Level returns the current minimum level
+func (l *Logger) Level() slog.Level {
+	return l.level.Level()
+}
+
+// SetLevel changes the minimum level of this logger and every logger derived from it
+func (l *Logger) SetLevel(level string) error {
+	var parsed slog.Level
+	if err := parsed.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+	l.level.Set(parsed)
+	return nil
+}
+
+// Close closes the log file, if logging to one
+func (l *Logger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
 }
 
 func (l *Logger) WithFields(fields map[string]any) *Logger {
-	return &Logger{l.Logger.With(fieldsToAttrs(fields)...)}
+	return &Logger{Logger: l.Logger.With(fieldsToAttrs(fields)...), level: l.level, closer: l.closer}
 }
 
 func (l *Logger) Info(msg string, args ...any) {
-	l.Logger.Info(msg, args...)
+	l.log(context.Background(), slog.LevelInfo, msg, args...)
 }
 
 func (l *Logger) Error(msg string, args ...any) {
-	l.Logger.Error(msg, args...)
+	l.log(context.Background(), slog.LevelError, msg, args...)
 }
 
 func (l *Logger) Debug(msg string, args ...any) {
-	l.Logger.Debug(msg, args...)
+	l.log(context.Background(), slog.LevelDebug, msg, args...)
 }
 
 func (l *Logger) Warn(msg string, args ...any) {
-	l.Logger.Warn(msg, args...)
+	l.log(context.Background(), slog.LevelWarn, msg, args...)
 }
 
 func (l *Logger) InfoContext(ctx context.Context, msg string, args ...any) {
-	l.Logger.InfoContext(ctx, msg, args...)
+	l.log(ctx, slog.LevelInfo, msg, args...)
 }
 
 func (l *Logger) ErrorContext(ctx context.Context, msg string, args ...any) {
-	l.Logger.ErrorContext(ctx, msg, args...)
+	l.log(ctx, slog.LevelError, msg, args...)
 }
 
 func (l *Logger) DebugContext(ctx context.Context, msg string, args ...any) {
-	l.Logger.DebugContext(ctx, msg, args...)
+	l.log(ctx, slog.LevelDebug, msg, args...)
 }
 
 func (l *Logger) WarnContext(ctx context.Context, msg string, args ...any) {
-	l.Logger.WarnContext(ctx, msg, args...)
+	l.log(ctx, slog.LevelWarn, msg, args...)
+}
+
+// log reports the caller of the exported method as the source instead of this package
+func (l *Logger) log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	if !l.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // skip runtime.Callers, log and the exported method
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.Add(args...)
+	_ = l.Handler().Handle(ctx, r)
 }
 
 func fieldsToAttrs(fields map[string]any) []any {
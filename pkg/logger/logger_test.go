@@ -0,0 +1,170 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"movie-project/pkg/auth"
+)
+
+func newTestLogger(buf *bytes.Buffer) *Logger {
+	level := new(slog.LevelVar)
+	return &Logger{Logger: slog.New(contextHandler{slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: level})}), level: level}
+}
+
+func decode(t *testing.T, buf *bytes.Buffer) map[string]any {
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	buf.Reset()
+	return record
+}
+
+func TestContextHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := newTestLogger(&buf)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+	ctx = ContextWith(ctx, "request_id", "req-1", slog.String("method", "/movie.MovieService/GetMovie"))
+	ctx = auth.ContextWithSubject(ctx, "alice")
+
+	log.WithFields(map[string]any{"component": "test"}).InfoContext(ctx, "traced")
+	record := decode(t, &buf)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", record["trace_id"])
+	assert.Equal(t, "00f067aa0ba902b7", record["span_id"])
+	assert.Equal(t, "req-1", record["request_id"])
+	assert.Equal(t, "/movie.MovieService/GetMovie", record["method"])
+	assert.Equal(t, "alice", record["user_id"])
+	assert.Equal(t, "test", record["component"])
+
+	log.InfoContext(context.Background(), "plain")
+	record = decode(t, &buf)
+	for _, key := range []string{"trace_id", "request_id", "method", "user_id"} {
+		assert.NotContains(t, record, key)
+	}
+}
+
+func TestContextWith_DoesNotShareAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	log := newTestLogger(&buf)
+
+	parent := ContextWith(context.Background(), "request_id", "req-1")
+	first := ContextWith(parent, "step", "first")
+	second := ContextWith(parent, "step", "second")
+
+	log.InfoContext(first, "msg")
+	assert.Equal(t, "first", decode(t, &buf)["step"])
+	log.InfoContext(second, "msg")
+	assert.Equal(t, "second", decode(t, &buf)["step"])
+	log.InfoContext(parent, "msg")
+	assert.NotContains(t, decode(t, &buf), "step")
+}
+
+func TestNewLogger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	log, err := NewLogger(Options{Level: "warn", Format: FormatText, Output: path, MaxSizeMB: 1, AddSource: true})
+	require.NoError(t, err)
+
+	log.Info("hidden")
+	log.Warn("shown", "id", 7)
+	require.NoError(t, log.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "hidden")
+	assert.Contains(t, string(content), "level=WARN")
+	assert.Contains(t, string(content), "msg=shown id=7")
+	assert.Contains(t, string(content), "logger_test.go", "source is the caller, not the logger package")
+
+	_, err = NewLogger(Options{Level: "verbose"})
+	assert.Error(t, err)
+	_, err = NewLogger(Options{Format: "xml"})
+	assert.Error(t, err)
+}
+
+func TestLevelHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := newTestLogger(&buf)
+	derived := log.WithFields(map[string]any{"component": "repository"})
+	handler := log.LevelHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/log/level", nil))
+	assert.JSONEq(t, `{"level":"info"}`, rec.Body.String())
+
+	derived.Debug("before")
+	assert.Empty(t, buf.String())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/log/level", strings.NewReader(`{"level":"debug"}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"level":"debug"}`, rec.Body.String())
+	buf.Reset()
+
+	derived.Debug("after")
+	assert.Equal(t, "after", decode(t, &buf)["msg"], "derived loggers follow the new level")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/log/level", strings.NewReader(`{"level":"loud"}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, slog.LevelDebug, log.Level())
+}
+
+func TestSamplingHandler(t *testing.T) {
+	var buf bytes.Buffer
+	handler := newSamplingHandler(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}), 2, 3, time.Second)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	handler.sampler.now = func() time.Time { return now }
+	log := slog.New(handler)
+
+	var logged []map[string]any
+	flush := func() {
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var record map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &record))
+			logged = append(logged, record)
+		}
+		buf.Reset()
+	}
+
+	for i := 1; i <= 8; i++ {
+		log.Debug("cache miss", "i", i)
+	}
+	log.Info("cache miss", "i", 100)
+	flush()
+
+	// 1 and 2 pass, then every third: 5 and 8; info is never sampled
+	require.Len(t, logged, 5)
+	assert.EqualValues(t, 1, logged[0]["i"])
+	assert.EqualValues(t, 2, logged[1]["i"])
+	assert.EqualValues(t, 5, logged[2]["i"])
+	assert.EqualValues(t, 2, logged[2]["dropped"])
+	assert.EqualValues(t, 8, logged[3]["i"])
+	assert.EqualValues(t, 100, logged[4]["i"])
+
+	now = now.Add(time.Second)
+	logged = nil
+	log.Debug("cache miss", "i", 9)
+	flush()
+	require.Len(t, logged, 1, "counts reset every interval")
+}
@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// samplingHandler thins out repeated debug records. Within each interval the first initial
+// records with a given message are logged, then every thereafter-th (none when it is 0).
+// A logged record carries the number of records with its message dropped before it.
+// Info and above are never sampled.
+type samplingHandler struct {
+	slog.Handler
+	sampler *sampler
+}
+
+type sampler struct {
+	initial    int
+	thereafter int
+	interval   time.Duration
+	now        func() time.Time
+
+	mu          sync.Mutex
+	windowStart time.Time
+	counts      map[string]*sampleCount
+}
+
+type sampleCount struct {
+	seen    int
+	dropped int
+}
+
+func newSamplingHandler(next slog.Handler, initial, thereafter int, interval time.Duration) samplingHandler {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return samplingHandler{Handler: next, sampler: &sampler{
+		initial:    initial,
+		thereafter: thereafter,
+		interval:   interval,
+		now:        time.Now,
+		counts:     map[string]*sampleCount{},
+	}}
+}
+
+// sample reports whether to log a record with msg and how many were dropped since the last one
+func (s *sampler) sample(msg string) (bool, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := s.now(); now.Sub(s.windowStart) >= s.interval {
+		s.windowStart = now
+		clear(s.counts)
+	}
+	count, ok := s.counts[msg]
+	if !ok {
+		count = &sampleCount{}
+		s.counts[msg] = count
+	}
+	count.seen++
+
+	keep := count.seen <= s.initial || (s.thereafter > 0 && (count.seen-s.initial)%s.thereafter == 0)
+	if !keep {
+		count.dropped++
+		return false, 0
+	}
+	dropped := count.dropped
+	count.dropped = 0
+	return true, dropped
+}
+
+func (h samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelInfo {
+		return h.Handler.Handle(ctx, r)
+	}
+	keep, dropped := h.sampler.sample(r.Message)
+	if !keep {
+		return nil
+	}
+	if dropped > 0 {
+		r.AddAttrs(slog.Int("dropped", dropped))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return samplingHandler{Handler: h.Handler.WithAttrs(attrs), sampler: h.sampler}
+}
+
+func (h samplingHandler) WithGroup(name string) slog.Handler {
+	return samplingHandler{Handler: h.Handler.WithGroup(name), sampler: h.sampler}
+}
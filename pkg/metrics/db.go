@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// RegisterDBStats exports the connection pool statistics of db (open, in use and idle
+// connections, waits and closed connections) labeled with the database name
+func RegisterDBStats(db *sql.DB, name string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveQuery records the duration of a repository operation started at start, typically deferred:
+//
+//	defer metrics.ObserveQuery("GetByID", time.Now())
+func ObserveQuery(operation string, start time.Time) {
+	DBQueryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// genres are the labels of MoviesByGenre set by SetMoviesByGenre
+var (
+	genresMu sync.Mutex
+	genres   = map[string]bool{}
+)
+
+// SetMoviesByGenre replaces the per-genre movie counts, dropping genres that no longer have movies.
+// Only those are deleted, so a scrape never sees the gauge without the other genres.
+func SetMoviesByGenre(counts map[string]int64) {
+	genresMu.Lock()
+	defer genresMu.Unlock()
+	for genre := range genres {
+		if _, ok := counts[genre]; !ok {
+			MoviesByGenre.DeleteLabelValues(genre)
+			delete(genres, genre)
+		}
+	}
+	for genre, count := range counts {
+		MoviesByGenre.WithLabelValues(genre).Set(float64(count))
+		genres[genre] = true
+	}
+}
@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// Values of the grpc_type label
+const (
+	Unary        = "unary"
+	ClientStream = "client_stream"
+	ServerStream = "server_stream"
+	BidiStream   = "bidi_stream"
+)
+
+// serverMetrics are the metrics of the RPCs handled by a gRPC server
+type serverMetrics struct {
+	started         *prometheus.CounterVec
+	handled         *prometheus.CounterVec
+	handlingSeconds *prometheus.HistogramVec
+	msgReceived     *prometheus.CounterVec
+	msgSent         *prometheus.CounterVec
+}
+
+func newServerMetrics(reg prometheus.Registerer) *serverMetrics {
+	labels := []string{"grpc_type", "grpc_service", "grpc_method"}
+	factory := promauto.With(reg)
+	return &serverMetrics{
+		started: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_server_started_total",
+			Help: "Total number of RPCs started on the server",
+		}, labels),
+		handled: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_server_handled_total",
+			Help: "Total number of RPCs completed on the server, by status code",
+		}, append(labels, "grpc_code")),
+		handlingSeconds: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "grpc_server_handling_seconds",
+			Help:    "Duration of RPCs handled by the server in seconds",
+			Buckets: prometheus.DefBuckets,
+		}, labels),
+		msgReceived: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_server_msg_received_total",
+			Help: "Total number of stream messages received from clients",
+		}, labels),
+		msgSent: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_server_msg_sent_total",
+			Help: "Total number of stream messages sent to clients",
+		}, labels),
+	}
+}
+
+// grpcServer is registered with the default registry and used by the exported interceptors
+var grpcServer = newServerMetrics(prometheus.DefaultRegisterer)
+
+// UnaryServerInterceptor counts started and handled calls by status code and observes their duration
+func UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return grpcServer.unaryServerInterceptor(ctx, req, info, handler)
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor, also counting messages
+func StreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return grpcServer.streamServerInterceptor(srv, ss, info, handler)
+}
+
+func (m *serverMetrics) unaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	service, method := splitMethod(info.FullMethod)
+	m.started.WithLabelValues(Unary, service, method).Inc()
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	m.observeHandled(Unary, service, method, err, time.Since(start))
+	return resp, err
+}
+
+func (m *serverMetrics) streamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	typ := streamType(info)
+	service, method := splitMethod(info.FullMethod)
+	m.started.WithLabelValues(typ, service, method).Inc()
+	start := time.Now()
+	err := handler(srv, &monitoredStream{
+		ServerStream: ss,
+		received:     m.msgReceived.WithLabelValues(typ, service, method),
+		sent:         m.msgSent.WithLabelValues(typ, service, method),
+	})
+	m.observeHandled(typ, service, method, err, time.Since(start))
+	return err
+}
+
+func (m *serverMetrics) observeHandled(typ, service, method string, err error, duration time.Duration) {
+	m.handled.WithLabelValues(typ, service, method, status.Code(err).String()).Inc()
+	m.handlingSeconds.WithLabelValues(typ, service, method).Observe(duration.Seconds())
+}
+
+// splitMethod splits "/movie.MovieService/GetMovie" into "movie.MovieService" and "GetMovie"
+func splitMethod(fullMethod string) (string, string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "unknown", "unknown"
+	}
+	return service, method
+}
+
+func streamType(info *grpc.StreamServerInfo) string {
+	switch {
+	case info.IsClientStream && info.IsServerStream:
+		return BidiStream
+	case info.IsClientStream:
+		return ClientStream
+	default:
+		return ServerStream
+	}
+}
+
+type monitoredStream struct {
+	grpc.ServerStream
+	received, sent prometheus.Counter
+}
+
+func (s *monitoredStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received.Inc()
+	}
+	return err
+}
+
+func (s *monitoredStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent.Inc()
+	}
+	return err
+}
@@ -2,12 +2,8 @@
 package metrics
 
 import (
-	"context"
-	"time"
-
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
-	"google.golang.org/grpc"
 )
 
 var (
@@ -46,18 +42,34 @@ var (
 		},
 		[]string{"handler", "code", "method"},
 	)
+
+	DBQueryDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Duration of repository queries in seconds",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		},
+		[]string{"operation"},
+	)
+
+	MoviesByGenre = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "movies",
+			Help: "Number of stored movies by genre, refreshed periodically",
+		},
+		[]string{"genre"},
+	)
+
+	ThrottledRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "rate_limited_requests_total",
+			Help: "Total number of requests rejected by the rate limiter",
+		},
+		[]string{"transport", "method"},
+	)
 )
 
 // InitMetrics initializes the metrics
 func InitMetrics() {
 	// Metrics are automatically registered via promauto
 }
-
-// UnaryServerInterceptor is a gRPC interceptor for Prometheus metrics
-func UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	start := time.Now()
-	resp, err := handler(ctx, req)
-	duration := time.Since(start).Seconds()
-	RequestDuration.WithLabelValues("grpc", info.FullMethod).Observe(duration)
-	return resp, err
-}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := newServerMetrics(reg)
+	info := &grpc.UnaryServerInfo{FullMethod: "/movie.MovieService/GetMovie"}
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return "movie", nil }
+	notFound := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "no such movie")
+	}
+
+	_, err := m.unaryServerInterceptor(context.Background(), nil, info, ok)
+	require.NoError(t, err)
+	_, err = m.unaryServerInterceptor(context.Background(), nil, info, notFound)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.handled.WithLabelValues(Unary, "movie.MovieService", "GetMovie", "NotFound")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.handled.WithLabelValues(Unary, "movie.MovieService", "GetMovie", "OK")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.started.WithLabelValues(Unary, "movie.MovieService", "GetMovie")))
+	count, err := testutil.GatherAndCount(reg, "grpc_server_handling_seconds")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+type fakeStream struct {
+	grpc.ServerStream
+	recv int
+}
+
+func (s *fakeStream) Context() context.Context { return context.Background() }
+
+func (s *fakeStream) RecvMsg(m interface{}) error {
+	if s.recv == 0 {
+		return status.Error(codes.Canceled, "done")
+	}
+	s.recv--
+	return nil
+}
+
+func (s *fakeStream) SendMsg(m interface{}) error { return nil }
+
+func TestStreamServerInterceptor(t *testing.T) {
+	info := &grpc.StreamServerInfo{FullMethod: "/movie.MovieService/Import", IsClientStream: true}
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		for ss.RecvMsg(nil) == nil {
+		}
+		return ss.SendMsg(nil)
+	}
+
+	m := newServerMetrics(prometheus.NewRegistry())
+	require.NoError(t, m.streamServerInterceptor(nil, &fakeStream{recv: 3}, info, handler))
+
+	assert.Equal(t, 3.0, testutil.ToFloat64(m.msgReceived.WithLabelValues(ClientStream, "movie.MovieService", "Import")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.msgSent.WithLabelValues(ClientStream, "movie.MovieService", "Import")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.handled.WithLabelValues(ClientStream, "movie.MovieService", "Import", "OK")))
+}
+
+func TestSplitMethod(t *testing.T) {
+	service, method := splitMethod("/movie.MovieService/ListMovies")
+	assert.Equal(t, "movie.MovieService", service)
+	assert.Equal(t, "ListMovies", method)
+
+	service, method = splitMethod("garbage")
+	assert.Equal(t, "unknown", service)
+	assert.Equal(t, "unknown", method)
+}
+
+func TestSetMoviesByGenre(t *testing.T) {
+	SetMoviesByGenre(map[string]int64{"Drama": 3, "Comedy": 1})
+	assert.Equal(t, 3.0, testutil.ToFloat64(MoviesByGenre.WithLabelValues("Drama")))
+
+	SetMoviesByGenre(map[string]int64{"Drama": 2})
+	assert.Equal(t, 1, testutil.CollectAndCount(MoviesByGenre), "genres without movies are dropped")
+	assert.Equal(t, 2.0, testutil.ToFloat64(MoviesByGenre.WithLabelValues("Drama")))
+
+	SetMoviesByGenre(nil)
+	assert.Zero(t, testutil.CollectAndCount(MoviesByGenre))
+}
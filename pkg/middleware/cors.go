@@ -0,0 +1,219 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// CORSOptions configures the CORS middleware
+type CORSOptions struct {
+	// AllowedOrigins holds exact origins ("https://app.example.com"), subdomain wildcards
+	// ("https://*.example.com", or "*.example.com" for any scheme) or "*" for any origin.
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	ExposedHeaders []string
+	// AllowCredentials is ignored together with "*": browsers reject credentials for any origin,
+	// and echoing the origin instead would hand them to every site
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+type originPattern struct {
+	scheme string // empty matches any scheme
+	host   string // lower-case host[:port], without the "*." prefix for wildcards
+	suffix bool
+}
+
+func parseOriginPattern(pattern string) originPattern {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	var p originPattern
+	if scheme, host, ok := strings.Cut(pattern, "://"); ok {
+		p.scheme, pattern = scheme, host
+	}
+	if strings.HasPrefix(pattern, "*.") {
+		p.suffix, pattern = true, pattern[1:]
+	}
+	p.host = strings.TrimSuffix(pattern, "/")
+	return p
+}
+
+func (p originPattern) matches(scheme, host string) bool {
+	if p.scheme != "" && p.scheme != scheme {
+		return false
+	}
+	if p.suffix {
+		return len(host) > len(p.host) && strings.HasSuffix(host, p.host)
+	}
+	return host == p.host
+}
+
+// corsPolicy is CORSOptions prepared for matching requests
+type corsPolicy struct {
+	allowAny         bool
+	patterns         []originPattern
+	methods          map[string]bool
+	headers          map[string]bool
+	allowedMethods   string
+	allowedHeaders   string
+	exposedHeaders   string
+	allowCredentials bool
+	maxAge           string
+}
+
+func newCORSPolicy(opts CORSOptions) *corsPolicy {
+	p := &corsPolicy{
+		patterns:         make([]originPattern, 0, len(opts.AllowedOrigins)),
+		methods:          make(map[string]bool, len(opts.AllowedMethods)),
+		headers:          make(map[string]bool, len(opts.AllowedHeaders)),
+		allowedMethods:   strings.Join(opts.AllowedMethods, ", "),
+		allowedHeaders:   strings.Join(opts.AllowedHeaders, ", "),
+		exposedHeaders:   strings.Join(opts.ExposedHeaders, ", "),
+		allowCredentials: opts.AllowCredentials,
+	}
+	for _, origin := range opts.AllowedOrigins {
+		if strings.TrimSpace(origin) == "*" {
+			p.allowAny = true
+			continue
+		}
+		p.patterns = append(p.patterns, parseOriginPattern(origin))
+	}
+	if p.allowAny {
+		p.allowCredentials = false
+	}
+	for _, method := range opts.AllowedMethods {
+		p.methods[strings.ToUpper(strings.TrimSpace(method))] = true
+	}
+	for _, header := range opts.AllowedHeaders {
+		p.headers[http.CanonicalHeaderKey(strings.TrimSpace(header))] = true
+	}
+	if opts.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(opts.MaxAge.Seconds()))
+	}
+	return p
+}
+
+func (p *corsPolicy) originAllowed(origin string) bool {
+	if p.allowAny {
+		return true
+	}
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return false
+	}
+	for _, pattern := range p.patterns {
+		if pattern.matches(u.Scheme, u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *corsPolicy) headersAllowed(requested string) bool {
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header != "" && !p.headers[http.CanonicalHeaderKey(header)] {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *corsPolicy) serve(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	origin := r.Header.Get("Origin")
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+	w.Header().Add("Vary", "Origin")
+	if preflight {
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+	}
+
+	if origin == "" {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	if !p.originAllowed(origin) {
+		if preflight {
+			http.Error(w, "CORS origin not allowed", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	if p.allowAny {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.allowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if !preflight {
+		if p.exposedHeaders != "" {
+			w.Header().Set("Access-Control-Expose-Headers", p.exposedHeaders)
+		}
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	if !p.methods[strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))] ||
+		!p.headersAllowed(r.Header.Get("Access-Control-Request-Headers")) {
+		w.Header().Del("Access-Control-Allow-Origin")
+		w.Header().Del("Access-Control-Allow-Credentials")
+		http.Error(w, "CORS request not allowed", http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Methods", p.allowedMethods)
+	if p.allowedHeaders != "" {
+		w.Header().Set("Access-Control-Allow-Headers", p.allowedHeaders)
+	}
+	if p.maxAge != "" {
+		w.Header().Set("Access-Control-Max-Age", p.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CORSPolicy is a CORS middleware whose options can be replaced while serving
+type CORSPolicy struct {
+	policy atomic.Pointer[corsPolicy]
+}
+
+// NewCORSPolicy returns a policy applying opts until the next Update
+func NewCORSPolicy(opts CORSOptions) *CORSPolicy {
+	c := &CORSPolicy{}
+	c.Update(opts)
+	return c
+}
+
+// Update replaces the options; requests in flight finish with the previous ones
+func (c *CORSPolicy) Update(opts CORSOptions) {
+	c.policy.Store(newCORSPolicy(opts))
+}
+
+// Middleware behaves like the middleware returned by CORS, with the current options
+func (c *CORSPolicy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.policy.Load().serve(w, r, next)
+	})
+}
+
+// CORS returns a middleware that answers preflight requests and adds CORS headers for allowed origins.
+// Preflight requests from other origins, or asking for a method or header that is not allowed,
+// are rejected with 403. Other requests pass through without CORS headers so the browser blocks them.
+func CORS(opts CORSOptions) func(http.Handler) http.Handler {
+	policy := newCORSPolicy(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			policy.serve(w, r, next)
+		})
+	}
+}
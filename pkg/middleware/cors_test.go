@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func corsOptions() CORSOptions {
+	return CORSOptions{
+		AllowedOrigins:   []string{"http://localhost:3000", "https://*.example.com", "*.example.org"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "If-Match"},
+		ExposedHeaders:   []string{"ETag"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	}
+}
+
+func TestCORS_OriginMatching(t *testing.T) {
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"http://localhost:3000", true},
+		{"http://localhost:3001", false},
+		{"https://localhost:3000", false},
+		{"https://api.example.com", true},
+		{"https://a.b.example.com", true},
+		{"https://example.com", false},
+		{"http://api.example.com", false},
+		{"https://evilexample.com", false},
+		{"https://api.example.com.evil.io", false},
+		{"http://www.example.org", true},
+		{"https://www.example.org", true},
+		{"null", false},
+	}
+
+	handler := CORS(corsOptions())(okHandler)
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/movies", nil)
+			req.Header.Set("Origin", tt.origin)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Contains(t, rec.Header().Values("Vary"), "Origin")
+			if tt.allowed {
+				assert.Equal(t, tt.origin, rec.Header().Get("Access-Control-Allow-Origin"))
+				assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+				assert.Equal(t, "ETag", rec.Header().Get("Access-Control-Expose-Headers"))
+			} else {
+				assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+			}
+		})
+	}
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	tests := []struct {
+		name    string
+		origin  string
+		method  string
+		headers string
+		code    int
+	}{
+		{"allowed", "http://localhost:3000", "PATCH", "content-type, if-match", http.StatusNoContent},
+		{"disallowed origin", "http://evil.io", "GET", "", http.StatusForbidden},
+		{"disallowed method", "http://localhost:3000", "TRACE", "", http.StatusForbidden},
+		{"disallowed header", "http://localhost:3000", "PUT", "X-Secret", http.StatusForbidden},
+	}
+
+	handler := CORS(corsOptions())(okHandler)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/v1/movies/1", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+			if tt.headers != "" {
+				req.Header.Set("Access-Control-Request-Headers", tt.headers)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.code, rec.Code)
+			if tt.code != http.StatusNoContent {
+				assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+				return
+			}
+			assert.Equal(t, tt.origin, rec.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, "GET, POST, PUT, PATCH, DELETE, OPTIONS", rec.Header().Get("Access-Control-Allow-Methods"))
+			assert.Equal(t, "Content-Type, Authorization, If-Match", rec.Header().Get("Access-Control-Allow-Headers"))
+			assert.Equal(t, "3600", rec.Header().Get("Access-Control-Max-Age"))
+		})
+	}
+}
+
+func TestCORS_AnyOrigin(t *testing.T) {
+	opts := corsOptions()
+	opts.AllowedOrigins = []string{"*"}
+
+	opts.AllowCredentials = false
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://anywhere.io")
+	rec := httptest.NewRecorder()
+	CORS(opts)(okHandler).ServeHTTP(rec, req)
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+
+	// Any origin never gets credentials, the origin isn't echoed to grant them
+	opts.AllowCredentials = true
+	rec = httptest.NewRecorder()
+	CORS(opts)(okHandler).ServeHTTP(rec, req)
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+func TestCORS_NoOrigin(t *testing.T) {
+	rec := httptest.NewRecorder()
+	CORS(corsOptions())(okHandler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSPolicy_Update(t *testing.T) {
+	policy := NewCORSPolicy(corsOptions())
+	handler := policy.Middleware(okHandler)
+	allowOrigin := func(origin string) string {
+		req := httptest.NewRequest(http.MethodGet, "/v1/movies", nil)
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	assert.Equal(t, "http://localhost:3000", allowOrigin("http://localhost:3000"))
+	assert.Empty(t, allowOrigin("https://app.movies.io"))
+
+	opts := corsOptions()
+	opts.AllowedOrigins = []string{"https://app.movies.io"}
+	policy.Update(opts)
+
+	assert.Empty(t, allowOrigin("http://localhost:3000"), "existing handlers use the new origins")
+	assert.Equal(t, "https://app.movies.io", allowOrigin("https://app.movies.io"))
+}
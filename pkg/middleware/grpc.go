@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// GRPC returns a middleware that hands gRPC requests (HTTP/2 with an application/grpc content type)
+// to grpcServer and everything else to the wrapped handler, so both can share one listener.
+// With requireClientCert, gRPC requests must come over TLS with a client certificate; verifying it
+// is left to the listener's TLS config.
+func GRPC(grpcServer http.Handler, requireClientCert bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isGRPCRequest(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if requireClientCert && (r.TLS == nil || len(r.TLS.PeerCertificates) == 0) {
+				// gRPC clients report 401 as codes.Unauthenticated
+				http.Error(w, "client certificate required", http.StatusUnauthorized)
+				return
+			}
+			grpcServer.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isGRPCRequest reports whether r is a gRPC call
+func isGRPCRequest(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
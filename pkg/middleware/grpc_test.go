@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func newSinglePortServer(t *testing.T, requireClientCert bool) (*httptest.Server, healthpb.HealthClient) {
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+	rest := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "rest") })
+
+	srv := httptest.NewServer(h2c.NewHandler(GRPC(grpcServer, requireClientCert)(rest), &http2.Server{}))
+	t.Cleanup(srv.Close)
+
+	conn, err := grpc.NewClient(strings.TrimPrefix(srv.URL, "http://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return srv, healthpb.NewHealthClient(conn)
+}
+
+func TestGRPC(t *testing.T) {
+	srv, client := newSinglePortServer(t, false)
+
+	resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err, "gRPC over h2c reaches the gRPC server")
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	httpResp, err := http.Get(srv.URL + "/v1/movies")
+	require.NoError(t, err)
+	defer httpResp.Body.Close()
+	body, _ := io.ReadAll(httpResp.Body)
+	assert.Equal(t, "rest", string(body), "other requests reach the HTTP handler")
+}
+
+func TestGRPC_RequireClientCert(t *testing.T) {
+	_, client := newSinglePortServer(t, true)
+
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"movie-project/pkg/auth"
+)
+
+// ClientResolver identifies the client of a request. X-Forwarded-For is only honored
+// when the request arrives from one of the trusted proxies.
+type ClientResolver struct {
+	trusted []*net.IPNet
+}
+
+// NewClientResolver parses trusted proxies given as CIDRs or single IP addresses
+func NewClientResolver(trustedProxies []string) (ClientResolver, error) {
+	var resolver ClientResolver
+	for _, proxy := range trustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return ClientResolver{}, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		resolver.trusted = append(resolver.trusted, network)
+	}
+	return resolver, nil
+}
+
+func (c ClientResolver) isTrusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range c.trusted {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP walks X-Forwarded-For from the closest hop outwards and returns the first address
+// that is not a trusted proxy. Peers without an IP address are in-process connections, such as
+// the gateway in single-port mode, and are always trusted.
+func (c ClientResolver) clientIP(remoteAddr string, forwardedFor []string) string {
+	ip := remoteAddr
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		ip = host
+	}
+	if net.ParseIP(ip) != nil && !c.isTrusted(ip) {
+		return ip
+	}
+
+	var hops []string
+	for _, value := range forwardedFor {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		if net.ParseIP(hops[i]) == nil {
+			break
+		}
+		ip = hops[i]
+		if !c.isTrusted(ip) {
+			break
+		}
+	}
+	return ip
+}
+
+// FromRequest returns the client IP of an HTTP request
+func (c ClientResolver) FromRequest(r *http.Request) string {
+	return c.clientIP(r.RemoteAddr, r.Header.Values("X-Forwarded-For"))
+}
+
+// FromContext returns the client IP of an incoming gRPC call
+func (c ClientResolver) FromContext(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	return c.clientIP(p.Addr.String(), md.Get("x-forwarded-for"))
+}
+
+// clientKey prefers the authenticated subject and falls back to the client IP, with prefixes
+// that keep a subject from ever sharing the bucket of an address
+func clientKey(ctx context.Context, ip string) string {
+	if subject := auth.SubjectFromContext(ctx); subject != "" {
+		return "sub:" + subject
+	}
+	return "ip:" + ip
+}
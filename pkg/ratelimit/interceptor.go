@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"movie-project/pkg/metrics"
+)
+
+// UnaryServerInterceptor rejects calls over the limit with codes.ResourceExhausted and a retry-after header
+func UnaryServerInterceptor(l *Limiter, clients ClientResolver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if ok, retryAfter := l.Allow(clientKey(ctx, clients.FromContext(ctx)), info.FullMethod); !ok {
+			metrics.ThrottledRequests.WithLabelValues("grpc", info.FullMethod).Inc()
+			grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfterSeconds(retryAfter)))
+			return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry after %ss", retryAfterSeconds(retryAfter))
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor, limiting stream creation
+func StreamServerInterceptor(l *Limiter, clients ClientResolver) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		if ok, retryAfter := l.Allow(clientKey(ctx, clients.FromContext(ctx)), info.FullMethod); !ok {
+			metrics.ThrottledRequests.WithLabelValues("grpc", info.FullMethod).Inc()
+			ss.SetHeader(metadata.Pairs("retry-after", retryAfterSeconds(retryAfter)))
+			return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry after %ss", retryAfterSeconds(retryAfter))
+		}
+		return handler(srv, ss)
+	}
+}
+
+// Middleware rejects HTTP requests over the limit with 429 Too Many Requests and a Retry-After header.
+// All requests of a client share one bucket.
+func Middleware(l *Limiter, clients ClientResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ok, retryAfter := l.Allow(clientKey(r.Context(), clients.FromRequest(r)), "http"); !ok {
+				metrics.ThrottledRequests.WithLabelValues("http", r.Method).Inc()
+				w.Header().Set("Retry-After", retryAfterSeconds(retryAfter))
+				http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	sweepInterval = time.Minute
+	idleTimeout   = 10 * time.Minute
+)
+
+// Limit is a token bucket refilled with Rate tokens per second that holds up to Burst tokens
+type Limit struct {
+	Rate  rate.Limit
+	Burst int
+}
+
+// ParseLimit parses "rate:burst", e.g. "10:20". A bare rate uses the rate as burst as well.
+func ParseLimit(s string) (Limit, error) {
+	rateStr, burstStr, hasBurst := strings.Cut(strings.TrimSpace(s), ":")
+	r, err := strconv.ParseFloat(rateStr, 64)
+	if err != nil || r <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: rate must be a positive number", s)
+	}
+
+	burst := int(r)
+	if hasBurst {
+		if burst, err = strconv.Atoi(burstStr); err != nil || burst < 1 {
+			return Limit{}, fmt.Errorf("invalid rate limit %q: burst must be a positive integer", s)
+		}
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return Limit{Rate: rate.Limit(r), Burst: burst}, nil
+}
+
+// ParseMethodLimits parses "Method=rate:burst" entries. Method is either a full gRPC method
+// name ("/movie.MovieService/ListMovies") or just the method ("ListMovies").
+func ParseMethodLimits(entries []string) (map[string]Limit, error) {
+	limits := make(map[string]Limit, len(entries))
+	for _, entry := range entries {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		method, value, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(method) == "" {
+			return nil, fmt.Errorf("invalid method rate limit %q: expected Method=rate:burst", entry)
+		}
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return nil, err
+		}
+		limits[strings.TrimSpace(method)] = limit
+	}
+	return limits, nil
+}
+
+type bucketKey struct {
+	client string
+	method string
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Limiter keeps a token bucket per client and method
+type Limiter struct {
+	defaultLimit Limit
+	methods      map[string]Limit
+
+	mu        sync.Mutex
+	buckets   map[bucketKey]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewLimiter returns a limiter applying methods[method] where configured and defaultLimit otherwise
+func NewLimiter(defaultLimit Limit, methods map[string]Limit) *Limiter {
+	return &Limiter{
+		defaultLimit: defaultLimit,
+		methods:      methods,
+		buckets:      make(map[bucketKey]*bucket),
+		now:          time.Now,
+	}
+}
+
+// SetLimits replaces the limits. Existing buckets keep their tokens and refill at the new rate.
+func (l *Limiter) SetLimits(defaultLimit Limit, methods map[string]Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.defaultLimit, l.methods = defaultLimit, methods
+	now := l.now()
+	for key, b := range l.buckets {
+		limit := l.limitFor(key.method)
+		b.limiter.SetLimitAt(now, limit.Rate)
+		b.limiter.SetBurstAt(now, limit.Burst)
+	}
+}
+
+func (l *Limiter) limitFor(method string) Limit {
+	if limit, ok := l.methods[method]; ok {
+		return limit
+	}
+	if i := strings.LastIndexByte(method, '/'); i >= 0 {
+		if limit, ok := l.methods[method[i+1:]]; ok {
+			return limit
+		}
+	}
+	return l.defaultLimit
+}
+
+// Allow takes a token from the bucket of client and method. When the bucket is empty
+// it returns false and how long the client should wait before retrying.
+func (l *Limiter) Allow(client, method string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	key := bucketKey{client: client, method: method}
+	b, ok := l.buckets[key]
+	if !ok {
+		limit := l.limitFor(method)
+		b = &bucket{limiter: rate.NewLimiter(limit.Rate, limit.Burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	reservation := b.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// sweep forgets buckets of clients that have been idle long enough for their bucket to refill
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > idleTimeout {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// retryAfterSeconds rounds delay up to whole seconds as used by the Retry-After header
+func retryAfterSeconds(delay time.Duration) string {
+	seconds := int((delay + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
+}
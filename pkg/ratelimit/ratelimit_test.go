@@ -0,0 +1,200 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"movie-project/pkg/auth"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("10:20")
+	require.NoError(t, err)
+	assert.Equal(t, Limit{Rate: 10, Burst: 20}, limit)
+
+	limit, err = ParseLimit("0.5")
+	require.NoError(t, err)
+	assert.Equal(t, Limit{Rate: 0.5, Burst: 1}, limit)
+
+	for _, invalid := range []string{"", "abc", "-1:5", "10:0", "10:x"} {
+		_, err := ParseLimit(invalid)
+		assert.Error(t, err, invalid)
+	}
+
+	methods, err := ParseMethodLimits([]string{"ListMovies=1:2", "/movie.MovieService/GetMovie=3:4"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Limit{"ListMovies": {1, 2}, "/movie.MovieService/GetMovie": {3, 4}}, methods)
+
+	_, err = ParseMethodLimits([]string{"ListMovies"})
+	assert.Error(t, err)
+}
+
+func newTestLimiter(defaultLimit Limit, methods map[string]Limit) (*Limiter, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiter(defaultLimit, methods)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	l, now := newTestLimiter(Limit{Rate: 1, Burst: 2}, map[string]Limit{"ListMovies": {Rate: rate.Every(10 * time.Second), Burst: 1}})
+
+	ok, _ := l.Allow("a", "/movie.MovieService/GetMovie")
+	assert.True(t, ok)
+	ok, _ = l.Allow("a", "/movie.MovieService/GetMovie")
+	assert.True(t, ok)
+	ok, retryAfter := l.Allow("a", "/movie.MovieService/GetMovie")
+	assert.False(t, ok, "burst exhausted")
+	assert.Equal(t, time.Second, retryAfter)
+
+	ok, _ = l.Allow("b", "/movie.MovieService/GetMovie")
+	assert.True(t, ok, "clients have separate buckets")
+
+	ok, _ = l.Allow("a", "/movie.MovieService/ListMovies")
+	assert.True(t, ok, "methods have separate buckets")
+	ok, retryAfter = l.Allow("a", "/movie.MovieService/ListMovies")
+	assert.False(t, ok)
+	assert.Equal(t, 10*time.Second, retryAfter, "per-method limit matched by short name")
+
+	*now = now.Add(time.Second)
+	ok, _ = l.Allow("a", "/movie.MovieService/GetMovie")
+	assert.True(t, ok, "bucket refills over time")
+
+	*now = now.Add(time.Hour)
+	l.Allow("c", "x")
+	assert.Len(t, l.buckets, 1, "idle buckets are swept")
+}
+
+func TestLimiter_SetLimits(t *testing.T) {
+	l, now := newTestLimiter(Limit{Rate: 1, Burst: 1}, nil)
+
+	ok, _ := l.Allow("a", "/movie.MovieService/ListMovies")
+	assert.True(t, ok)
+	ok, _ = l.Allow("a", "/movie.MovieService/ListMovies")
+	assert.False(t, ok)
+
+	l.SetLimits(Limit{Rate: 1, Burst: 1}, map[string]Limit{"ListMovies": {Rate: 10, Burst: 5}})
+	*now = now.Add(time.Second)
+	for i := 0; i < 5; i++ {
+		ok, _ = l.Allow("a", "/movie.MovieService/ListMovies")
+		assert.True(t, ok, "existing bucket uses the new burst, call %d", i)
+	}
+	ok, retryAfter := l.Allow("a", "/movie.MovieService/ListMovies")
+	assert.False(t, ok)
+	assert.Equal(t, 100*time.Millisecond, retryAfter, "and the new rate")
+}
+
+func TestClientResolver(t *testing.T) {
+	clients, err := NewClientResolver([]string{"10.0.0.0/8", "127.0.0.1"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		want         string
+	}{
+		{"direct client", "203.0.113.5:4000", nil, "203.0.113.5"},
+		{"untrusted peer cannot spoof", "203.0.113.5:4000", []string{"1.2.3.4"}, "203.0.113.5"},
+		{"trusted proxy", "10.0.0.1:4000", []string{"198.51.100.7"}, "198.51.100.7"},
+		{"proxy chain", "127.0.0.1:4000", []string{"1.2.3.4, 198.51.100.7, 10.1.1.1"}, "198.51.100.7"},
+		{"all hops trusted", "127.0.0.1:4000", []string{"10.0.0.2"}, "10.0.0.2"},
+		{"trusted proxy without header", "10.0.0.1:4000", nil, "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			assert.Equal(t, tt.want, clients.FromRequest(req))
+
+			addr, err := net.ResolveTCPAddr("tcp", tt.remoteAddr)
+			require.NoError(t, err)
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+			ctx = metadata.NewIncomingContext(ctx, metadata.MD{"x-forwarded-for": tt.forwardedFor})
+			assert.Equal(t, tt.want, clients.FromContext(ctx))
+		})
+	}
+
+	inProcess := peer.NewContext(context.Background(), &peer.Peer{Addr: bufconnAddr{}})
+	inProcess = metadata.NewIncomingContext(inProcess, metadata.Pairs("x-forwarded-for", "198.51.100.7"))
+	assert.Equal(t, "198.51.100.7", clients.FromContext(inProcess), "in-process peers are trusted")
+
+	_, err = NewClientResolver([]string{"not-an-ip"})
+	assert.Error(t, err)
+}
+
+type bufconnAddr struct{}
+
+func (bufconnAddr) Network() string { return "bufconn" }
+func (bufconnAddr) String() string  { return "bufconn" }
+
+func TestMiddleware(t *testing.T) {
+	l, _ := newTestLimiter(Limit{Rate: 0.5, Burst: 1}, nil)
+	clients, err := NewClientResolver(nil)
+	require.NoError(t, err)
+	handler := Middleware(l, clients)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func(remoteAddr, subject string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/movies", nil)
+		req.RemoteAddr = remoteAddr
+		if subject != "" {
+			req = req.WithContext(auth.ContextWithSubject(req.Context(), subject))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, request("192.0.2.1:1000", "").Code)
+	rec := request("192.0.2.1:1001", "")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, request("192.0.2.1:1002", "alice").Code, "authenticated subjects have their own bucket")
+	assert.Equal(t, http.StatusTooManyRequests, request("192.0.2.99:1000", "alice").Code, "subject bucket follows the user across addresses")
+	assert.Equal(t, http.StatusOK, request("192.0.2.1:1003", "bob").Code, "subjects behind one address have separate buckets")
+	assert.Equal(t, http.StatusOK, request("192.0.2.1:1004", "192.0.2.1").Code, "a subject never shares the bucket of an address")
+}
+
+func TestClientKey(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "ip:192.0.2.1", clientKey(ctx, "192.0.2.1"))
+	assert.Equal(t, "sub:alice", clientKey(auth.ContextWithSubject(ctx, "alice"), "192.0.2.1"))
+	assert.Equal(t, "sub:192.0.2.1", clientKey(auth.ContextWithSubject(ctx, "192.0.2.1"), "192.0.2.1"))
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	l, _ := newTestLimiter(Limit{Rate: 1, Burst: 1}, nil)
+	clients, err := NewClientResolver(nil)
+	require.NoError(t, err)
+	interceptor := UnaryServerInterceptor(l, clients)
+
+	addr, err := net.ResolveTCPAddr("tcp", "192.0.2.1:1000")
+	require.NoError(t, err)
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+	info := &grpc.UnaryServerInfo{FullMethod: "/movie.MovieService/ListMovies"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	resp, err := interceptor(ctx, nil, info, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+
+	_, err = interceptor(ctx, nil, info, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
@@ -0,0 +1,115 @@
+// Package requestid assigns every request an id that is passed from HTTP through the gateway
+// into gRPC, returned to the caller and added to every log line written for the request.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"movie-project/pkg/logger"
+)
+
+const (
+	// Header is the HTTP header carrying the request id
+	Header = "X-Request-ID"
+	// MetadataKey is the gRPC metadata key carrying the request id
+	MetadataKey = "x-request-id"
+
+	maxLength = 128
+)
+
+type requestIDKey struct{}
+
+// NewContext returns a copy of ctx carrying the request id
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// FromContext returns the request id, or "" outside of a request
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// New generates a random request id
+func New() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// valid accepts ids generated by other services and proxies (UUIDs, hex strings, "host/1234-5")
+// while keeping arbitrary client input out of logs and headers
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '/', c == '+', c == '=':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// accept returns id when the caller sent a usable one and a new id otherwise
+func accept(id string) string {
+	if valid(id) {
+		return id
+	}
+	return New()
+}
+
+// start stores the request id and method in ctx for handlers and logging
+func start(ctx context.Context, id, method string) context.Context {
+	ctx = NewContext(ctx, id)
+	return logger.ContextWith(ctx, "request_id", id, "method", method)
+}
+
+// Middleware accepts the X-Request-ID of the caller or generates one, and echoes it in the response
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := accept(r.Header.Get(Header))
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(start(r.Context(), id, r.Method+" "+r.URL.Path)))
+	})
+}
+
+// UnaryServerInterceptor accepts the x-request-id metadata of the caller or generates one,
+// and returns it in the response header metadata
+func UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	id := accept(fromMetadata(ctx))
+	grpc.SetHeader(ctx, metadata.Pairs(MetadataKey, id))
+	return handler(start(ctx, id, info.FullMethod), req)
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor
+func StreamServerInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	id := accept(fromMetadata(ss.Context()))
+	ss.SetHeader(metadata.Pairs(MetadataKey, id))
+	return handler(srv, &serverStream{ServerStream: ss, ctx: start(ss.Context(), id, info.FullMethod)})
+}
+
+func fromMetadata(ctx context.Context) string {
+	if values := metadata.ValueFromIncomingContext(ctx, MetadataKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,36 @@
+// Package storage stores blobs, such as the posters of movies, in a local directory or an
+// S3-compatible bucket.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// ErrNotFound is returned when reading a blob that does not exist
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore stores blobs under slash-separated keys such as "movies/1/poster.jpg"
+type BlobStore interface {
+	// Put stores size bytes of body under key, replacing any blob stored under it
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get returns the content of the blob, or ErrNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob; deleting a missing blob is not an error
+	Delete(ctx context.Context, key string) error
+	// URL returns the URL the blob is downloaded from
+	URL(key string) string
+}
+
+// validKey reports whether key is a relative slash-separated path without "." or ".." elements
+func validKey(key string) bool {
+	return key != "" && fs.ValidPath(key) && key != "."
+}
+
+// joinURL appends the escaped key to base
+func joinURL(base, key string) string {
+	return strings.TrimSuffix(base, "/") + "/" + uriEncode(key, true)
+}
@@ -0,0 +1,92 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+
+	"movie-project/config"
+	"movie-project/internal/model"
+	"movie-project/pkg/database"
+	"movie-project/pkg/logger"
+	"movie-project/pkg/tracing"
+)
+
+func newRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestGormPlugin(t *testing.T) {
+	db, err := database.Open(config.Config{DBDriver: config.DriverSQLite, DBPath: filepath.Join(t.TempDir(), "movie.db")}, logger.Discard())
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Movie{}))
+	recorder := newRecorder(t)
+
+	ctx, parent := tracing.Start(context.Background(), "test")
+	require.NoError(t, db.WithContext(ctx).Create(&model.Movie{Title: "Alien", Director: "Ridley Scott", Genre: "Sci-Fi", Rating: 8.5}).Error)
+	err = db.WithContext(ctx).First(&model.Movie{}, 42).Error
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	create, query := spans[0], spans[1]
+
+	assert.Equal(t, "gorm.create", create.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), create.Parent().SpanID())
+	assert.Equal(t, "sqlite", attr(create, "db.system").AsString())
+	assert.Equal(t, "movies", attr(create, "db.sql.table").AsString())
+	assert.Contains(t, attr(create, "db.statement").AsString(), "INSERT INTO `movies`")
+	assert.NotContains(t, attr(create, "db.statement").AsString(), "Alien", "arguments are not recorded")
+	assert.EqualValues(t, 1, attr(create, "db.rows_affected").AsInt64())
+
+	assert.Equal(t, "gorm.query", query.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), query.Parent().SpanID(), "statements don't nest under earlier ones")
+	assert.Equal(t, codes.Unset, query.Status().Code, "not found is not an error")
+}
+
+func TestEnd(t *testing.T) {
+	recorder := newRecorder(t)
+
+	_, span := tracing.Start(context.Background(), "failing", attribute.Int("movie.id", 1))
+	tracing.End(span, errors.New("boom"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "boom", spans[0].Status().Description)
+	assert.EqualValues(t, 1, attr(spans[0], "movie.id").AsInt64())
+}
+
+func TestSetup(t *testing.T) {
+	shutdown, err := tracing.Setup(context.Background(), tracing.Options{Exporter: tracing.ExporterNone})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+	assert.Contains(t, otel.GetTextMapPropagator().Fields(), "traceparent", "trace context is propagated even without an exporter")
+
+	_, err = tracing.Setup(context.Background(), tracing.Options{Exporter: "jaeger"})
+	assert.Error(t, err)
+}